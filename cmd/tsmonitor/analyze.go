@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/otcnet/tsmonitor/internal/analyze"
+)
+
+// runAnalyze разбирает запись MPEG-TS и печатает отчёт:
+// tsmonitor analyze -format json capture.ts
+func runAnalyze(args []string) int {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	format := flags.String("format", "text", "text or json")
+	realtime := flags.Bool("realtime", false, "read at the recorded pace (by PCR) instead of as fast as possible")
+	window := flags.Duration("window", 0, "bitrate timeline step (default 1s)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tsmonitor analyze [flags] file.ts")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "❌ invalid format %q (text or json)\n", *format)
+		return 2
+	}
+
+	// Ctrl+C в режиме realtime завершает анализ с отчётом по прочитанному
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := analyze.File(ctx, flags.Arg(0), analyze.Options{Realtime: *realtime, Window: *window})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/otcnet/tsmonitor/internal/config"
 	"github.com/otcnet/tsmonitor/internal/monitor"
@@ -17,6 +19,14 @@ const (
 )
 
 func main() {
+	// Подкоманды пишут отчёт в stdout, поэтому без баннера
+	if len(os.Args) > 1 && os.Args[1] == "sla" {
+		os.Exit(runSLA(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		os.Exit(runAnalyze(os.Args[2:]))
+	}
+
 	fmt.Printf("TSMonitor v%s - MPEG-TS Stream Monitor\n\n", version)
 
 	// Определяем путь к конфигу
@@ -31,12 +41,14 @@ func main() {
 	if err != nil {
 		fmt.Printf("❌ Failed to load config: %v\n", err)
 		fmt.Println("\nUsage: tsmonitor [config.yaml]")
+		fmt.Println("       tsmonitor sla [flags]   (see tsmonitor sla -h)")
+		fmt.Println("       tsmonitor analyze [flags] file.ts")
 		fmt.Printf("Default config path: %s\n", defaultConfigPath)
 		os.Exit(1)
 	}
 
 	fmt.Printf("✅ Config loaded: %d streams\n", cfg.StreamCount())
-	fmt.Printf("   Interface: %s\n", cfg.Interface)
+	fmt.Printf("   Interfaces: %s\n", strings.Join(cfg.Interfaces(), ", "))
 	fmt.Printf("   Metrics port: %d\n", cfg.MetricsPort)
 	fmt.Println()
 
@@ -48,6 +60,10 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 
+	// SIGHUP перечитывает конфиг без перезапуска
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
 	// Создаём и запускаем orchestrator
 	orch := monitor.NewOrchestrator(cfg)
 	
@@ -56,8 +72,26 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Ждём сигнала остановки
-	sig := <-sigChan
+	// Изменение файла конфига проверяем по времени модификации
+	var changedChan <-chan struct{}
+	if cfg.WatchConfig {
+		changedChan = watchConfig(ctx, configPath)
+		fmt.Printf("👀 Watching %s for changes\n", configPath)
+	}
+
+	// Ждём сигнала остановки, перечитывая конфиг по SIGHUP
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case sig = <-sigChan:
+		case <-hupChan:
+			fmt.Println("\n📡 Received SIGHUP, reloading config")
+			reloadConfig(orch, configPath)
+		case <-changedChan:
+			fmt.Println("\n📝 Config file changed, reloading")
+			reloadConfig(orch, configPath)
+		}
+	}
 	fmt.Printf("\n📡 Received signal: %v\n", sig)
 	fmt.Println("🛑 Shutting down gracefully...")
 
@@ -67,3 +101,51 @@ func main() {
 
 	fmt.Println("👋 Goodbye!")
 }
+
+// reloadConfig загружает конфиг и применяет его; при ошибке остаётся старый конфиг
+func reloadConfig(orch *monitor.Orchestrator, configPath string) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("❌ Reload failed, keeping current config: %v\n", err)
+		return
+	}
+
+	if err := orch.Reload(cfg); err != nil {
+		fmt.Printf("❌ Reload error: %v\n", err)
+	}
+}
+
+// watchConfig раз в 5 секунд проверяет время модификации файла конфига
+func watchConfig(ctx context.Context, configPath string) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	var lastMod time.Time
+	if info, err := os.Stat(configPath); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(configPath)
+				if err != nil || info.ModTime().Equal(lastMod) {
+					continue
+				}
+				lastMod = info.ModTime()
+
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/events"
+	"github.com/otcnet/tsmonitor/internal/sla"
+)
+
+// runSLA строит отчёт о доступности по файлу истории событий:
+// tsmonitor sla -config config.yaml -month 2025-01 -format csv
+func runSLA(args []string) int {
+	flags := flag.NewFlagSet("sla", flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "path to config.yaml (streams and events.path)")
+	month := flags.String("month", "", "report month, YYYY-MM")
+	from := flags.String("from", "", "period start, RFC3339 or YYYY-MM-DD (default: start of current month)")
+	to := flags.String("to", "", "period end, RFC3339 or YYYY-MM-DD, exclusive (default: now)")
+	group := flags.String("group", "", "stream or provider (default: both)")
+	format := flags.String("format", "csv", "csv or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load config: %v\n", err)
+		return 1
+	}
+	if cfg.Events.Path == "" {
+		fmt.Fprintln(os.Stderr, "❌ events.path is not set in config: no status history to report on")
+		return 1
+	}
+
+	start, end, err := sla.ParsePeriod(*from, *to, *month, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+
+	// Файл только читаем: его может дописывать работающий tsmonitor
+	history, err := events.Load(cfg.Events.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	summary, err := sla.Build(sla.StreamsFromConfig(cfg.Streams), history, start, end, *group)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+
+	switch *format {
+	case "csv":
+		err = summary.WriteCSV(os.Stdout)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(summary)
+	default:
+		fmt.Fprintf(os.Stderr, "❌ invalid format %q (csv or json)\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
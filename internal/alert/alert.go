@@ -0,0 +1,296 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// Статусы уведомлений
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// queueSize сколько уведомлений может ждать отправки,
+// дальше новые отбрасываются, чтобы не тормозить обработку метрик
+const queueSize = 1000
+
+// Notification уведомление о срабатывании или снятии алерта
+type Notification struct {
+	Status      string     `json:"status"` // firing или resolved
+	Rule        string     `json:"rule"`
+	Type        string     `json:"type"`
+	Stream      string     `json:"stream"`
+	Description string     `json:"description"`
+	Message     string     `json:"message"`
+	Value       float64    `json:"value"`
+	Threshold   float64    `json:"threshold"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"` // только для resolved
+	Repeat      bool       `json:"repeat"`            // повтор уже отправленного firing
+
+	webhooks []string // маршрутизация, пусто = все
+}
+
+// Notifier отправляет уведомления во внешнюю систему
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Engine проверяет правила на каждом обновлении метрик и рассылает уведомления
+type Engine struct {
+	mu        sync.Mutex
+	rules     []config.AlertRule
+	streams   map[string]config.Stream // пороги и маршрутизация потоков по URL
+	notifiers []Notifier
+	states    map[stateKey]*ruleState
+	queue     chan Notification
+	now       func() time.Time
+}
+
+// stateKey состояние хранится отдельно для каждой пары правило/поток
+type stateKey struct {
+	rule   string
+	stream string
+}
+
+// ruleState состояние правила для одного потока
+type ruleState struct {
+	activeSince time.Time // условие выполняется с этого момента (pending)
+	clearSince  time.Time // условие не выполняется с этого момента (firing)
+	firing      bool
+	startsAt    time.Time
+	lastSent    time.Time
+	description string
+	samples     []ccSample // cc_errors: ошибки за окно
+}
+
+// NewEngine создает Engine с правилами и webhooks из конфигурации
+func NewEngine(cfg config.Alerts) *Engine {
+	e := &Engine{
+		states: make(map[stateKey]*ruleState),
+		queue:  make(chan Notification, queueSize),
+		now:    time.Now,
+	}
+	e.Update(cfg)
+	return e
+}
+
+// Update применяет новую конфигурацию алертов (reload).
+// Состояние сохраняется для правил, которые не изменились
+func (e *Engine) Update(cfg config.Alerts) {
+	notifiers := make([]Notifier, 0, len(cfg.Webhooks))
+	for _, webhook := range cfg.Webhooks {
+		notifiers = append(notifiers, NewWebhook(webhook))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	unchanged := make(map[string]bool)
+	for _, next := range cfg.Rules {
+		for _, rule := range e.rules {
+			if rule.Name == next.Name && reflect.DeepEqual(rule, next) {
+				unchanged[rule.Name] = true
+			}
+		}
+	}
+	for key := range e.states {
+		if !unchanged[key.rule] {
+			delete(e.states, key)
+		}
+	}
+
+	e.rules = cfg.Rules
+	e.notifiers = notifiers
+}
+
+// SetStreams задаёт настройки потоков (thresholds, bitrate, webhooks),
+// которые перекрывают параметры правил
+func (e *Engine) SetStreams(streams []config.Stream) {
+	byURL := make(map[string]config.Stream, len(streams))
+	for _, stream := range streams {
+		byURL[stream.URL] = stream
+	}
+
+	e.mu.Lock()
+	e.streams = byURL
+	e.mu.Unlock()
+}
+
+// Run отправляет уведомления из очереди, пока не отменён контекст
+func (e *Engine) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-e.queue:
+			e.send(ctx, n)
+		}
+	}
+}
+
+// send отправляет уведомление всем webhooks правила
+func (e *Engine) send(ctx context.Context, n Notification) {
+	e.mu.Lock()
+	notifiers := e.notifiers
+	e.mu.Unlock()
+
+	for _, notifier := range notifiers {
+		if len(n.webhooks) > 0 && !contains(n.webhooks, notifier.Name()) {
+			continue
+		}
+		if err := notifier.Notify(ctx, n); err != nil {
+			fmt.Printf("❌ [%s] alert %s: webhook %s: %v\n", n.Stream, n.Rule, notifier.Name(), err)
+		}
+	}
+}
+
+// Evaluate проверяет все правила для очередного обновления потока
+func (e *Engine) Evaluate(m *tsp.StreamMetrics) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for _, rule := range e.rules {
+		if len(rule.Streams) > 0 && !contains(rule.Streams, m.StreamURL) {
+			continue
+		}
+		if stream, ok := e.streams[m.StreamURL]; ok {
+			rule = rule.ForStream(stream)
+		}
+
+		key := stateKey{rule: rule.Name, stream: m.StreamURL}
+		st, ok := e.states[key]
+		if !ok {
+			st = &ruleState{}
+			e.states[key] = st
+		}
+		st.description = m.Description
+
+		result := check(rule, m, st, now)
+		e.transition(rule, m.StreamURL, st, result, now)
+	}
+}
+
+// transition переводит правило между состояниями inactive → pending → firing → resolved
+func (e *Engine) transition(rule config.AlertRule, stream string, st *ruleState, result checkResult, now time.Time) {
+	if result.active {
+		st.clearSince = time.Time{}
+
+		if !st.firing {
+			if st.activeSince.IsZero() {
+				st.activeSince = now
+			}
+			if now.Sub(st.activeSince) < rule.For {
+				return // pending
+			}
+			st.firing = true
+			st.startsAt = st.activeSince
+			st.lastSent = now
+			e.enqueue(e.notification(rule, stream, st, result, StatusFiring))
+			return
+		}
+
+		// Уже firing: повторяем не чаще repeat_interval
+		if now.Sub(st.lastSent) >= rule.RepeatInterval {
+			st.lastSent = now
+			n := e.notification(rule, stream, st, result, StatusFiring)
+			n.Repeat = true
+			e.enqueue(n)
+		}
+		return
+	}
+
+	st.activeSince = time.Time{}
+	if !st.firing {
+		return
+	}
+
+	// Снимаем алерт, только если условие не возвращается resolve_for
+	if st.clearSince.IsZero() {
+		st.clearSince = now
+	}
+	if now.Sub(st.clearSince) < rule.ResolveFor {
+		return
+	}
+	e.resolve(rule, stream, st, result, now)
+}
+
+// resolve отправляет resolved и сбрасывает состояние
+func (e *Engine) resolve(rule config.AlertRule, stream string, st *ruleState, result checkResult, now time.Time) {
+	n := e.notification(rule, stream, st, result, StatusResolved)
+	n.EndsAt = &now
+	e.enqueue(n)
+
+	st.firing = false
+	st.clearSince = time.Time{}
+}
+
+// Forget удаляет состояние потока (поток убран из конфигурации).
+// Активные алерты снимаются
+func (e *Engine) Forget(stream string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for _, rule := range e.rules {
+		key := stateKey{rule: rule.Name, stream: stream}
+		st, ok := e.states[key]
+		if !ok {
+			continue
+		}
+		if st.firing {
+			e.resolve(rule, stream, st, checkResult{message: "stream is no longer monitored"}, now)
+		}
+		delete(e.states, key)
+	}
+}
+
+// notification собирает уведомление по правилу
+func (e *Engine) notification(rule config.AlertRule, stream string, st *ruleState, result checkResult, status string) Notification {
+	return Notification{
+		Status:      status,
+		Rule:        rule.Name,
+		Type:        rule.Type,
+		Stream:      stream,
+		Description: st.description,
+		Message:     result.message,
+		Value:       result.value,
+		Threshold:   rule.Threshold,
+		StartsAt:    st.startsAt,
+		webhooks:    rule.Webhooks,
+	}
+}
+
+// enqueue ставит уведомление в очередь, не блокируя обработку метрик
+func (e *Engine) enqueue(n Notification) {
+	icon := "🚨"
+	if n.Status == StatusResolved {
+		icon = "✅"
+	}
+	fmt.Printf("%s [%s] alert %s %s: %s\n", icon, n.Stream, n.Rule, n.Status, n.Message)
+
+	select {
+	case e.queue <- n:
+	default:
+		fmt.Printf("⚠️  Alert queue full, notification %s for %s dropped\n", n.Rule, n.Stream)
+	}
+}
+
+// contains проверяет наличие строки в списке
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+const testStream = "233.198.134.1:3333"
+
+// testEngine создает Engine с управляемыми часами
+func testEngine(rules ...config.AlertRule) (*Engine, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := NewEngine(config.Alerts{Rules: rules})
+	e.now = func() time.Time { return now }
+	return e, &now
+}
+
+// drain забирает все уведомления из очереди
+func drain(e *Engine) []Notification {
+	var out []Notification
+	for {
+		select {
+		case n := <-e.queue:
+			out = append(out, n)
+		default:
+			return out
+		}
+	}
+}
+
+func online(bitrate int64, ccErrors int64) *tsp.StreamMetrics {
+	return &tsp.StreamMetrics{
+		StreamURL: testStream,
+		Status:    true,
+		Bitrate:   tsp.BitrateInfo{TotalBPS: bitrate},
+		PIDs:      []tsp.PIDInfo{{PID: "0x0066", Type: "video"}},
+		CCErrors:  map[string]int64{"0x0066": ccErrors},
+	}
+}
+
+// silent возвращает метрики потока с аудио PID 0x00CA; silence — тишина дольше порога
+func silent(silence bool) *tsp.StreamMetrics {
+	m := online(5000000, 0)
+	m.PIDs = append(m.PIDs, tsp.PIDInfo{PID: "0x00CA", Type: "audio", Audio: &tsp.AudioInfo{
+		Level: &tsp.AudioLevel{RMSDBFS: -120, PeakDBFS: -120, Silent: 15 * time.Second, Silence: silence},
+	}})
+	return m
+}
+
+func offline() *tsp.StreamMetrics {
+	return &tsp.StreamMetrics{StreamURL: testStream, CCErrors: map[string]int64{}}
+}
+
+func TestEngineLifecycle(t *testing.T) {
+	e, now := testEngine(config.AlertRule{
+		Name:           "down",
+		Type:           config.AlertOffline,
+		For:            30 * time.Second,
+		ResolveFor:     10 * time.Second,
+		RepeatInterval: time.Hour,
+	})
+
+	steps := []struct {
+		after   time.Duration
+		metrics *tsp.StreamMetrics
+		want    []string // статусы уведомлений
+	}{
+		{0, online(5000000, 0), nil},
+		{10 * time.Second, offline(), nil},                    // pending
+		{20 * time.Second, offline(), nil},                    // 20s < for
+		{10 * time.Second, offline(), []string{StatusFiring}}, // 30s
+		{10 * time.Second, offline(), nil},                    // dedup
+		{10 * time.Second, online(5000000, 0), nil},           // resolve_for
+		{5 * time.Second, offline(), nil},                     // flap: still firing
+		{10 * time.Second, online(5000000, 0), nil},           // clear again
+		{10 * time.Second, online(5000000, 0), []string{StatusResolved}},
+		{time.Hour, online(5000000, 0), nil},
+	}
+
+	for i, step := range steps {
+		*now = now.Add(step.after)
+		e.Evaluate(step.metrics)
+
+		got := drain(e)
+		if len(got) != len(step.want) {
+			t.Fatalf("step %d: got %d notifications %+v, want %v", i, len(got), got, step.want)
+		}
+		for j, n := range got {
+			if n.Status != step.want[j] {
+				t.Errorf("step %d: status = %s, want %s", i, n.Status, step.want[j])
+			}
+			if n.Status == StatusResolved && n.EndsAt == nil {
+				t.Errorf("step %d: resolved without ends_at", i)
+			}
+		}
+	}
+}
+
+func TestEngineRepeat(t *testing.T) {
+	e, now := testEngine(config.AlertRule{
+		Name:           "low",
+		Type:           config.AlertBitrateLow,
+		Threshold:      2000000,
+		RepeatInterval: 10 * time.Minute,
+	})
+
+	var sent []Notification
+	for i := 0; i < 25; i++ {
+		e.Evaluate(online(1000000, 0))
+		sent = append(sent, drain(e)...)
+		*now = now.Add(time.Minute)
+	}
+
+	// Первое уведомление и повторы на 10-й и 20-й минуте
+	if len(sent) != 3 {
+		t.Fatalf("got %d notifications, want 3", len(sent))
+	}
+	if sent[0].Repeat || !sent[1].Repeat || !sent[2].Repeat {
+		t.Errorf("repeat flags = %v %v %v", sent[0].Repeat, sent[1].Repeat, sent[2].Repeat)
+	}
+	if sent[0].Value != 1000000 || sent[0].Threshold != 2000000 {
+		t.Errorf("value = %v, threshold = %v", sent[0].Value, sent[0].Threshold)
+	}
+}
+
+func TestStreamOverrides(t *testing.T) {
+	e, _ := testEngine(
+		config.AlertRule{Name: "low", Type: config.AlertBitrateLow, Threshold: 1000000},
+		config.AlertRule{Name: "high", Type: config.AlertBitrateHigh, Threshold: 20000000, Webhooks: []string{"noc"}},
+	)
+	e.SetStreams([]config.Stream{{
+		URL:        testStream,
+		Bitrate:    config.BitrateRange{Min: 4000000, Max: 6000000},
+		Thresholds: map[string]float64{"high": 8000000},
+		Webhooks:   []string{"provider"},
+	}})
+
+	// 3 Мбит/с: ниже ожидаемого min, хотя выше порога правила
+	e.Evaluate(online(3000000, 0))
+	got := drain(e)
+	if len(got) != 1 || got[0].Rule != "low" || got[0].Threshold != 4000000 {
+		t.Fatalf("low bitrate: got %+v", got)
+	}
+	if len(got[0].webhooks) != 1 || got[0].webhooks[0] != "provider" {
+		t.Errorf("low bitrate: webhooks = %v, want stream routing", got[0].webhooks)
+	}
+
+	// thresholds по имени правила важнее bitrate.max
+	e.Evaluate(online(7000000, 0))
+	if got := drain(e); len(got) != 1 || got[0].Status != StatusResolved {
+		t.Fatalf("7 Mbit/s: got %+v, want low resolved only", got)
+	}
+	e.Evaluate(online(9000000, 0))
+	if got := drain(e); len(got) != 1 || got[0].Rule != "high" || got[0].Threshold != 8000000 {
+		t.Fatalf("9 Mbit/s: got %+v", got)
+	}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    config.AlertRule
+		updates []*tsp.StreamMetrics
+		firing  bool
+	}{
+		{
+			name:    "bitrate high",
+			rule:    config.AlertRule{Type: config.AlertBitrateHigh, Threshold: 8000000},
+			updates: []*tsp.StreamMetrics{online(9000000, 0)},
+			firing:  true,
+		},
+		{
+			name:    "bitrate low ignores offline stream",
+			rule:    config.AlertRule{Type: config.AlertBitrateLow, Threshold: 2000000},
+			updates: []*tsp.StreamMetrics{offline()},
+			firing:  false,
+		},
+		{
+			name:    "cc errors over window",
+			rule:    config.AlertRule{Type: config.AlertCCErrors, Threshold: 5, Window: time.Minute},
+			updates: []*tsp.StreamMetrics{online(5000000, 3), online(5000000, 3)},
+			firing:  true,
+		},
+		{
+			name:    "cc errors below threshold",
+			rule:    config.AlertRule{Type: config.AlertCCErrors, Threshold: 5, Window: time.Minute},
+			updates: []*tsp.StreamMetrics{online(5000000, 3), online(5000000, 0)},
+			firing:  false,
+		},
+		{
+			name:    "audio pid missing",
+			rule:    config.AlertRule{Type: config.AlertPIDMissing, PIDType: "audio"},
+			updates: []*tsp.StreamMetrics{online(5000000, 0)},
+			firing:  true,
+		},
+		{
+			name: "subtitle pid present",
+			rule: config.AlertRule{Type: config.AlertPIDMissing, PIDType: "subtitle"},
+			updates: []*tsp.StreamMetrics{func() *tsp.StreamMetrics {
+				m := online(5000000, 0)
+				m.PIDs = append(m.PIDs, tsp.PIDInfo{PID: "0x00D2", Type: "data", IsSubtitle: true})
+				return m
+			}()},
+			firing: false,
+		},
+		{
+			name:    "pid present",
+			rule:    config.AlertRule{Type: config.AlertPIDMissing, PID: "0x0066"},
+			updates: []*tsp.StreamMetrics{online(5000000, 0)},
+			firing:  false,
+		},
+		{
+			name:    "audio silence",
+			rule:    config.AlertRule{Type: config.AlertSilence},
+			updates: []*tsp.StreamMetrics{silent(true)},
+			firing:  true,
+		},
+		{
+			name:    "audio silence below duration",
+			rule:    config.AlertRule{Type: config.AlertSilence},
+			updates: []*tsp.StreamMetrics{silent(false)},
+			firing:  false,
+		},
+		{
+			name:    "audio silence on other pid",
+			rule:    config.AlertRule{Type: config.AlertSilence, PID: "0x00CB"},
+			updates: []*tsp.StreamMetrics{silent(true)},
+			firing:  false,
+		},
+		{
+			name:    "other stream",
+			rule:    config.AlertRule{Type: config.AlertOffline, Streams: []string{"233.198.134.2:3333"}},
+			updates: []*tsp.StreamMetrics{offline()},
+			firing:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Name = "rule"
+			tt.rule.RepeatInterval = time.Hour
+			e, now := testEngine(tt.rule)
+
+			for _, m := range tt.updates {
+				e.Evaluate(m)
+				*now = now.Add(time.Second)
+			}
+
+			got := drain(e)
+			if firing := len(got) > 0 && got[0].Status == StatusFiring; firing != tt.firing {
+				t.Errorf("firing = %v, want %v (%+v)", firing, tt.firing, got)
+			}
+		})
+	}
+}
+
+func TestForgetResolves(t *testing.T) {
+	e, _ := testEngine(config.AlertRule{Name: "down", Type: config.AlertOffline, RepeatInterval: time.Hour})
+	e.Evaluate(offline())
+	drain(e)
+
+	e.Forget(testStream)
+	got := drain(e)
+	if len(got) != 1 || got[0].Status != StatusResolved {
+		t.Fatalf("Forget() notifications = %+v, want one resolved", got)
+	}
+	if len(e.states) != 0 {
+		t.Errorf("states left after Forget: %d", len(e.states))
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	received := make(chan Notification, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- n
+	}))
+	defer server.Close()
+
+	cfg := config.Alerts{
+		Webhooks: []config.Webhook{
+			{Name: "noc", URL: server.URL, Timeout: time.Second, Headers: map[string]string{"Authorization": "Bearer secret"}},
+			{Name: "other", URL: server.URL + "/other", Timeout: time.Second},
+		},
+		Rules: []config.AlertRule{
+			{Name: "down", Type: config.AlertOffline, RepeatInterval: time.Hour, Webhooks: []string{"noc"}},
+		},
+	}
+	e := NewEngine(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	m := offline()
+	m.Description = "Test Stream| Provider"
+	e.Evaluate(m)
+
+	select {
+	case n := <-received:
+		if n.Status != StatusFiring || n.Rule != "down" || n.Stream != testStream || n.Description != "Test Stream| Provider" {
+			t.Errorf("notification = %+v", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+
+	// Webhook other не указан в правиле
+	select {
+	case n := <-received:
+		t.Errorf("unexpected second notification %+v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(config.Webhook{Name: "noc", URL: server.URL, Timeout: time.Second})
+	if err := webhook.Notify(context.Background(), Notification{Status: StatusFiring}); err == nil {
+		t.Error("Notify() error = nil for HTTP 500")
+	}
+}
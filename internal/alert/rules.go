@@ -0,0 +1,145 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// checkResult результат проверки условия правила
+type checkResult struct {
+	active  bool
+	value   float64
+	message string
+}
+
+// ccSample ошибки CC из одного обновления
+type ccSample struct {
+	at     time.Time
+	errors int64
+}
+
+// check проверяет условие правила на очередном обновлении метрик
+func check(rule config.AlertRule, m *tsp.StreamMetrics, st *ruleState, now time.Time) checkResult {
+	switch rule.Type {
+	case config.AlertOffline:
+		if m.Status {
+			return checkResult{message: "stream is online"}
+		}
+		down := now.Sub(m.LastSeen).Seconds()
+		if m.LastSeen.IsZero() {
+			down = 0
+		}
+		return checkResult{
+			active:  true,
+			value:   down,
+			message: "stream is offline",
+		}
+
+	case config.AlertBitrateLow:
+		bitrate := float64(m.Bitrate.TotalBPS)
+		return checkResult{
+			active:  m.Status && bitrate < rule.Threshold,
+			value:   bitrate,
+			message: fmt.Sprintf("bitrate %.0f bit/s, expected at least %.0f bit/s", bitrate, rule.Threshold),
+		}
+
+	case config.AlertBitrateHigh:
+		bitrate := float64(m.Bitrate.TotalBPS)
+		return checkResult{
+			active:  m.Status && bitrate > rule.Threshold,
+			value:   bitrate,
+			message: fmt.Sprintf("bitrate %.0f bit/s, expected at most %.0f bit/s", bitrate, rule.Threshold),
+		}
+
+	case config.AlertCCErrors:
+		total := ccErrorsInWindow(m, st, now, rule.Window)
+		return checkResult{
+			active:  float64(total) > rule.Threshold,
+			value:   float64(total),
+			message: fmt.Sprintf("%d CC errors in the last %s", total, rule.Window),
+		}
+
+	case config.AlertPIDMissing:
+		target := rule.PID
+		if target == "" {
+			target = rule.PIDType + " PID"
+		}
+		return checkResult{
+			active:  m.Status && !hasPID(m, rule),
+			message: fmt.Sprintf("%s is missing", target),
+		}
+
+	case config.AlertSilence:
+		pid, silent := silentAudio(m, rule)
+		if pid == "" {
+			return checkResult{message: "audio is not silent"}
+		}
+		return checkResult{
+			active:  m.Status,
+			value:   silent.Seconds(),
+			message: fmt.Sprintf("audio %s is silent for %s", pid, silent.Round(time.Second)),
+		}
+	}
+
+	return checkResult{}
+}
+
+// ccErrorsInWindow добавляет ошибки обновления в окно и возвращает их сумму за window
+func ccErrorsInWindow(m *tsp.StreamMetrics, st *ruleState, now time.Time, window time.Duration) int64 {
+	var errors int64
+	for _, count := range m.CCErrors {
+		errors += count
+	}
+	if errors > 0 {
+		st.samples = append(st.samples, ccSample{at: now, errors: errors})
+	}
+
+	// Отбрасываем ошибки старше окна
+	keep := 0
+	for keep < len(st.samples) && now.Sub(st.samples[keep].at) > window {
+		keep++
+	}
+	st.samples = st.samples[keep:]
+
+	var total int64
+	for _, sample := range st.samples {
+		total += sample.errors
+	}
+	return total
+}
+
+// hasPID проверяет, есть ли в потоке PID из правила pid_missing
+func hasPID(m *tsp.StreamMetrics, rule config.AlertRule) bool {
+	for _, pid := range m.PIDs {
+		if rule.PID != "" && strings.EqualFold(pid.PID, rule.PID) {
+			return true
+		}
+		if rule.PIDType != "" && (pid.Type == rule.PIDType || rule.PIDType == "subtitle" && pid.IsSubtitle) {
+			return true
+		}
+	}
+	return false
+}
+
+// silentAudio возвращает аудио PID с самой долгой тишиной (только PID правила, если он задан)
+// и её длительность; пустую строку, если тишины нет
+func silentAudio(m *tsp.StreamMetrics, rule config.AlertRule) (string, time.Duration) {
+	var silentPID string
+	var silent time.Duration
+	for _, pid := range m.PIDs {
+		if pid.Audio == nil || pid.Audio.Level == nil || !pid.Audio.Level.Silence {
+			continue
+		}
+		if rule.PID != "" && !strings.EqualFold(pid.PID, rule.PID) {
+			continue
+		}
+		if silentPID == "" || pid.Audio.Level.Silent > silent {
+			silentPID, silent = pid.PID, pid.Audio.Level.Silent
+		}
+	}
+	return silentPID, silent
+}
@@ -0,0 +1,358 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/mpegts"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// Report итоговый отчёт по записи MPEG-TS. Время отсчитывается от начала записи по PCR
+type Report struct {
+	File            string  `json:"file"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Packets         int64   `json:"packets"`
+	NullPackets     int64   `json:"null_packets"`
+	TSID            string  `json:"tsid,omitempty"`
+
+	BitrateAvg int64          `json:"bitrate_avg_bps"`
+	BitrateMin int64          `json:"bitrate_min_bps"` // по полным окнам
+	BitrateMax int64          `json:"bitrate_max_bps"`
+	Bitrate    []BitratePoint `json:"bitrate"` // по окнам
+
+	Programs []tsp.ProgramInfo `json:"programs"`
+	PIDs     []PID             `json:"pids"`
+	PCR      []PCR             `json:"pcr,omitempty"`
+	ETR290   []ETR290Error     `json:"etr290_errors"`
+}
+
+// BitratePoint битрейт одного окна
+type BitratePoint struct {
+	OffsetSeconds float64 `json:"offset_seconds"` // начало окна
+	TotalBPS      int64   `json:"total_bps"`
+	NetBPS        int64   `json:"net_bps"`
+}
+
+// PID итог по одному PID за всю запись
+type PID struct {
+	tsp.PIDInfo
+	Packets  int64 `json:"packets"`
+	CCErrors int64 `json:"cc_errors"`
+
+	fromPMT bool
+}
+
+// PCR итог по PCR одного PID
+type PCR struct {
+	PID         string        `json:"pid"`
+	Count       int           `json:"count"`
+	IntervalMax time.Duration `json:"interval_max_ns"`
+	AccuracyMax time.Duration `json:"accuracy_max_ns"`
+}
+
+// ETR290Error ошибка TR 101 290 за всю запись и время первого появления
+type ETR290Error struct {
+	tsp.ETR290Error
+	FirstSeconds float64 `json:"first_seconds"`
+}
+
+// Options параметры воспроизведения записи
+type Options struct {
+	Realtime bool          // в темпе записи по PCR, как живой поток
+	Window   time.Duration // окно временной шкалы битрейта; 0 = 1s
+}
+
+// File разбирает запись path и строит отчёт
+func File(ctx context.Context, path string, opts Options) (*Report, error) {
+	builder := NewBuilder(path)
+	replay := &tsp.FileReplay{Path: path, Realtime: opts.Realtime, Window: opts.Window}
+	if err := replay.Run(ctx, mpegts.NewDemuxer(), time.Now(), builder.Add); err != nil {
+		return nil, err
+	}
+	return builder.Report(), nil
+}
+
+// Builder накапливает отчёты демультиплексора по окнам
+type Builder struct {
+	file     string
+	start    time.Time
+	end      time.Time
+	bitrate  []BitratePoint
+	packets  int64
+	nulls    int64
+	pids     map[uint16]*PID
+	pcr      map[uint16]*PCR
+	etr290   map[string]*ETR290Error
+	tsid     string
+	programs []tsp.ProgramInfo // из последнего окна с PAT
+}
+
+// NewBuilder создаёт Builder для записи file
+func NewBuilder(file string) *Builder {
+	return &Builder{
+		file:   file,
+		pids:   make(map[uint16]*PID),
+		pcr:    make(map[uint16]*PCR),
+		etr290: make(map[string]*ETR290Error),
+	}
+}
+
+// Add учитывает отчёт очередного окна
+func (b *Builder) Add(r *mpegts.Report) {
+	if b.start.IsZero() {
+		b.start = r.Start
+	}
+	b.end = r.End
+	b.packets += r.Packets
+	b.nulls += r.NullPackets
+	b.bitrate = append(b.bitrate, BitratePoint{
+		OffsetSeconds: r.Start.Sub(b.start).Seconds(),
+		TotalBPS:      r.Bitrate(),
+		NetBPS:        r.NetBitrate(),
+	})
+
+	// Тип и кодек PID и программы берём так же, как для живого потока
+	metrics := tsp.MetricsFromReport(r, b.file, "")
+	if metrics.TSID != "" {
+		b.tsid = metrics.TSID
+	}
+	if len(metrics.Programs) > 0 {
+		b.programs = metrics.Programs
+	}
+	// PID из PMT описаны точнее, чем PID вне PMT: описание из PMT не перетираем
+	for i, pids := range [][]tsp.PIDInfo{metrics.PIDs, metrics.SystemPIDs} {
+		for _, info := range pids {
+			pid := uint16(info.PIDDecimal)
+			if b.pids[pid] == nil {
+				b.pids[pid] = &PID{}
+			} else if i == 1 && b.pids[pid].fromPMT {
+				continue
+			}
+			b.pids[pid].PIDInfo = info
+			b.pids[pid].fromPMT = i == 0
+		}
+	}
+	for pid, packets := range r.PIDPackets {
+		if b.pids[pid] == nil {
+			b.pids[pid] = &PID{PIDInfo: tsp.PIDInfo{PID: fmt.Sprintf("0x%04X", pid), PIDDecimal: int(pid)}}
+		}
+		b.pids[pid].Packets += packets
+		b.pids[pid].CCErrors += r.CCErrors[pid]
+	}
+
+	for pid, stats := range r.PCR {
+		p := b.pcr[pid]
+		if p == nil {
+			p = &PCR{PID: fmt.Sprintf("0x%04X", pid)}
+			b.pcr[pid] = p
+		}
+		p.Count += stats.Count
+		p.IntervalMax = max(p.IntervalMax, stats.IntervalMax)
+		p.AccuracyMax = max(p.AccuracyMax, stats.AccuracyMax)
+	}
+
+	for _, e := range metrics.ETR290Errors {
+		key := e.Indicator + "/" + e.PID
+		if b.etr290[key] == nil {
+			b.etr290[key] = &ETR290Error{
+				ETR290Error:  tsp.ETR290Error{Priority: e.Priority, Indicator: e.Indicator, PID: e.PID},
+				FirstSeconds: r.Start.Sub(b.start).Seconds(),
+			}
+		}
+		b.etr290[key].Count += e.Count
+	}
+}
+
+// Report возвращает отчёт по всем добавленным окнам
+func (b *Builder) Report() *Report {
+	report := &Report{
+		File:            b.file,
+		DurationSeconds: b.end.Sub(b.start).Seconds(),
+		Packets:         b.packets,
+		NullPackets:     b.nulls,
+		TSID:            b.tsid,
+		Bitrate:         b.bitrate,
+		Programs:        []tsp.ProgramInfo{},
+		PIDs:            []PID{},
+		ETR290:          []ETR290Error{},
+	}
+
+	// Последнее окно обычно неполное, в минимум и максимум его не берём
+	full := b.bitrate
+	if len(full) > 1 {
+		full = full[:len(full)-1]
+	}
+	for i, point := range full {
+		if i == 0 || point.TotalBPS < report.BitrateMin {
+			report.BitrateMin = point.TotalBPS
+		}
+		report.BitrateMax = max(report.BitrateMax, point.TotalBPS)
+	}
+	if seconds := report.DurationSeconds; seconds > 0 {
+		report.BitrateAvg = int64(float64(report.Packets*mpegts.PacketSize*8) / seconds)
+	}
+
+	for _, pid := range b.pids {
+		if seconds := report.DurationSeconds; seconds > 0 {
+			pid.BitrateBPS = int64(float64(pid.Packets*mpegts.PacketSize*8) / seconds)
+		}
+		report.PIDs = append(report.PIDs, *pid)
+	}
+	sort.Slice(report.PIDs, func(i, j int) bool { return report.PIDs[i].PIDDecimal < report.PIDs[j].PIDDecimal })
+
+	// Битрейт программ — средний за запись, а не последнего окна
+	bitrates := make(map[string]int64, len(report.PIDs))
+	for _, pid := range report.PIDs {
+		bitrates[pid.PID] = pid.BitrateBPS
+	}
+	for _, program := range b.programs {
+		program.BitrateBPS = bitrates[program.PMTPID]
+		for _, pid := range program.PIDs {
+			program.BitrateBPS += bitrates[pid]
+		}
+		report.Programs = append(report.Programs, program)
+	}
+
+	for _, p := range b.pcr {
+		report.PCR = append(report.PCR, *p)
+	}
+	sort.Slice(report.PCR, func(i, j int) bool { return report.PCR[i].PID < report.PCR[j].PID })
+
+	for _, e := range b.etr290 {
+		report.ETR290 = append(report.ETR290, *e)
+	}
+	sort.Slice(report.ETR290, func(i, j int) bool {
+		a, b := report.ETR290[i], report.ETR290[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if a.Indicator != b.Indicator {
+			return a.Indicator < b.Indicator
+		}
+		return a.PID < b.PID
+	})
+
+	return report
+}
+
+// WriteText выводит отчёт в читаемом виде
+func (r *Report) WriteText(w io.Writer) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "File:     %s\n", r.File)
+	fmt.Fprintf(&sb, "Duration: %.1fs (by PCR), %d packets, %d null\n", r.DurationSeconds, r.Packets, r.NullPackets)
+	if r.TSID != "" {
+		fmt.Fprintf(&sb, "TSID:     %s\n", r.TSID)
+	}
+	fmt.Fprintf(&sb, "Bitrate:  avg %s, min %s, max %s\n",
+		formatBitrate(r.BitrateAvg), formatBitrate(r.BitrateMin), formatBitrate(r.BitrateMax))
+
+	fmt.Fprintf(&sb, "\nPrograms:\n")
+	if len(r.Programs) == 0 {
+		fmt.Fprintf(&sb, "  none (no PAT/PMT)\n")
+	}
+	for _, p := range r.Programs {
+		pcrPID := p.PCRPID
+		if pcrPID == "" {
+			pcrPID = "-" // PMT не получена
+		}
+		fmt.Fprintf(&sb, "  %5d  PMT %s  PCR %-6s  %12s  %s", p.Number, p.PMTPID, pcrPID,
+			formatBitrate(p.BitrateBPS), strings.Join(p.PIDs, " "))
+		if p.ServiceName != "" {
+			fmt.Fprintf(&sb, "  %q (%s, %s)", p.ServiceName, p.Provider, p.ServiceType)
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "\nPIDs:\n")
+	fmt.Fprintf(&sb, "  %-6s  %-6s  %-12s  %-4s  %12s  %10s  %9s\n", "PID", "type", "codec", "lang", "bitrate", "packets", "CC errors")
+	for _, p := range r.PIDs {
+		fmt.Fprintf(&sb, "  %-6s  %-6s  %-12s  %-4s  %12s  %10d  %9d\n",
+			p.PID, p.Type, p.Codec, p.Language, formatBitrate(p.BitrateBPS), p.Packets, p.CCErrors)
+	}
+
+	var video []PID
+	for _, p := range r.PIDs {
+		if p.Video != nil {
+			video = append(video, p)
+		}
+	}
+	if len(video) > 0 {
+		fmt.Fprintf(&sb, "\nVideo:\n")
+		for _, p := range video {
+			v := p.Video
+			scan := "p"
+			if v.Interlaced {
+				scan = "i"
+			}
+			fmt.Fprintf(&sb, "  %s  %s  %dx%d%s %g fps  %s@%s  %s", p.PID, p.Codec, v.Width, v.Height, scan, v.FrameRate, v.Profile, v.Level, v.Aspect)
+			if v.GOPLength > 0 {
+				fmt.Fprintf(&sb, "  GOP %d", v.GOPLength)
+			}
+			if v.IDRInterval > 0 {
+				fmt.Fprintf(&sb, "  IDR every %.2fs", v.IDRInterval.Seconds())
+			}
+			if v.SuspectFrozen {
+				fmt.Fprintf(&sb, "  suspect frozen (%s)", v.FrozenReason)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	var audio []PID
+	for _, p := range r.PIDs {
+		if p.Audio != nil {
+			audio = append(audio, p)
+		}
+	}
+	if len(audio) > 0 {
+		fmt.Fprintf(&sb, "\nAudio:\n")
+		for _, p := range audio {
+			a := p.Audio
+			fmt.Fprintf(&sb, "  %s  %s  %s  %d Hz  %s  %d kbit/s", p.PID, p.Codec, a.Format, a.SampleRate, a.Layout, a.BitrateBPS/1000)
+			if a.Dialnorm != 0 {
+				fmt.Fprintf(&sb, "  dialnorm %d dB", a.Dialnorm)
+			}
+			if l := a.Level; l != nil {
+				fmt.Fprintf(&sb, "  RMS %.1f dBFS  peak %.1f dBFS", l.RMSDBFS, l.PeakDBFS)
+				if l.Silence {
+					fmt.Fprintf(&sb, "  silence %s", l.Silent.Round(time.Second))
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(r.PCR) > 0 {
+		fmt.Fprintf(&sb, "\nPCR:\n")
+		for _, p := range r.PCR {
+			fmt.Fprintf(&sb, "  %s  %d PCR, interval max %v, accuracy max %v\n", p.PID, p.Count, p.IntervalMax, p.AccuracyMax)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nETR 290 errors:\n")
+	if len(r.ETR290) == 0 {
+		fmt.Fprintf(&sb, "  none\n")
+	}
+	for _, e := range r.ETR290 {
+		fmt.Fprintf(&sb, "  %d  %-22s  %-6s  %6d  first at %.1fs\n", e.Priority, e.Indicator, e.PID, e.Count, e.FirstSeconds)
+	}
+
+	fmt.Fprintf(&sb, "\nBitrate timeline:\n")
+	for _, point := range r.Bitrate {
+		fmt.Fprintf(&sb, "  %7.1fs  %12s  net %12s\n", point.OffsetSeconds, formatBitrate(point.TotalBPS), formatBitrate(point.NetBPS))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatBitrate форматирует битрейт в Mbit/s
+func formatBitrate(bps int64) string {
+	return fmt.Sprintf("%.3f Mbit/s", float64(bps)/1e6)
+}
@@ -0,0 +1,72 @@
+package analyze
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/mpegts"
+)
+
+func TestBuilder(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	window := func(i int, packets int64, duration time.Duration) *mpegts.Report {
+		return &mpegts.Report{
+			Start:      start.Add(time.Duration(i) * time.Second),
+			End:        start.Add(time.Duration(i)*time.Second + duration),
+			Packets:    packets,
+			PIDPackets: map[uint16]int64{0x0100: packets},
+			CCErrors:   map[uint16]int64{},
+			ETR290:     map[mpegts.ETR290Error]int64{},
+			PCR:        map[uint16]*mpegts.PCRStats{},
+		}
+	}
+
+	first := window(0, 1000, time.Second)
+	first.Programs = []mpegts.Program{{Number: 1, PMTPID: 0x0020, PCRPID: 0x0100,
+		Streams: []mpegts.ElementaryStream{{Type: 0x1B, PID: 0x0100}}, PMT: true}}
+	second := window(1, 2000, time.Second)
+	second.CCErrors[0x0100] = 2
+	second.ETR290[mpegts.ETR290Error{Indicator: mpegts.CCError, PID: 0x0100}] = 2
+	second.PCR[0x0100] = &mpegts.PCRStats{Count: 25, IntervalMax: 45 * time.Millisecond}
+	last := window(2, 100, 200*time.Millisecond)
+
+	builder := NewBuilder("capture.ts")
+	for _, r := range []*mpegts.Report{first, second, last} {
+		builder.Add(r)
+	}
+	report := builder.Report()
+
+	if report.DurationSeconds != 2.2 || report.Packets != 3100 || len(report.Bitrate) != 3 {
+		t.Errorf("report = %.1fs, %d packets, %d windows", report.DurationSeconds, report.Packets, len(report.Bitrate))
+	}
+	// Неполное последнее окно не влияет на минимум и максимум
+	if report.BitrateMin != 1000*188*8 || report.BitrateMax != 2000*188*8 {
+		t.Errorf("bitrate min/max = %d/%d", report.BitrateMin, report.BitrateMax)
+	}
+	if len(report.PIDs) != 1 || report.PIDs[0].PID != "0x0100" || report.PIDs[0].Packets != 3100 || report.PIDs[0].CCErrors != 2 {
+		t.Errorf("pids = %+v", report.PIDs)
+	}
+	if len(report.Programs) != 1 || report.Programs[0].Number != 1 || report.PIDs[0].Type != "video" ||
+		report.Programs[0].BitrateBPS != report.PIDs[0].BitrateBPS {
+		t.Errorf("programs = %+v, pids = %+v", report.Programs, report.PIDs)
+	}
+	if len(report.ETR290) != 1 || report.ETR290[0].Indicator != "CC_error" || report.ETR290[0].Count != 2 ||
+		report.ETR290[0].FirstSeconds != 1 {
+		t.Errorf("etr290 = %+v", report.ETR290)
+	}
+	if len(report.PCR) != 1 || report.PCR[0].Count != 25 || report.PCR[0].IntervalMax != 45*time.Millisecond {
+		t.Errorf("pcr = %+v", report.PCR)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Duration: 2.2s", "0x0100", "CC_error", "first at 1.0s", "PMT 0x0020"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text report has no %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alerts настройки встроенного алертинга
+type Alerts struct {
+	RepeatInterval time.Duration `yaml:"repeat_interval"` // Повтор уведомления о всё ещё активном алерте
+	Webhooks       []Webhook     `yaml:"webhooks"`        // Получатели уведомлений
+	Rules          []AlertRule   `yaml:"rules"`           // Правила
+}
+
+// Webhook получатель уведомлений (JSON POST)
+type Webhook struct {
+	Name    string            `yaml:"name"`
+	URL     string            `yaml:"url"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Headers map[string]string `yaml:"headers"` // Например Authorization
+}
+
+// pidTypes типы элементарных потоков PMT для pid_missing: PIDInfo.Type, а subtitle
+// по PIDInfo.IsSubtitle. PSI и null PID вне PMT правилом не проверяются
+var pidTypes = map[string]bool{"video": true, "audio": true, "subtitle": true, "data": true, "other": true}
+
+// AlertRule правило алерта, проверяется на каждом обновлении метрик потока
+type AlertRule struct {
+	Name      string  `yaml:"name"`
+	Type      string  `yaml:"type"`      // offline, bitrate_low, bitrate_high, cc_errors, pid_missing, audio_silence
+	Threshold float64 `yaml:"threshold"` // bit/s для bitrate_*, число ошибок за window для cc_errors
+	PID       string  `yaml:"pid"`       // pid_missing: конкретный PID (0x0066); audio_silence: только этот PID
+	PIDType   string  `yaml:"pid_type"`  // pid_missing: video, audio, subtitle, data, other
+
+	For            time.Duration `yaml:"for"`             // Сколько условие должно держаться до срабатывания
+	ResolveFor     time.Duration `yaml:"resolve_for"`     // Сколько условие должно отсутствовать до resolved
+	RepeatInterval time.Duration `yaml:"repeat_interval"` // По умолчанию Alerts.RepeatInterval
+	Window         time.Duration `yaml:"window"`          // cc_errors: окно подсчёта ошибок
+
+	Streams  []string `yaml:"streams"`  // URL потоков; пусто = все
+	Webhooks []string `yaml:"webhooks"` // Имена webhooks; пусто = все
+}
+
+// Типы правил алертов
+const (
+	AlertOffline     = "offline"
+	AlertBitrateLow  = "bitrate_low"
+	AlertBitrateHigh = "bitrate_high"
+	AlertCCErrors    = "cc_errors"
+	AlertPIDMissing  = "pid_missing"
+	AlertSilence     = "audio_silence"
+)
+
+// Значения по умолчанию для алертов
+const (
+	defaultRepeatInterval = time.Hour
+	defaultWebhookTimeout = 5 * time.Second
+	defaultCCWindow       = time.Minute
+)
+
+// validate проверяет правила и заполняет значения по умолчанию
+func (a *Alerts) validate() error {
+	if a.RepeatInterval == 0 {
+		a.RepeatInterval = defaultRepeatInterval
+	}
+
+	webhooks := make(map[string]bool)
+	for i := range a.Webhooks {
+		webhook := &a.Webhooks[i]
+		if webhook.Name == "" {
+			return fmt.Errorf("webhook %d: name is required", i)
+		}
+		if webhooks[webhook.Name] {
+			return fmt.Errorf("webhook %s: duplicate name", webhook.Name)
+		}
+		webhooks[webhook.Name] = true
+
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook %s: invalid url %q", webhook.Name, webhook.URL)
+		}
+		if webhook.Timeout == 0 {
+			webhook.Timeout = defaultWebhookTimeout
+		}
+	}
+
+	rules := make(map[string]bool)
+	for i := range a.Rules {
+		rule := &a.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("alert rule %d: name is required", i)
+		}
+		if rules[rule.Name] {
+			return fmt.Errorf("alert rule %s: duplicate name", rule.Name)
+		}
+		rules[rule.Name] = true
+
+		switch rule.Type {
+		case AlertOffline:
+		case AlertBitrateLow, AlertBitrateHigh:
+			if rule.Threshold <= 0 {
+				return fmt.Errorf("alert rule %s: threshold (bit/s) is required for %s", rule.Name, rule.Type)
+			}
+		case AlertCCErrors:
+			if rule.Window == 0 {
+				rule.Window = defaultCCWindow
+			}
+		case AlertPIDMissing:
+			if (rule.PID == "") == (rule.PIDType == "") {
+				return fmt.Errorf("alert rule %s: exactly one of pid or pid_type is required", rule.Name)
+			}
+			if rule.PIDType != "" && !pidTypes[rule.PIDType] {
+				return fmt.Errorf("alert rule %s: invalid pid_type %q (must be video, audio, subtitle, data or other)", rule.Name, rule.PIDType)
+			}
+		case AlertSilence:
+		default:
+			return fmt.Errorf("alert rule %s: unknown type %q", rule.Name, rule.Type)
+		}
+
+		if rule.PID != "" {
+			pid, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(rule.PID), "0x"), 16, 13)
+			if err != nil {
+				return fmt.Errorf("alert rule %s: invalid pid %q", rule.Name, rule.PID)
+			}
+			rule.PID = fmt.Sprintf("0x%04X", pid) // как в PIDInfo.PID
+		}
+
+		if rule.For < 0 || rule.ResolveFor < 0 || rule.RepeatInterval < 0 {
+			return fmt.Errorf("alert rule %s: durations must not be negative", rule.Name)
+		}
+		if rule.RepeatInterval == 0 {
+			rule.RepeatInterval = a.RepeatInterval
+		}
+
+		for _, name := range rule.Webhooks {
+			if !webhooks[name] {
+				return fmt.Errorf("alert rule %s: unknown webhook %q", rule.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -10,18 +14,63 @@ import (
 
 // Config содержит всю конфигурацию приложения
 type Config struct {
-	Interface   string        `yaml:"interface"`   // IP адрес интерфейса для multicast
+	Interface   string        `yaml:"interface"`    // Интерфейс для multicast по умолчанию: IP адрес или имя (eth1)
 	MetricsPort int           `yaml:"metrics_port"` // Порт для Prometheus metrics
 	Timeout     time.Duration `yaml:"timeout"`      // Таймаут для команд tsp
+	WatchConfig bool          `yaml:"watch_config"` // Перечитывать конфиг при изменении файла (кроме SIGHUP)
 	Streams     []Stream      `yaml:"streams"`      // Список потоков для мониторинга
+	Groups      []Group       `yaml:"groups"`       // Группы потоков с настройками по умолчанию
+	Alerts      Alerts        `yaml:"alerts"`       // Встроенный алертинг (webhooks)
+	Events      Events        `yaml:"events"`       // История событий потоков
+}
+
+// Events настройки истории событий (online/offline, изменения PID и сервиса)
+type Events struct {
+	Path      string        `yaml:"path"`      // Файл истории (JSON lines); пусто = только в памяти
+	Retention time.Duration `yaml:"retention"` // Сколько хранить события (по умолчанию 90 дней)
 }
 
 // Stream описывает один MPEG-TS поток
 type Stream struct {
-	URL         string `yaml:"url"`         // Multicast адрес (например: 233.198.134.1:3333)
-	Description string `yaml:"description"` // Описание потока
+	URL         string            `yaml:"url"`         // Multicast адрес (например: 233.198.134.1:3333) или запись file:///path.ts
+	Description string            `yaml:"description"` // Описание потока; устаревший формат "Name| Provider| HD| multicast| ID"
+	Name        string            `yaml:"name"`        // Название канала
+	Provider    string            `yaml:"provider"`    // Провайдер контента
+	Format      string            `yaml:"format"`      // Формат: HD, SD, UHD...
+	ChannelID   string            `yaml:"channel_id"`  // Идентификатор канала
+	Labels      map[string]string `yaml:"labels"`      // Дополнительные метки Prometheus
+	Input       string            `yaml:"input"`       // Способ приёма: tsp (по умолчанию), native или rtp; PCR анализируется только в native и rtp
+	PIDBitrate  bool              `yaml:"pid_bitrate"` // Битрейт по PID для tsp (плагин analyze); native и rtp измеряют его всегда
+
+	Group      string             `yaml:"group"`      // Имя группы, настройки которой наследуются
+	Interface  string             `yaml:"interface"`  // Интерфейс для multicast (IP или имя); по умолчанию группы или глобальный
+	Source     string             `yaml:"source"`     // Адрес источника для SSM (S,G); пусто = любой источник
+	Bitrate    BitrateRange       `yaml:"bitrate"`    // Ожидаемый диапазон битрейта (для алертов bitrate_*)
+	Thresholds map[string]float64 `yaml:"thresholds"` // Пороги алертов по имени правила
+	Webhooks   []string           `yaml:"webhooks"`   // Куда отправлять алерты потока вместо webhooks правила
+
+	MDIInterval time.Duration `yaml:"mdi_interval"` // Интервал расчёта MDI (RFC 4445), native/rtp; по умолчанию 1s
+
+	SilenceLevel    float64       `yaml:"silence_level"`    // Порог тишины MP2 аудио, dBFS (native/rtp); по умолчанию -60
+	SilenceDuration time.Duration `yaml:"silence_duration"` // Сколько должна длиться тишина; по умолчанию 10s
 }
 
+// reservedLabels метки, которые выставляет сам экспортер
+var reservedLabels = []string{"stream", "description", "name", "provider", "format", "channel_id", "source"}
+
+// labelNameRe допустимое имя метки Prometheus
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// FileScheme префикс url потока, воспроизводимого из записи MPEG-TS
+const FileScheme = "file://"
+
+// Способы приёма потока
+const (
+	InputTSP    = "tsp"    // внешний процесс tsp (TSDuck); битрейт по PID с pid_bitrate
+	InputNative = "native" // встроенный разбор MPEG-TS на Go
+	InputRTP    = "rtp"    // как native, но датаграммы в RTP: потери, дубликаты, порядок, jitter
+)
+
 // Load загружает конфигурацию из YAML файла
 func Load(path string) (*Config, error) {
 	// Читаем файл
@@ -46,10 +95,6 @@ func Load(path string) (*Config, error) {
 
 // Validate проверяет корректность конфигурации
 func (c *Config) Validate() error {
-	if c.Interface == "" {
-		return fmt.Errorf("interface is required")
-	}
-
 	if c.MetricsPort <= 0 || c.MetricsPort > 65535 {
 		return fmt.Errorf("invalid metrics_port: %d (must be 1-65535)", c.MetricsPort)
 	}
@@ -62,19 +107,193 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("no streams configured")
 	}
 
-	// Проверяем каждый поток
-	for i, stream := range c.Streams {
+	if err := c.Alerts.validate(); err != nil {
+		return fmt.Errorf("alerts: %w", err)
+	}
+
+	// Поля из описания заполняются до групп: значения потока важнее значений группы
+	urls := make(map[string]int, len(c.Streams))
+	for i := range c.Streams {
+		stream := &c.Streams[i]
 		if stream.URL == "" {
 			return fmt.Errorf("stream %d: url is required", i)
 		}
+		// Потоки различаются по url: runner, метрики и события второго затёрли бы первый
+		if first, ok := urls[stream.URL]; ok {
+			return fmt.Errorf("stream %d: duplicate url %s (already used by stream %d)", i, stream.URL, first)
+		}
+		urls[stream.URL] = i
+		if stream.Description == "" && stream.Name == "" {
+			return fmt.Errorf("stream %d: name or description is required", i)
+		}
+		if stream.Description != "" {
+			stream.applyDescription()
+		}
+	}
+	if err := c.resolveGroups(); err != nil {
+		return err
+	}
+
+	rules := make(map[string]bool, len(c.Alerts.Rules))
+	for _, rule := range c.Alerts.Rules {
+		rules[rule.Name] = true
+	}
+	webhooks := make(map[string]bool, len(c.Alerts.Webhooks))
+	for _, webhook := range c.Alerts.Webhooks {
+		webhooks[webhook.Name] = true
+	}
+
+	// Проверяем каждый поток с учётом настроек группы
+	for i := range c.Streams {
+		stream := &c.Streams[i]
 		if stream.Description == "" {
-			return fmt.Errorf("stream %d: description is required", i)
+			stream.applyDescription() // описание из полей, включая провайдера группы
+		}
+		if path, ok := stream.FilePath(); ok {
+			// Запись воспроизводится встроенным демультиплексором, сеть не нужна
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("stream %d: %w", i, err)
+			}
+			if stream.Input == "" {
+				stream.Input = InputNative
+			}
+			if stream.Input != InputNative || stream.Source != "" {
+				return fmt.Errorf("stream %d: %s urls support only input %s without source", i, FileScheme, InputNative)
+			}
+		} else if stream.Interface == "" {
+			return fmt.Errorf("stream %d: interface is required (global, group or stream)", i)
+		}
+		if stream.Source != "" {
+			ip := net.ParseIP(stream.Source)
+			if ip == nil || ip.To4() == nil || ip.IsMulticast() || ip.IsUnspecified() {
+				return fmt.Errorf("stream %d: invalid source %q (unicast IPv4 address expected)", i, stream.Source)
+			}
+			stream.Source = ip.To4().String()
+		}
+		if err := stream.Bitrate.validate(); err != nil {
+			return fmt.Errorf("stream %d: %w", i, err)
+		}
+		switch {
+		case stream.MDIInterval == 0:
+			stream.MDIInterval = time.Second // default
+		case stream.MDIInterval < 100*time.Millisecond || stream.MDIInterval > time.Minute:
+			return fmt.Errorf("stream %d: mdi_interval %s out of range (100ms-1m)", i, stream.MDIInterval)
+		}
+		switch {
+		case stream.SilenceLevel == 0:
+			stream.SilenceLevel = -60 // default
+		case stream.SilenceLevel < -120 || stream.SilenceLevel > 0:
+			return fmt.Errorf("stream %d: silence_level %g out of range (-120..0 dBFS)", i, stream.SilenceLevel)
+		}
+		switch {
+		case stream.SilenceDuration == 0:
+			stream.SilenceDuration = 10 * time.Second // default
+		case stream.SilenceDuration < time.Second || stream.SilenceDuration > time.Hour:
+			return fmt.Errorf("stream %d: silence_duration %s out of range (1s-1h)", i, stream.SilenceDuration)
+		}
+		for name, threshold := range stream.Thresholds {
+			if !rules[name] {
+				return fmt.Errorf("stream %d: threshold for unknown alert rule %q", i, name)
+			}
+			if threshold < 0 {
+				return fmt.Errorf("stream %d: threshold for %s must not be negative", i, name)
+			}
+		}
+		for _, name := range stream.Webhooks {
+			if !webhooks[name] {
+				return fmt.Errorf("stream %d: unknown webhook %q", i, name)
+			}
+		}
+
+		for key := range stream.Labels {
+			if !labelNameRe.MatchString(key) || strings.HasPrefix(key, "__") {
+				return fmt.Errorf("stream %d: invalid label name %q", i, key)
+			}
+			for _, reserved := range reservedLabels {
+				if key == reserved {
+					return fmt.Errorf("stream %d: label %q is reserved", i, key)
+				}
+			}
+		}
+
+		switch stream.Input {
+		case "":
+			stream.Input = InputTSP // default
+		case InputTSP, InputNative, InputRTP:
+		default:
+			return fmt.Errorf("stream %d: invalid input %q (must be %s, %s or %s)", i, stream.Input, InputTSP, InputNative, InputRTP)
 		}
 	}
 
+	if c.Events.Retention == 0 {
+		c.Events.Retention = 90 * 24 * time.Hour // default
+	}
+
 	return nil
 }
 
+// applyDescription заполняет пустые поля из описания в устаревшем формате
+// "Name| Provider| HD| multicast| ID001" (четвёртое поле не используется),
+// а при пустом описании собирает его из полей
+func (s *Stream) applyDescription() {
+	if s.Description == "" {
+		var parts []string
+		for _, field := range []string{s.Name, s.Provider, s.Format, s.ChannelID} {
+			if field != "" {
+				parts = append(parts, field)
+			}
+		}
+		s.Description = strings.Join(parts, "| ")
+		return
+	}
+
+	fields := strings.Split(s.Description, "|")
+	for i, target := range []*string{&s.Name, &s.Provider, &s.Format, nil, &s.ChannelID} {
+		if target == nil || i >= len(fields) || *target != "" {
+			continue
+		}
+		*target = strings.TrimSpace(fields[i])
+	}
+}
+
+// FilePath возвращает путь к записи, если поток воспроизводится из файла
+func (s Stream) FilePath() (string, bool) {
+	return strings.CutPrefix(s.URL, FileScheme)
+}
+
+// LabelKeys возвращает отсортированные имена дополнительных меток всех потоков
+func (c *Config) LabelKeys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, stream := range c.Streams {
+		for key := range stream.Labels {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Interfaces возвращает отсортированный список интерфейсов всех потоков (кроме записей без интерфейса)
+func (c *Config) Interfaces() []string {
+	seen := make(map[string]bool)
+	var interfaces []string
+	for _, stream := range c.Streams {
+		if _, ok := stream.FilePath(); ok && stream.Interface == "" {
+			continue
+		}
+		if !seen[stream.Interface] {
+			seen[stream.Interface] = true
+			interfaces = append(interfaces, stream.Interface)
+		}
+	}
+	sort.Strings(interfaces)
+	return interfaces
+}
+
 // StreamCount возвращает количество потоков
 func (c *Config) StreamCount() int {
 	return len(c.Streams)
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,6 +60,10 @@ streams:
 	if cfg.Streams[0].URL != "233.198.134.1:3333" {
 		t.Errorf("Stream[0].URL = %s, want 233.198.134.1:3333", cfg.Streams[0].URL)
 	}
+
+	if cfg.Streams[0].Input != InputTSP {
+		t.Errorf("Stream[0].Input = %s, want %s", cfg.Streams[0].Input, InputTSP)
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -88,6 +94,83 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "interface per stream without global",
+			config: Config{
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test", Interface: "eth1"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ssm source",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "232.198.134.1:3333", Description: "Test", Source: "10.1.1.1"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "multicast source",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "232.198.134.1:3333", Description: "Test", Source: "232.198.134.1"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "mdi interval too short",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test", MDIInterval: 10 * time.Millisecond},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate url",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test"},
+					{URL: "233.198.134.1:3333", Description: "Test 2"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "positive silence level",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test", SilenceLevel: 6},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "silence duration too short",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test", SilenceDuration: 100 * time.Millisecond},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid port",
 			config: Config{
@@ -99,6 +182,118 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid input",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test", Input: "udp"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "alert rule with unknown webhook",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test"},
+				},
+				Alerts: Alerts{
+					Webhooks: []Webhook{{Name: "noc", URL: "http://127.0.0.1:8080/hook"}},
+					Rules:    []AlertRule{{Name: "down", Type: AlertOffline, Webhooks: []string{"chat"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "bitrate alert without threshold",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test"},
+				},
+				Alerts: Alerts{
+					Rules: []AlertRule{{Name: "low", Type: AlertBitrateLow}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pid_missing with unknown pid_type",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test"},
+				},
+				Alerts: Alerts{
+					Rules: []AlertRule{{Name: "no video", Type: AlertPIDMissing, PIDType: "Video"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pid_missing with subtitle pid_type",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Description: "Test"},
+				},
+				Alerts: Alerts{
+					Rules: []AlertRule{{Name: "no subtitles", Type: AlertPIDMissing, PIDType: "subtitle"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "name without description",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Name: "Test"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "no name and no description",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Provider: "Provider"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid label name",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Name: "Test", Labels: map[string]string{"region-1": "msk"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "reserved label name",
+			config: Config{
+				Interface:   "172.22.2.154",
+				MetricsPort: 9090,
+				Streams: []Stream{
+					{URL: "233.198.134.1:3333", Name: "Test", Labels: map[string]string{"provider": "Other"}},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "no streams",
 			config: Config{
@@ -119,3 +314,217 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAlertDefaults(t *testing.T) {
+	cfg := Config{
+		Interface:   "172.22.2.154",
+		MetricsPort: 9090,
+		Streams: []Stream{
+			{URL: "233.198.134.1:3333", Description: "Test"},
+		},
+		Alerts: Alerts{
+			Webhooks: []Webhook{{Name: "noc", URL: "http://127.0.0.1:8080/hook"}},
+			Rules: []AlertRule{
+				{Name: "cc", Type: AlertCCErrors},
+				{Name: "video", Type: AlertPIDMissing, PID: "0x66", RepeatInterval: 5 * time.Minute},
+				{Name: "silence", Type: AlertSilence, PID: "0xca"},
+			},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if cfg.Alerts.Webhooks[0].Timeout != 5*time.Second {
+		t.Errorf("webhook timeout = %v, want 5s", cfg.Alerts.Webhooks[0].Timeout)
+	}
+	if cfg.Alerts.Rules[0].RepeatInterval != time.Hour || cfg.Alerts.Rules[0].Window != time.Minute {
+		t.Errorf("cc rule defaults = %+v", cfg.Alerts.Rules[0])
+	}
+	if cfg.Alerts.Rules[1].PID != "0x0066" || cfg.Alerts.Rules[1].RepeatInterval != 5*time.Minute {
+		t.Errorf("pid rule = %+v", cfg.Alerts.Rules[1])
+	}
+	if cfg.Alerts.Rules[2].PID != "0x00CA" {
+		t.Errorf("silence rule = %+v", cfg.Alerts.Rules[2])
+	}
+}
+
+func TestStreamDescriptionFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream Stream
+		want   Stream
+	}{
+		{
+			name:   "legacy description",
+			stream: Stream{Description: "Stream Name| Provider| HD| multicast| ID001"},
+			want:   Stream{Description: "Stream Name| Provider| HD| multicast| ID001", Name: "Stream Name", Provider: "Provider", Format: "HD", ChannelID: "ID001"},
+		},
+		{
+			name:   "short legacy description",
+			stream: Stream{Description: "Stream Name| Provider"},
+			want:   Stream{Description: "Stream Name| Provider", Name: "Stream Name", Provider: "Provider"},
+		},
+		{
+			name:   "explicit fields win",
+			stream: Stream{Description: "Old Name| Old Provider| SD", Name: "New Name", Format: "HD"},
+			want:   Stream{Description: "Old Name| Old Provider| SD", Name: "New Name", Provider: "Old Provider", Format: "HD"},
+		},
+		{
+			name:   "description from fields",
+			stream: Stream{Name: "Stream Name", Provider: "Provider", ChannelID: "ID001"},
+			want:   Stream{Description: "Stream Name| Provider| ID001", Name: "Stream Name", Provider: "Provider", ChannelID: "ID001"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := tt.stream
+			stream.applyDescription()
+			if !reflect.DeepEqual(stream, tt.want) {
+				t.Errorf("applyDescription() = %+v, want %+v", stream, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelKeys(t *testing.T) {
+	cfg := Config{
+		Streams: []Stream{
+			{URL: "233.198.134.1:3333", Labels: map[string]string{"region": "msk", "tier": "gold"}},
+			{URL: "233.198.134.2:3333"},
+			{URL: "233.198.134.3:3333", Labels: map[string]string{"genre": "news", "region": "spb"}},
+		},
+	}
+
+	got := cfg.LabelKeys()
+	want := []string{"genre", "region", "tier"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("LabelKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	base := func() Config {
+		return Config{
+			Interface:   "172.22.2.154",
+			MetricsPort: 9090,
+			Groups: []Group{{
+				Name:       "provider-a",
+				Interface:  "10.0.0.1",
+				Provider:   "Provider A",
+				Input:      InputNative,
+				PIDBitrate: true,
+				Bitrate:    BitrateRange{Min: 3000000, Max: 8000000},
+				Thresholds: map[string]float64{"cc": 10},
+				Webhooks:   []string{"provider-a"},
+				Labels:     map[string]string{"headend": "msk", "tier": "basic"},
+
+				MDIInterval:  500 * time.Millisecond,
+				SilenceLevel: -50,
+			}},
+			Alerts: Alerts{
+				Webhooks: []Webhook{
+					{Name: "noc", URL: "http://127.0.0.1:8080/noc"},
+					{Name: "provider-a", URL: "http://127.0.0.1:8080/a"},
+				},
+				Rules: []AlertRule{{Name: "cc", Type: AlertCCErrors}},
+			},
+		}
+	}
+
+	cfg := base()
+	cfg.Streams = []Stream{
+		{URL: "233.198.134.1:3333", Name: "One", Group: "provider-a"},
+		{
+			URL:         "233.198.134.2:3333",
+			Description: "Two| Legacy Provider| HD",
+			Group:       "provider-a",
+			Interface:   "10.0.0.2",
+			Input:       InputTSP,
+			Bitrate:     BitrateRange{Max: 12000000},
+			Thresholds:  map[string]float64{"cc": 0},
+			Webhooks:    []string{"noc"},
+			Labels:      map[string]string{"tier": "premium"},
+			MDIInterval: 2 * time.Second,
+		},
+		{URL: "233.198.134.3:3333", Name: "Three"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	one, two, three := cfg.Streams[0], cfg.Streams[1], cfg.Streams[2]
+	if one.Interface != "10.0.0.1" || one.Provider != "Provider A" || one.Input != InputNative ||
+		one.Bitrate.Min != 3000000 || one.Thresholds["cc"] != 10 || one.Webhooks[0] != "provider-a" ||
+		one.Labels["tier"] != "basic" || one.Description != "One| Provider A" || one.MDIInterval != 500*time.Millisecond ||
+		one.SilenceLevel != -50 || one.SilenceDuration != 10*time.Second {
+		t.Errorf("inherited stream = %+v", one)
+	}
+	if two.Interface != "10.0.0.2" || two.Provider != "Legacy Provider" || two.Input != InputTSP ||
+		two.Bitrate != (BitrateRange{Min: 3000000, Max: 12000000}) || two.Thresholds["cc"] != 0 ||
+		two.Webhooks[0] != "noc" || two.Labels["tier"] != "premium" || two.Labels["headend"] != "msk" ||
+		two.MDIInterval != 2*time.Second || !two.PIDBitrate {
+		t.Errorf("overriding stream = %+v", two)
+	}
+	if three.Interface != "172.22.2.154" || three.Provider != "" || len(three.Labels) != 0 || three.MDIInterval != time.Second ||
+		three.SilenceLevel != -60 || three.PIDBitrate {
+		t.Errorf("stream without group = %+v", three)
+	}
+	if cfg.Groups[0].Labels["tier"] != "basic" {
+		t.Errorf("group labels modified: %v", cfg.Groups[0].Labels)
+	}
+
+	invalid := []struct {
+		name   string
+		stream Stream
+	}{
+		{"unknown group", Stream{URL: "233.198.134.1:3333", Name: "One", Group: "provider-b"}},
+		{"unknown rule threshold", Stream{URL: "233.198.134.1:3333", Name: "One", Thresholds: map[string]float64{"low": 1}}},
+		{"unknown webhook", Stream{URL: "233.198.134.1:3333", Name: "One", Webhooks: []string{"chat"}}},
+		{"inverted bitrate range", Stream{URL: "233.198.134.1:3333", Name: "One", Group: "provider-a", Bitrate: BitrateRange{Max: 2000000}}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			cfg.Streams = []Stream{tt.stream}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() expected error")
+			}
+		})
+	}
+}
+
+func TestValidateFileStream(t *testing.T) {
+	path := t.TempDir() + "/capture.ts"
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Запись не требует интерфейса и по умолчанию разбирается встроенным демультиплексором
+	cfg := Config{
+		MetricsPort: 9090,
+		Streams:     []Stream{{URL: "file://" + path, Name: "Capture"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if cfg.Streams[0].Input != InputNative || len(cfg.Interfaces()) != 0 {
+		t.Errorf("file stream input = %q, interfaces = %v", cfg.Streams[0].Input, cfg.Interfaces())
+	}
+	if got, ok := cfg.Streams[0].FilePath(); !ok || got != path {
+		t.Errorf("FilePath() = %q, %v", got, ok)
+	}
+
+	for name, stream := range map[string]Stream{
+		"missing file": {URL: "file://" + path + ".missing", Name: "Capture"},
+		"rtp input":    {URL: "file://" + path, Name: "Capture", Input: InputRTP},
+		"source":       {URL: "file://" + path, Name: "Capture", Source: "10.1.1.1"},
+	} {
+		cfg := Config{MetricsPort: 9090, Streams: []Stream{stream}}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil", name)
+		}
+	}
+}
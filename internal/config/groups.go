@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Group именованная группа потоков (провайдер, головная станция) с настройками
+// по умолчанию. Потоки группы наследуют их, если не задали свои
+type Group struct {
+	Name       string             `yaml:"name"`
+	Interface  string             `yaml:"interface"`   // Интерфейс для multicast вместо глобального (IP или имя)
+	Provider   string             `yaml:"provider"`    // Провайдер контента
+	Input      string             `yaml:"input"`       // Способ приёма: tsp, native или rtp
+	PIDBitrate bool               `yaml:"pid_bitrate"` // Битрейт по PID для tsp (плагин analyze)
+	Bitrate    BitrateRange       `yaml:"bitrate"`     // Ожидаемый диапазон битрейта
+	Thresholds map[string]float64 `yaml:"thresholds"`  // Пороги алертов по имени правила
+	Webhooks   []string           `yaml:"webhooks"`    // Куда отправлять алерты потоков группы
+	Labels     map[string]string  `yaml:"labels"`      // Дополнительные метки Prometheus
+
+	MDIInterval time.Duration `yaml:"mdi_interval"` // Интервал расчёта MDI
+
+	SilenceLevel    float64       `yaml:"silence_level"`    // Порог тишины MP2 аудио, dBFS
+	SilenceDuration time.Duration `yaml:"silence_duration"` // Сколько должна длиться тишина
+}
+
+// BitrateRange ожидаемый битрейт потока в bit/s; 0 = без ограничения.
+// Заменяет порог правил bitrate_low (min) и bitrate_high (max)
+type BitrateRange struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// validate проверяет диапазон
+func (b BitrateRange) validate() error {
+	if b.Min < 0 || b.Max < 0 {
+		return fmt.Errorf("bitrate must not be negative")
+	}
+	if b.Max > 0 && b.Min >= b.Max {
+		return fmt.Errorf("bitrate min (%.0f) must be less than max (%.0f)", b.Min, b.Max)
+	}
+	return nil
+}
+
+// resolveGroups применяет настройки групп и глобальные значения по умолчанию к потокам.
+// Явно заданные в потоке значения имеют приоритет, labels и thresholds объединяются
+func (c *Config) resolveGroups() error {
+	groups := make(map[string]*Group, len(c.Groups))
+	for i := range c.Groups {
+		group := &c.Groups[i]
+		if group.Name == "" {
+			return fmt.Errorf("group %d: name is required", i)
+		}
+		if groups[group.Name] != nil {
+			return fmt.Errorf("group %s: duplicate name", group.Name)
+		}
+		groups[group.Name] = group
+	}
+
+	for i := range c.Streams {
+		stream := &c.Streams[i]
+		if stream.Group == "" {
+			continue
+		}
+		group, ok := groups[stream.Group]
+		if !ok {
+			return fmt.Errorf("stream %d: unknown group %q", i, stream.Group)
+		}
+
+		if stream.Interface == "" {
+			stream.Interface = group.Interface
+		}
+		if stream.Provider == "" {
+			stream.Provider = group.Provider
+		}
+		if stream.Input == "" {
+			stream.Input = group.Input
+		}
+		if !stream.PIDBitrate {
+			stream.PIDBitrate = group.PIDBitrate
+		}
+		if stream.Bitrate.Min == 0 {
+			stream.Bitrate.Min = group.Bitrate.Min
+		}
+		if stream.Bitrate.Max == 0 {
+			stream.Bitrate.Max = group.Bitrate.Max
+		}
+		if len(stream.Webhooks) == 0 {
+			stream.Webhooks = group.Webhooks
+		}
+		if stream.MDIInterval == 0 {
+			stream.MDIInterval = group.MDIInterval
+		}
+		if stream.SilenceLevel == 0 {
+			stream.SilenceLevel = group.SilenceLevel
+		}
+		if stream.SilenceDuration == 0 {
+			stream.SilenceDuration = group.SilenceDuration
+		}
+		stream.Thresholds = mergeMaps(group.Thresholds, stream.Thresholds)
+		stream.Labels = mergeMaps(group.Labels, stream.Labels)
+	}
+
+	for i := range c.Streams {
+		if c.Streams[i].Interface == "" {
+			c.Streams[i].Interface = c.Interface
+		}
+	}
+	return nil
+}
+
+// mergeMaps возвращает новую карту: defaults, перекрытые overrides
+func mergeMaps[V any](defaults, overrides map[string]V) map[string]V {
+	if len(defaults) == 0 {
+		return overrides
+	}
+	merged := make(map[string]V, len(defaults)+len(overrides))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// ForStream возвращает правило с учётом настроек потока: порог из thresholds
+// или ожидаемого битрейта и маршрутизацию webhooks потока
+func (r AlertRule) ForStream(stream Stream) AlertRule {
+	if threshold, ok := stream.Thresholds[r.Name]; ok {
+		r.Threshold = threshold
+	} else if r.Type == AlertBitrateLow && stream.Bitrate.Min > 0 {
+		r.Threshold = stream.Bitrate.Min
+	} else if r.Type == AlertBitrateHigh && stream.Bitrate.Max > 0 {
+		r.Threshold = stream.Bitrate.Max
+	}
+
+	if len(stream.Webhooks) > 0 {
+		r.Webhooks = stream.Webhooks
+	}
+	return r
+}
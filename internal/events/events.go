@@ -0,0 +1,136 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// Типы событий
+const (
+	TypeOffline        = "offline"         // поток пропал
+	TypeOnline         = "online"          // поток появился
+	TypePIDAdded       = "pid_added"       // новый PID в PMT
+	TypePIDRemoved     = "pid_removed"     // PID пропал из PMT
+	TypeServiceChanged = "service_changed" // изменилось имя сервиса в SDT
+	TypeCodecChanged   = "codec_changed"   // изменился кодек PID
+	TypeStopped        = "stopped"         // мониторинг потока остановлен: статус неизвестен до следующего обновления
+)
+
+// Event событие в истории потока
+type Event struct {
+	Time    time.Time `json:"time"`
+	Stream  string    `json:"stream"`
+	Type    string    `json:"type"`
+	PID     string    `json:"pid,omitempty"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Detect сравнивает два последовательных обновления потока и возвращает события.
+// previous == nil означает первое обновление после запуска: тогда статус
+// сравнивается с последним известным из истории (known, nil если истории нет)
+func Detect(previous, current *tsp.StreamMetrics, known *bool, now time.Time) []Event {
+	var events []Event
+
+	wasOnline := known
+	if previous != nil {
+		status := previous.Status
+		wasOnline = &status
+	}
+
+	if wasOnline == nil || *wasOnline != current.Status {
+		events = append(events, statusEvent(current, now))
+	}
+
+	// Состав PID и сервис сравниваем только между двумя online обновлениями:
+	// у offline обновлений PID пустые
+	if previous == nil || !previous.Status || !current.Status {
+		return events
+	}
+
+	oldPIDs := make(map[string]tsp.PIDInfo, len(previous.PIDs))
+	for _, pid := range previous.PIDs {
+		oldPIDs[pid.PID] = pid
+	}
+	newPIDs := make(map[string]bool, len(current.PIDs))
+
+	for _, pid := range current.PIDs {
+		newPIDs[pid.PID] = true
+		old, ok := oldPIDs[pid.PID]
+		switch {
+		case !ok:
+			events = append(events, Event{
+				Time:    now,
+				Stream:  current.StreamURL,
+				Type:    TypePIDAdded,
+				PID:     pid.PID,
+				New:     pid.Codec,
+				Message: fmt.Sprintf("PID %s (%s %s) added", pid.PID, pid.Type, pid.Codec),
+			})
+		case old.Codec != pid.Codec:
+			events = append(events, Event{
+				Time:    now,
+				Stream:  current.StreamURL,
+				Type:    TypeCodecChanged,
+				PID:     pid.PID,
+				Old:     old.Codec,
+				New:     pid.Codec,
+				Message: fmt.Sprintf("PID %s codec changed from %s to %s", pid.PID, old.Codec, pid.Codec),
+			})
+		}
+	}
+
+	for _, pid := range previous.PIDs {
+		if !newPIDs[pid.PID] {
+			events = append(events, Event{
+				Time:    now,
+				Stream:  current.StreamURL,
+				Type:    TypePIDRemoved,
+				PID:     pid.PID,
+				Old:     pid.Codec,
+				Message: fmt.Sprintf("PID %s (%s %s) removed", pid.PID, pid.Type, pid.Codec),
+			})
+		}
+	}
+
+	oldName := previous.ServiceInfo.ServiceName
+	newName := current.ServiceInfo.ServiceName
+	if oldName != "" && newName != "" && oldName != newName {
+		events = append(events, Event{
+			Time:    now,
+			Stream:  current.StreamURL,
+			Type:    TypeServiceChanged,
+			Old:     oldName,
+			New:     newName,
+			Message: fmt.Sprintf("service name changed from %q to %q", oldName, newName),
+		})
+	}
+
+	return events
+}
+
+// Stopped событие остановки мониторинга потока (выключение tsmonitor, удаление
+// или перезапуск потока). Время до следующего online/offline не учитывается в SLA
+func Stopped(stream, reason string, now time.Time) Event {
+	return Event{Time: now, Stream: stream, Type: TypeStopped, Message: "monitoring stopped: " + reason}
+}
+
+// statusEvent событие online/offline с причиной
+func statusEvent(m *tsp.StreamMetrics, now time.Time) Event {
+	if m.Status {
+		return Event{Time: now, Stream: m.StreamURL, Type: TypeOnline, Message: "stream is online"}
+	}
+
+	// Причины повторяют условия StreamMetrics.UpdateStatus
+	reason := "no data received"
+	switch {
+	case m.Bitrate.TotalBPS == 0:
+		reason = "bitrate is 0"
+	case len(m.PIDs) == 0:
+		reason = "no elementary streams in PMT"
+	}
+	return Event{Time: now, Stream: m.StreamURL, Type: TypeOffline, Message: "stream is offline: " + reason}
+}
@@ -0,0 +1,229 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+const testStream = "233.198.134.1:3333"
+
+func metrics(status bool, service string, pids ...tsp.PIDInfo) *tsp.StreamMetrics {
+	m := &tsp.StreamMetrics{
+		StreamURL:   testStream,
+		Status:      status,
+		PIDs:        pids,
+		ServiceInfo: tsp.ServiceInfo{ServiceName: service},
+	}
+	if status {
+		m.Bitrate.TotalBPS = 5000000
+	}
+	return m
+}
+
+var (
+	video = tsp.PIDInfo{PID: "0x0066", Type: "video", Codec: "h264"}
+	audio = tsp.PIDInfo{PID: "0x00CA", Type: "audio", Codec: "mpeg1audio"}
+)
+
+func TestDetect(t *testing.T) {
+	online, offline := true, false
+
+	tests := []struct {
+		name     string
+		previous *tsp.StreamMetrics
+		current  *tsp.StreamMetrics
+		known    *bool
+		want     []string
+	}{
+		{
+			name:    "first update without history",
+			current: metrics(true, "Силк", video, audio),
+			want:    []string{TypeOnline},
+		},
+		{
+			name:    "first update, same as history",
+			current: metrics(true, "Силк", video, audio),
+			known:   &online,
+			want:    nil,
+		},
+		{
+			name:    "first update, differs from history",
+			current: metrics(false, ""),
+			known:   &online,
+			want:    []string{TypeOffline},
+		},
+		{
+			name:     "goes offline",
+			previous: metrics(true, "Силк", video, audio),
+			current:  metrics(false, ""),
+			known:    &offline, // игнорируется, есть previous
+			want:     []string{TypeOffline},
+		},
+		{
+			name:     "pid changes",
+			previous: metrics(true, "Силк", video, audio),
+			current:  metrics(true, "Силк", tsp.PIDInfo{PID: "0x0066", Type: "video", Codec: "hevc"}, tsp.PIDInfo{PID: "0x00CB", Type: "audio", Codec: "aac"}),
+			want:     []string{TypeCodecChanged, TypePIDAdded, TypePIDRemoved},
+		},
+		{
+			name:     "service renamed",
+			previous: metrics(true, "Силк", video),
+			current:  metrics(true, "Силк HD", video),
+			want:     []string{TypeServiceChanged},
+		},
+		{
+			name:     "service not yet known",
+			previous: metrics(true, "", video),
+			current:  metrics(true, "Силк", video),
+			want:     nil,
+		},
+	}
+
+	now := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.previous, tt.current, tt.known, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Detect() = %+v, want types %v", got, tt.want)
+			}
+			for i, ev := range got {
+				if ev.Type != tt.want[i] || ev.Stream != testStream || !ev.Time.Equal(now) {
+					t.Errorf("event %d = %+v, want type %s", i, ev, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// Причина offline попадает в сообщение
+	got := Detect(metrics(true, "Силк", video), metrics(false, ""), nil, now)
+	if got[0].Message != "stream is offline: bitrate is 0" {
+		t.Errorf("offline message = %q", got[0].Message)
+	}
+}
+
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	now := time.Now().Truncate(time.Second)
+
+	store := NewStore(24 * time.Hour)
+	if err := store.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	err := store.Append(
+		Event{Time: now.Add(-3 * time.Minute), Stream: testStream, Type: TypeOnline},
+		Event{Time: now.Add(-2 * time.Minute), Stream: "233.198.134.2:3333", Type: TypeOnline},
+		Event{Time: now.Add(-time.Minute), Stream: testStream, Type: TypeOffline},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// Старое событие и битая строка отбрасываются при открытии
+	// (последний статус потока сохранился бы, поэтому старое событие не статусное)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"time":"` + now.Add(-48*time.Hour).Format(time.RFC3339) + `","stream":"x","type":"pid_added"}` + "\n")
+	file.WriteString("{broken\n")
+	file.Close()
+
+	store = NewStore(24 * time.Hour)
+	if err := store.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	all := store.Query(Query{})
+	if len(all) != 3 {
+		t.Fatalf("loaded %d events, want 3: %+v", len(all), all)
+	}
+	if status := store.LastStatus(testStream); status == nil || *status {
+		t.Errorf("LastStatus() = %v, want offline", status)
+	}
+	if status := store.LastStatus("233.198.134.9:3333"); status != nil {
+		t.Errorf("LastStatus() of unknown stream = %v, want nil", *status)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"by stream", Query{Streams: []string{testStream}}, 2},
+		{"by type", Query{Types: []string{TypeOffline}}, 1},
+		{"time range", Query{From: now.Add(-150 * time.Second), To: now.Add(-time.Minute)}, 1},
+		{"limit keeps latest", Query{Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.Query(tt.query); len(got) != tt.want {
+				t.Errorf("Query() = %d events, want %d", len(got), tt.want)
+			}
+		})
+	}
+	if latest := store.Query(Query{Limit: 1}); latest[0].Type != TypeOffline {
+		t.Errorf("Query(limit 1) = %+v, want the latest event", latest[0])
+	}
+
+	// Файл переписан без старых и битых строк
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("events file has %d lines after compaction, want 3", lines)
+	}
+
+	// После остановки мониторинга статус снова неизвестен
+	if err := store.Append(Stopped(testStream, "shutdown", now)); err != nil {
+		t.Fatal(err)
+	}
+	if status := store.LastStatus(testStream); status != nil {
+		t.Errorf("LastStatus() after stop = %v, want nil", *status)
+	}
+}
+
+func TestStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	now := time.Now()
+
+	store := NewStore(time.Hour)
+	if err := store.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err := store.Append(
+		Event{Time: now.Add(-2 * time.Hour), Stream: testStream, Type: TypeOnline},
+		Event{Time: now.Add(-2 * time.Hour), Stream: testStream, Type: TypePIDAdded, PID: "0x0066"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Следующая запись отбрасывает устаревший pid_added и переписывает файл
+	if err := store.Append(Event{Time: now, Stream: testStream, Type: TypeOffline}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(Event{Time: now, Stream: testStream, Type: TypeOnline}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("events file has %d lines after runtime prune, want 3:\n%s", lines, data)
+	}
+	if strings.Contains(string(data), TypePIDAdded) {
+		t.Errorf("events file still has the pruned event:\n%s", data)
+	}
+}
@@ -0,0 +1,330 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store история событий: в памяти и, если задан путь, в файле JSON lines.
+// Файл дописывается; когда отбрасываются события старше retention (кроме
+// последнего статуса каждого потока), файл переписывается
+type Store struct {
+	mu        sync.Mutex
+	retention time.Duration
+	events    []Event         // по возрастанию времени
+	status    map[string]bool // последний online/offline по потоку
+	path      string
+	file      *os.File
+}
+
+// Query фильтр выборки событий
+type Query struct {
+	From    time.Time // включительно, нулевое = без ограничения
+	To      time.Time // не включительно, нулевое = без ограничения
+	Streams []string  // пусто = все потоки
+	Types   []string  // пусто = все типы
+	Limit   int       // 0 = без ограничения, иначе последние Limit событий
+}
+
+// NewStore создает Store в памяти; retention 0 хранит события бессрочно
+func NewStore(retention time.Duration) *Store {
+	return &Store{
+		retention: retention,
+		status:    make(map[string]bool),
+	}
+}
+
+// Open загружает историю из файла и открывает его для дописывания.
+// Пустой путь оставляет историю только в памяти
+func (s *Store) Open(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create events directory: %w", err)
+	}
+
+	loaded, dropped, err := readEvents(path)
+	if err != nil {
+		return err
+	}
+	if dropped > 0 {
+		fmt.Printf("⚠️  Events file %s: %d malformed lines skipped\n", path, dropped)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = loaded
+	dropped += s.prune(time.Now())
+
+	// Переписываем файл, если в нём были старые или битые строки
+	if dropped > 0 {
+		if err := writeEvents(path, s.events); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+	s.file = file
+	s.path = path
+
+	for _, ev := range s.events {
+		s.track(ev)
+	}
+	return nil
+}
+
+// Load читает историю из файла без изменения файла (для отчётов вне процесса мониторинга).
+// Предупреждения пишутся в stderr, stdout остаётся под отчёт
+func Load(path string) ([]Event, error) {
+	loaded, dropped, err := readEvents(path)
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Events file %s: %d malformed lines skipped\n", path, dropped)
+	}
+	return loaded, err
+}
+
+// readEvents читает файл событий; битые строки пропускаются и считаются в dropped
+func readEvents(path string) ([]Event, int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read events file: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	var dropped int
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			dropped++
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read events file: %w", err)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, dropped, nil
+}
+
+// writeEvents атомарно переписывает файл событий
+func writeEvents(path string, events []Event) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact events file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, ev := range events {
+		if err := encoder.Encode(ev); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to compact events file: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact events file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to compact events file: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to compact events file: %w", err)
+	}
+	return nil
+}
+
+// Append добавляет события в историю
+func (s *Store) Append(events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := s.prune(time.Now())
+
+	var data []byte
+	for _, ev := range events {
+		s.events = append(s.events, ev)
+		s.track(ev)
+
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	// Старые события отброшены: переписываем файл целиком, иначе он растёт до перезапуска
+	if pruned > 0 && s.file != nil {
+		return s.compact()
+	}
+
+	if s.file != nil {
+		if _, err := s.file.Write(data); err != nil {
+			return fmt.Errorf("failed to write events file: %w", err)
+		}
+	}
+	return nil
+}
+
+// compact переписывает файл текущей историей и открывает его заново, вызывается под mu
+func (s *Store) compact() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to compact events file: %w", err)
+	}
+	s.file = nil
+
+	if err := writeEvents(s.path, s.events); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+	s.file = file
+	return nil
+}
+
+// track запоминает последний статус потока, вызывается под mu
+func (s *Store) track(ev Event) {
+	switch ev.Type {
+	case TypeOnline:
+		s.status[ev.Stream] = true
+	case TypeOffline:
+		s.status[ev.Stream] = false
+	case TypeStopped:
+		delete(s.status, ev.Stream) // после перезапуска статус записывается заново
+	}
+}
+
+// prune удаляет события старше retention, вызывается под mu.
+// Последнее online/offline/stopped событие каждого потока сохраняется:
+// по нему SLA определяет статус на начало периода у потоков, которые
+// не меняли статус дольше retention
+func (s *Store) prune(now time.Time) int {
+	if s.retention <= 0 {
+		return 0
+	}
+
+	cutoff := now.Add(-s.retention)
+	n := sort.Search(len(s.events), func(i int) bool { return !s.events[i].Time.Before(cutoff) })
+	if n == 0 {
+		return 0
+	}
+
+	last := make(map[string]int)
+	for i, ev := range s.events[:n] {
+		if isStatus(ev.Type) {
+			last[ev.Stream] = i
+		}
+	}
+
+	kept := make([]Event, 0, len(last)+len(s.events)-n)
+	for i, ev := range s.events[:n] {
+		if idx, ok := last[ev.Stream]; ok && idx == i {
+			kept = append(kept, ev)
+		}
+	}
+	dropped := n - len(kept)
+	if dropped == 0 {
+		return 0
+	}
+	s.events = append(kept, s.events[n:]...)
+	return dropped
+}
+
+// isStatus сообщает, меняет ли событие статус потока
+func isStatus(eventType string) bool {
+	return eventType == TypeOnline || eventType == TypeOffline || eventType == TypeStopped
+}
+
+// LastStatus возвращает последний записанный статус потока (nil если неизвестен)
+func (s *Store) LastStatus(stream string) *bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.status[stream]
+	if !ok {
+		return nil
+	}
+	return &status
+}
+
+// Query возвращает события по фильтру в порядке времени
+func (s *Store) Query(q Query) []Event {
+	streams := toSet(q.Streams)
+	types := toSet(q.Types)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []Event{}
+	for _, ev := range s.events {
+		if !q.From.IsZero() && ev.Time.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !ev.Time.Before(q.To) {
+			continue
+		}
+		if len(streams) > 0 && !streams[ev.Stream] {
+			continue
+		}
+		if len(types) > 0 && !types[ev.Type] {
+			continue
+		}
+		result = append(result, ev)
+	}
+
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result
+}
+
+// Close закрывает файл событий
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// toSet превращает список в множество
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
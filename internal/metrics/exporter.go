@@ -1,29 +1,91 @@
 package metrics
 
 import (
-	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+
+	"github.com/otcnet/tsmonitor/internal/config"
 	"github.com/otcnet/tsmonitor/internal/tsp"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Exporter экспортирует метрики в Prometheus
 type Exporter struct {
+	labelKeys []string // дополнительные метки ts_stream_info (labels: в конфиге)
+
+	streamInfo        *prometheus.GaugeVec
 	streamStatus      *prometheus.GaugeVec
 	streamBitrate     *prometheus.GaugeVec
 	streamPIDCount    *prometheus.GaugeVec
 	streamPIDInfo     *prometheus.GaugeVec
 	streamServiceInfo *prometheus.GaugeVec
-	streamCCErrors    *prometheus.CounterVec
+
+	programInfo    *prometheus.GaugeVec
+	programPIDInfo *prometheus.GaugeVec
+	programBitrate *prometheus.GaugeVec
+
+	videoInfo        *prometheus.GaugeVec
+	videoWidth       *prometheus.GaugeVec
+	videoHeight      *prometheus.GaugeVec
+	videoGOPLength   *prometheus.GaugeVec
+	videoIDRInterval *prometheus.GaugeVec
+	videoFrozen      *prometheus.GaugeVec
+
+	audioInfo       *prometheus.GaugeVec
+	audioSampleRate *prometheus.GaugeVec
+	audioChannels   *prometheus.GaugeVec
+	audioBitrate    *prometheus.GaugeVec
+	audioDialnorm   *prometheus.GaugeVec
+	audioLevel      *prometheus.GaugeVec
+	audioSilence    *prometheus.GaugeVec
+
+	streamCCErrors   *prometheus.CounterVec
+	streamETR290     *prometheus.CounterVec
+	streamPIDBitrate *prometheus.GaugeVec
+	streamNullRatio  *prometheus.GaugeVec
+	streamSenders    *prometheus.GaugeVec
+
+	rtpPackets    *prometheus.CounterVec
+	rtpLost       *prometheus.CounterVec
+	rtpDuplicates *prometheus.CounterVec
+	rtpReordered  *prometheus.CounterVec
+	rtpJitter     *prometheus.GaugeVec
+	mdiDF         *prometheus.GaugeVec
+	mdiMLR        *prometheus.GaugeVec
+
+	udpInterarrival    *prometheus.HistogramVec
+	udpInterarrivalMax *prometheus.GaugeVec
+	udpBurstMax        *prometheus.GaugeVec
+
+	pcrInterval    *prometheus.HistogramVec
+	pcrIntervalMax *prometheus.GaugeVec
+	pcrIntervalAvg *prometheus.GaugeVec
+	pcrJitter      *prometheus.HistogramVec
+	pcrJitterMax   *prometheus.GaugeVec
+	pcrAccuracyMax *prometheus.GaugeVec
+	pcrBitrate     *prometheus.GaugeVec
 }
 
-// NewExporter создаёт новый экспортер метрик
-func NewExporter() *Exporter {
+// NewExporter создаёт новый экспортер метрик.
+// labelKeys — имена дополнительных меток потоков; набор меток метрики
+// фиксируется при создании, новые ключи появятся только после перезапуска
+func NewExporter(labelKeys []string) *Exporter {
 	return &Exporter{
+		labelKeys: labelKeys,
+
+		streamInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_info",
+				Help: "Stream information from config (value always 1, info in labels)",
+			},
+			append([]string{"stream", "description", "name", "provider", "format", "channel_id", "source"}, labelKeys...),
+		),
+
 		streamStatus: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "ts_stream_status",
 				Help: "Stream status (1 = online, 0 = offline)",
 			},
-			[]string{"stream", "description"},
+			[]string{"stream"},
 		),
 
 		streamBitrate: prometheus.NewGaugeVec(
@@ -31,7 +93,7 @@ func NewExporter() *Exporter {
 				Name: "ts_stream_bitrate_bps",
 				Help: "Stream bitrate in bits per second",
 			},
-			[]string{"stream", "description", "type"},
+			[]string{"stream", "type"},
 		),
 
 		streamPIDCount: prometheus.NewGaugeVec(
@@ -39,7 +101,7 @@ func NewExporter() *Exporter {
 				Name: "ts_stream_pid_count",
 				Help: "Number of PIDs by type",
 			},
-			[]string{"stream", "description", "type"},
+			[]string{"stream", "type"},
 		),
 
 		streamPIDInfo: prometheus.NewGaugeVec(
@@ -47,7 +109,7 @@ func NewExporter() *Exporter {
 				Name: "ts_stream_pid_info",
 				Help: "PID information (value always 1, info in labels)",
 			},
-			[]string{"stream", "description", "pid", "type", "codec", "language"},
+			[]string{"stream", "pid", "type", "codec", "language"},
 		),
 
 		streamServiceInfo: prometheus.NewGaugeVec(
@@ -55,7 +117,135 @@ func NewExporter() *Exporter {
 				Name: "ts_stream_service_info",
 				Help: "Service information (value always 1, info in labels)",
 			},
-			[]string{"stream", "description", "service_name", "provider", "service_type"},
+			[]string{"stream", "service_name", "provider", "service_type"},
+		),
+
+		programInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_program_info",
+				Help: "Program of the transport stream from PAT/PMT and SDT (value always 1, info in labels)",
+			},
+			[]string{"stream", "program", "pmt_pid", "pcr_pid", "service_name", "provider", "service_type"},
+		),
+
+		programPIDInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_program_pid_info",
+				Help: "Elementary stream PID of a program (value always 1, info in labels)",
+			},
+			[]string{"stream", "program", "pid", "type", "codec"},
+		),
+
+		programBitrate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_program_bitrate_bps",
+				Help: "Program bitrate (PMT and elementary streams) in bits per second over the last 1s window",
+			},
+			[]string{"stream", "program"},
+		),
+
+		videoInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_video_info",
+				Help: "Video PID parameters from SPS or MPEG-2 sequence header (value always 1, info in labels)",
+			},
+			[]string{"stream", "program", "pid", "codec", "width", "height", "frame_rate", "interlaced", "profile", "level", "aspect"},
+		),
+
+		videoWidth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_video_width_pixels",
+				Help: "Video picture width from SPS or MPEG-2 sequence header",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		videoHeight: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_video_height_pixels",
+				Help: "Video picture height from SPS or MPEG-2 sequence header",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		videoGOPLength: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_video_gop_length_frames",
+				Help: "Frames between the last two I-frames",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		videoIDRInterval: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_video_idr_interval_seconds",
+				Help: "Time between the last two IDR frames (IRAP for HEVC, I-frames for MPEG-2) by PTS",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		videoFrozen: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_video_suspect_frozen",
+				Help: "Frozen picture suspected from coded frame sizes and missing I-frames (1 = suspect)",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		audioInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_info",
+				Help: "Audio PID parameters from frame headers (value always 1, info in labels)",
+			},
+			[]string{"stream", "program", "pid", "codec", "format", "sample_rate", "channels", "layout"},
+		),
+
+		audioSampleRate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_sample_rate_hz",
+				Help: "Audio sample rate from frame headers",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		audioChannels: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_channels",
+				Help: "Audio channels including LFE from frame headers",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		audioBitrate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_bitrate_bps",
+				Help: "Audio codec bitrate: nominal from frame headers, or from frame sizes for AAC and E-AC-3",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		audioDialnorm: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_dialnorm_db",
+				Help: "AC-3/E-AC-3 dialogue normalization in dB",
+			},
+			[]string{"stream", "program", "pid"},
+		),
+
+		audioLevel: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_level_dbfs",
+				Help: "MPEG-1/2 Layer II audio level over the last update from subband samples (type: rms, peak)",
+			},
+			[]string{"stream", "program", "pid", "type"},
+		),
+
+		audioSilence: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_audio_silence",
+				Help: "MPEG-1/2 Layer II audio below silence_level for at least silence_duration (1 = silent)",
+			},
+			[]string{"stream", "program", "pid"},
 		),
 
 		streamCCErrors: prometheus.NewCounterVec(
@@ -63,49 +253,299 @@ func NewExporter() *Exporter {
 				Name: "ts_stream_cc_errors_total",
 				Help: "Total number of continuity counter errors by PID",
 			},
-			[]string{"stream", "description", "pid"},
+			[]string{"stream", "pid"},
+		),
+
+		streamETR290: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ts_stream_etr290_errors_total",
+				Help: "Total number of ETSI TR 101 290 errors by priority, indicator and PID",
+			},
+			[]string{"stream", "priority", "indicator", "program", "pid"},
+		),
+
+		streamPIDBitrate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_pid_bitrate_bps",
+				Help: "PID bitrate in bits per second over the last 1s window",
+			},
+			[]string{"stream", "program", "pid", "type", "codec"},
+		),
+
+		streamNullRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_null_ratio",
+				Help: "Share of null (stuffing) packets in the stream, 0..1",
+			},
+			[]string{"stream"},
+		),
+
+		streamSenders: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_sender_datagrams",
+				Help: "UDP datagrams per sender address over the last 1s window (expected=false: not the configured source)",
+			},
+			[]string{"stream", "sender", "expected"},
+		),
+
+		rtpPackets: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ts_stream_rtp_packets_total",
+				Help: "Total number of received RTP packets",
+			},
+			[]string{"stream"},
+		),
+
+		rtpLost: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ts_stream_rtp_lost_packets_total",
+				Help: "Total number of RTP packets lost in the network (sequence number gaps)",
+			},
+			[]string{"stream"},
+		),
+
+		rtpDuplicates: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ts_stream_rtp_duplicate_packets_total",
+				Help: "Total number of duplicate RTP packets",
+			},
+			[]string{"stream"},
+		),
+
+		rtpReordered: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ts_stream_rtp_reordered_packets_total",
+				Help: "Total number of RTP packets received out of order",
+			},
+			[]string{"stream"},
+		),
+
+		rtpJitter: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_rtp_jitter_seconds",
+				Help: "RTP interarrival jitter (RFC 3550)",
+			},
+			[]string{"stream"},
+		),
+
+		mdiDF: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_mdi_df_ms",
+				Help: "MDI delay factor (RFC 4445) over the last mdi_interval: receive buffer needed to absorb arrival jitter, ms",
+			},
+			[]string{"stream"},
+		),
+
+		mdiMLR: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_mdi_mlr",
+				Help: "MDI media loss rate (RFC 4445) over the last mdi_interval: lost TS packets per second",
+			},
+			[]string{"stream"},
+		),
+
+		udpInterarrival: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "ts_stream_udp_interarrival_seconds",
+				Help:    "Time between consecutive UDP datagrams of the stream",
+				Buckets: []float64{0.00001, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.002, 0.005, 0.01, 0.02, 0.05, 0.1},
+			},
+			[]string{"stream"},
+		),
+
+		udpInterarrivalMax: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_udp_interarrival_max_seconds",
+				Help: "Longest gap between UDP datagrams over the last 1s window",
+			},
+			[]string{"stream"},
+		),
+
+		udpBurstMax: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_udp_burst_max_datagrams",
+				Help: "Most UDP datagrams received within any 1ms over the last 1s window",
+			},
+			[]string{"stream"},
+		),
+
+		pcrInterval: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "ts_stream_pcr_interval_seconds",
+				Help:    "Interval between consecutive PCR values",
+				Buckets: []float64{0.005, 0.01, 0.02, 0.03, 0.04, 0.05, 0.1, 0.2, 0.5},
+			},
+			[]string{"stream", "pid"},
+		),
+
+		pcrIntervalMax: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_pcr_interval_max_seconds",
+				Help: "Maximum PCR repetition interval over the last 1s window",
+			},
+			[]string{"stream", "pid"},
+		),
+
+		pcrIntervalAvg: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_pcr_interval_avg_seconds",
+				Help: "Average PCR repetition interval over the last 1s window",
+			},
+			[]string{"stream", "pid"},
+		),
+
+		pcrJitter: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "ts_stream_pcr_jitter_seconds",
+				Help:    "PCR overall jitter: deviation of PCR from packet arrival time",
+				Buckets: []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.002, 0.005, 0.01, 0.05},
+			},
+			[]string{"stream", "pid"},
+		),
+
+		pcrJitterMax: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_pcr_jitter_max_seconds",
+				Help: "Peak-to-peak PCR overall jitter over the last 1s window",
+			},
+			[]string{"stream", "pid"},
+		),
+
+		pcrAccuracyMax: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_pcr_accuracy_max_seconds",
+				Help: "Maximum absolute PCR accuracy (PCR_AC) over the last 1s window",
+			},
+			[]string{"stream", "pid"},
+		),
+
+		pcrBitrate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ts_stream_pcr_bitrate_bps",
+				Help: "Transport stream bitrate derived from PCR values",
+			},
+			[]string{"stream", "pid"},
 		),
 	}
 }
 
 // Register регистрирует все метрики в Prometheus
 func (e *Exporter) Register() error {
-	if err := prometheus.Register(e.streamStatus); err != nil {
-		return err
-	}
-	if err := prometheus.Register(e.streamBitrate); err != nil {
-		return err
-	}
-	if err := prometheus.Register(e.streamPIDCount); err != nil {
-		return err
-	}
-	if err := prometheus.Register(e.streamPIDInfo); err != nil {
-		return err
+	for _, c := range e.collectors() {
+		if err := prometheus.Register(c); err != nil {
+			return err
+		}
 	}
-	if err := prometheus.Register(e.streamServiceInfo); err != nil {
-		return err
+	return nil
+}
+
+// collectors возвращает все семейства метрик экспортера
+func (e *Exporter) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		e.streamInfo, e.streamStatus, e.streamBitrate, e.streamPIDCount, e.streamPIDInfo, e.streamServiceInfo,
+		e.streamCCErrors, e.streamETR290, e.streamPIDBitrate, e.streamNullRatio, e.streamSenders,
+		e.rtpPackets, e.rtpLost, e.rtpDuplicates, e.rtpReordered, e.rtpJitter,
+		e.mdiDF, e.mdiMLR,
+		e.programInfo, e.programPIDInfo, e.programBitrate,
+		e.videoInfo, e.videoWidth, e.videoHeight, e.videoGOPLength, e.videoIDRInterval, e.videoFrozen,
+		e.audioInfo, e.audioSampleRate, e.audioChannels, e.audioBitrate, e.audioDialnorm, e.audioLevel, e.audioSilence,
+		e.udpInterarrival, e.udpInterarrivalMax, e.udpBurstMax,
+		e.pcrInterval, e.pcrIntervalMax, e.pcrIntervalAvg,
+		e.pcrJitter, e.pcrJitterMax, e.pcrAccuracyMax, e.pcrBitrate,
 	}
-	if err := prometheus.Register(e.streamCCErrors); err != nil {
-		return err
+}
+
+// SetStreamInfo выставляет ts_stream_info с полями потока из конфигурации.
+// Метки, которых не было при создании экспортера, пропускаются
+func (e *Exporter) SetStreamInfo(stream config.Stream) {
+	e.streamInfo.DeletePartialMatch(prometheus.Labels{"stream": stream.URL})
+
+	values := []string{stream.URL, stream.Description, stream.Name, stream.Provider, stream.Format, stream.ChannelID, stream.Source}
+	for _, key := range e.labelKeys {
+		values = append(values, stream.Labels[key])
 	}
-	return nil
+	e.streamInfo.WithLabelValues(values...).Set(1)
 }
 
 // UpdateMetrics обновляет метрики на основе StreamMetrics
 func (e *Exporter) UpdateMetrics(m *tsp.StreamMetrics) {
 	stream := m.StreamURL
-	desc := m.Description
 
 	// Обновляем статус
 	var status float64
 	if m.Status {
 		status = 1
 	}
-	e.streamStatus.WithLabelValues(stream, desc).Set(status)
+	e.streamStatus.WithLabelValues(stream).Set(status)
 
 	// Обновляем битрейт
-	e.streamBitrate.WithLabelValues(stream, desc, "total").Set(float64(m.Bitrate.TotalBPS))
-	e.streamBitrate.WithLabelValues(stream, desc, "net").Set(float64(m.Bitrate.NetBPS))
+	e.streamBitrate.WithLabelValues(stream, "total").Set(float64(m.Bitrate.TotalBPS))
+	e.streamBitrate.WithLabelValues(stream, "net").Set(float64(m.Bitrate.NetBPS))
+
+	// Битрейт по PID: в нативном режиме всегда, для tsp после первого отчёта analyze; нули не публикуем
+	e.streamPIDBitrate.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	e.streamNullRatio.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	programs := pidPrograms(m)
+	if m.Bitrate.PerPID {
+		for _, pids := range [][]tsp.PIDInfo{m.PIDs, m.SystemPIDs} {
+			for _, pid := range pids {
+				e.streamPIDBitrate.WithLabelValues(stream, programs[pid.PID], pid.PID, pid.Type, pid.Codec).Set(float64(pid.BitrateBPS))
+			}
+		}
+		e.streamNullRatio.WithLabelValues(stream).Set(m.Bitrate.NullRatio)
+	}
+
+	// Отправители за окно; пропавшие удаляем
+	e.streamSenders.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	for _, sender := range m.Senders {
+		e.streamSenders.WithLabelValues(stream, sender.Address, strconv.FormatBool(sender.Expected)).Set(float64(sender.Datagrams))
+	}
+
+	// RTP: счётчики за окно добавляются, Add(0) создаёт их сразу
+	if m.RTP != nil {
+		e.rtpPackets.WithLabelValues(stream).Add(float64(m.RTP.Packets))
+		e.rtpLost.WithLabelValues(stream).Add(float64(m.RTP.Lost))
+		e.rtpDuplicates.WithLabelValues(stream).Add(float64(m.RTP.Duplicates))
+		e.rtpReordered.WithLabelValues(stream).Add(float64(m.RTP.Reordered))
+		e.rtpJitter.WithLabelValues(stream).Set(m.RTP.Jitter.Seconds())
+	}
+
+	// MDI за последний завершённый интервал; нет данных — убираем прошлые значения
+	if m.MDI != nil {
+		e.mdiDF.WithLabelValues(stream).Set(m.MDI.DF.Seconds() * 1000)
+		e.mdiMLR.WithLabelValues(stream).Set(m.MDI.MLR)
+	} else {
+		e.mdiDF.DeletePartialMatch(prometheus.Labels{"stream": stream})
+		e.mdiMLR.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	}
+
+	// Время прихода датаграмм
+	if m.Arrival != nil {
+		histogram := e.udpInterarrival.WithLabelValues(stream)
+		for _, interval := range m.Arrival.Intervals {
+			histogram.Observe(interval.Seconds())
+		}
+		e.udpInterarrivalMax.WithLabelValues(stream).Set(m.Arrival.IntervalMax.Seconds())
+		e.udpBurstMax.WithLabelValues(stream).Set(float64(m.Arrival.BurstMax))
+	}
+
+	// Анализ PCR; gauges PID, по которым PCR перестал приходить, удаляем
+	for _, g := range []*prometheus.GaugeVec{e.pcrIntervalMax, e.pcrIntervalAvg, e.pcrJitterMax, e.pcrAccuracyMax, e.pcrBitrate} {
+		g.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	}
+	for _, pcr := range m.PCR {
+		for _, interval := range pcr.Intervals {
+			e.pcrInterval.WithLabelValues(stream, pcr.PID).Observe(interval.Seconds())
+		}
+		for _, jitter := range pcr.Jitter {
+			e.pcrJitter.WithLabelValues(stream, pcr.PID).Observe(jitter.Seconds())
+		}
+		e.pcrIntervalMax.WithLabelValues(stream, pcr.PID).Set(pcr.IntervalMax.Seconds())
+		e.pcrIntervalAvg.WithLabelValues(stream, pcr.PID).Set(pcr.IntervalAvg.Seconds())
+		e.pcrJitterMax.WithLabelValues(stream, pcr.PID).Set(pcr.JitterMax.Seconds())
+		e.pcrAccuracyMax.WithLabelValues(stream, pcr.PID).Set(pcr.AccuracyMax.Seconds())
+		e.pcrBitrate.WithLabelValues(stream, pcr.PID).Set(float64(pcr.BitrateBPS))
+	}
 
 	// Подсчитываем PIDs по типам
 	pidCounts := make(map[string]int)
@@ -114,10 +554,10 @@ func (e *Exporter) UpdateMetrics(m *tsp.StreamMetrics) {
 	}
 
 	// Обновляем счётчики PIDs
-	e.streamPIDCount.WithLabelValues(stream, desc, "video").Set(float64(pidCounts["video"]))
-	e.streamPIDCount.WithLabelValues(stream, desc, "audio").Set(float64(pidCounts["audio"]))
-	e.streamPIDCount.WithLabelValues(stream, desc, "data").Set(float64(pidCounts["data"]))
-	e.streamPIDCount.WithLabelValues(stream, desc, "other").Set(float64(pidCounts["other"]))
+	e.streamPIDCount.WithLabelValues(stream, "video").Set(float64(pidCounts["video"]))
+	e.streamPIDCount.WithLabelValues(stream, "audio").Set(float64(pidCounts["audio"]))
+	e.streamPIDCount.WithLabelValues(stream, "data").Set(float64(pidCounts["data"]))
+	e.streamPIDCount.WithLabelValues(stream, "other").Set(float64(pidCounts["other"]))
 
 	// Сбрасываем старые PID метрики для этого потока
 	e.streamPIDInfo.DeletePartialMatch(prometheus.Labels{"stream": stream})
@@ -128,10 +568,9 @@ func (e *Exporter) UpdateMetrics(m *tsp.StreamMetrics) {
 		if lang == "" {
 			lang = "none"
 		}
-		
+
 		e.streamPIDInfo.WithLabelValues(
 			stream,
-			desc,
 			pid.PID,
 			pid.Type,
 			pid.Codec,
@@ -145,37 +584,219 @@ func (e *Exporter) UpdateMetrics(m *tsp.StreamMetrics) {
 		if serviceType == "" {
 			serviceType = "unknown"
 		}
-		
+
 		e.streamServiceInfo.WithLabelValues(
 			stream,
-			desc,
 			m.ServiceInfo.ServiceName,
 			m.ServiceInfo.Provider,
 			serviceType,
 		).Set(1)
 	}
 
+	// Программы (в MPTS их несколько); пропавшие удаляем
+	e.updatePrograms(stream, m)
+
+	// Параметры видео (только нативный режим); смена разрешения или профиля меняет метки
+	e.updateVideo(stream, m, programs)
+	e.updateAudio(stream, m, programs)
+
 	// КРИТИЧНО: Инициализируем CC Errors счетчики для всех PIDs в 0
 	// Это позволяет Prometheus видеть метрику даже когда ошибок нет
 	for _, pid := range m.PIDs {
 		// Add(0) создаст метрику если её нет, или ничего не сделает если есть
-		e.streamCCErrors.WithLabelValues(stream, desc, pid.PID).Add(0)
+		e.streamCCErrors.WithLabelValues(stream, pid.PID).Add(0)
 	}
 
 	// Обновляем ТОЛЬКО если есть новые ошибки
 	for pid, errors := range m.CCErrors {
 		if errors > 0 {
-			e.streamCCErrors.WithLabelValues(stream, desc, pid).Add(float64(errors))
+			e.streamCCErrors.WithLabelValues(stream, pid).Add(float64(errors))
+		}
+	}
+
+	// ETR 290 проверяется только нативным разбором; для tsp семейство не создаётся
+	if !m.ETR290Checked {
+		return
+	}
+	for _, pid := range m.PIDs {
+		e.streamETR290.WithLabelValues(stream, "1", "CC_error", programs[pid.PID], pid.PID).Add(0)
+	}
+
+	for _, etr := range m.ETR290Errors {
+		if etr.Count > 0 {
+			e.streamETR290.WithLabelValues(
+				stream,
+				strconv.Itoa(etr.Priority),
+				etr.Indicator,
+				programs[etr.PID],
+				etr.PID,
+			).Add(float64(etr.Count))
+		}
+	}
+}
+
+// updatePrograms выставляет метрики программ потока; тип и кодек PID берутся из m.PIDs
+func (e *Exporter) updatePrograms(stream string, m *tsp.StreamMetrics) {
+	for _, g := range []*prometheus.GaugeVec{e.programInfo, e.programPIDInfo, e.programBitrate} {
+		g.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	}
+
+	pids := make(map[string]tsp.PIDInfo, len(m.PIDs))
+	for _, pid := range m.PIDs {
+		pids[pid.PID] = pid
+	}
+
+	for _, program := range m.Programs {
+		number := strconv.Itoa(program.Number)
+		serviceType := program.ServiceType
+		if serviceType == "" {
+			serviceType = "unknown"
+		}
+		e.programInfo.WithLabelValues(stream, number, program.PMTPID, program.PCRPID,
+			program.ServiceName, program.Provider, serviceType).Set(1)
+
+		for _, pid := range program.PIDs {
+			info, ok := pids[pid]
+			if !ok {
+				info = tsp.PIDInfo{Type: "other", Codec: "unknown"}
+			}
+			e.programPIDInfo.WithLabelValues(stream, number, pid, info.Type, info.Codec).Set(1)
+		}
+
+		if m.Bitrate.PerPID {
+			e.programBitrate.WithLabelValues(stream, number).Set(float64(program.BitrateBPS))
+		}
+	}
+}
+
+// noPCRPID PCR_PID программы без PCR (null PID)
+const noPCRPID = "0x1FFF"
+
+// pidPrograms возвращает номер программы для PID её элементарных потоков, PMT и PCR.
+// PID, общий для нескольких программ, относится к программе с меньшим номером;
+// PID вне программ в карту не попадают (метка program пустая)
+func pidPrograms(m *tsp.StreamMetrics) map[string]string {
+	programs := make(map[string]string)
+	for _, program := range m.Programs {
+		number := strconv.Itoa(program.Number)
+		for _, pid := range append([]string{program.PMTPID, program.PCRPID}, program.PIDs...) {
+			if _, ok := programs[pid]; !ok && pid != "" && pid != noPCRPID {
+				programs[pid] = number
+			}
+		}
+	}
+	return programs
+}
+
+// updateVideo выставляет метрики видео PID, для которых разобран заголовок
+func (e *Exporter) updateVideo(stream string, m *tsp.StreamMetrics, programs map[string]string) {
+	for _, g := range []*prometheus.GaugeVec{e.videoInfo, e.videoWidth, e.videoHeight, e.videoGOPLength, e.videoIDRInterval, e.videoFrozen} {
+		g.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	}
+
+	for _, pid := range m.PIDs {
+		v := pid.Video
+		if v == nil {
+			continue
+		}
+		program := programs[pid.PID]
+		e.videoInfo.WithLabelValues(stream, program, pid.PID, pid.Codec,
+			strconv.Itoa(v.Width), strconv.Itoa(v.Height),
+			strconv.FormatFloat(v.FrameRate, 'f', -1, 64), strconv.FormatBool(v.Interlaced),
+			v.Profile, v.Level, v.Aspect).Set(1)
+		e.videoWidth.WithLabelValues(stream, program, pid.PID).Set(float64(v.Width))
+		e.videoHeight.WithLabelValues(stream, program, pid.PID).Set(float64(v.Height))
+		// GOP и интервал IDR появляются после второго ключевого кадра
+		if v.GOPLength > 0 {
+			e.videoGOPLength.WithLabelValues(stream, program, pid.PID).Set(float64(v.GOPLength))
+		}
+		if v.IDRInterval > 0 {
+			e.videoIDRInterval.WithLabelValues(stream, program, pid.PID).Set(v.IDRInterval.Seconds())
+		}
+		var frozen float64
+		if v.SuspectFrozen {
+			frozen = 1
+		}
+		e.videoFrozen.WithLabelValues(stream, program, pid.PID).Set(frozen)
+	}
+}
+
+// updateAudio выставляет метрики аудио PID, для которых разобран кадр
+func (e *Exporter) updateAudio(stream string, m *tsp.StreamMetrics, programs map[string]string) {
+	for _, g := range []*prometheus.GaugeVec{e.audioInfo, e.audioSampleRate, e.audioChannels, e.audioBitrate, e.audioDialnorm, e.audioLevel, e.audioSilence} {
+		g.DeletePartialMatch(prometheus.Labels{"stream": stream})
+	}
+
+	for _, pid := range m.PIDs {
+		a := pid.Audio
+		if a == nil {
+			continue
+		}
+		program := programs[pid.PID]
+		e.audioInfo.WithLabelValues(stream, program, pid.PID, pid.Codec, a.Format,
+			strconv.Itoa(a.SampleRate), strconv.Itoa(a.Channels), a.Layout).Set(1)
+		e.audioSampleRate.WithLabelValues(stream, program, pid.PID).Set(float64(a.SampleRate))
+		e.audioChannels.WithLabelValues(stream, program, pid.PID).Set(float64(a.Channels))
+		e.audioBitrate.WithLabelValues(stream, program, pid.PID).Set(float64(a.BitrateBPS))
+		if a.Dialnorm != 0 {
+			e.audioDialnorm.WithLabelValues(stream, program, pid.PID).Set(float64(a.Dialnorm))
+		}
+		if level := a.Level; level != nil {
+			e.audioLevel.WithLabelValues(stream, program, pid.PID, "rms").Set(level.RMSDBFS)
+			e.audioLevel.WithLabelValues(stream, program, pid.PID, "peak").Set(level.PeakDBFS)
+			var silence float64
+			if level.Silence {
+				silence = 1
+			}
+			e.audioSilence.WithLabelValues(stream, program, pid.PID).Set(silence)
 		}
 	}
 }
 
 // ClearStreamMetrics очищает метрики для потока
 func (e *Exporter) ClearStreamMetrics(streamURL string) {
+	e.streamInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 	e.streamStatus.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 	e.streamBitrate.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 	e.streamPIDCount.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 	e.streamPIDInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 	e.streamServiceInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.programInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.programPIDInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.programBitrate.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.videoInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.videoWidth.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.videoHeight.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.videoGOPLength.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.videoIDRInterval.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.videoFrozen.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioInfo.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioSampleRate.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioChannels.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioBitrate.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioDialnorm.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioLevel.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.audioSilence.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 	e.streamCCErrors.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.streamETR290.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.streamPIDBitrate.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.streamNullRatio.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.streamSenders.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.rtpPackets.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.rtpLost.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.rtpDuplicates.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.rtpReordered.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.rtpJitter.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.mdiDF.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.mdiMLR.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.udpInterarrival.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.udpInterarrivalMax.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.udpBurstMax.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrInterval.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrIntervalMax.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrIntervalAvg.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrJitter.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrJitterMax.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrAccuracyMax.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
+	e.pcrBitrate.DeletePartialMatch(prometheus.Labels{"stream": streamURL})
 }
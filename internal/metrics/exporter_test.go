@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// nativeMetrics обновление нативного runner'а, заполняющее все семейства метрик
+func nativeMetrics(url string) *tsp.StreamMetrics {
+	return &tsp.StreamMetrics{
+		StreamURL:   url,
+		Description: "Silk Way| OTCNET| HD",
+		Status:      true,
+		Bitrate:     tsp.BitrateInfo{TotalBPS: 5000000, NetBPS: 4500000, PerPID: true, NullRatio: 0.1},
+		PIDs: []tsp.PIDInfo{
+			{PID: "0x0066", PIDDecimal: 102, Type: "video", Codec: "h264", BitrateBPS: 4000000,
+				Video: &tsp.VideoInfo{Width: 1920, Height: 1080, FrameRate: 25, GOPLength: 12, IDRInterval: 480 * time.Millisecond}},
+			{PID: "0x00CA", PIDDecimal: 202, Type: "audio", Codec: "ac3", Language: "rus", BitrateBPS: 192000,
+				Audio: &tsp.AudioInfo{Format: "AC-3", SampleRate: 48000, Channels: 6, Layout: "5.1", BitrateBPS: 192000, Dialnorm: -27,
+					Level: &tsp.AudioLevel{RMSDBFS: -20, PeakDBFS: -6}}},
+		},
+		SystemPIDs: []tsp.PIDInfo{
+			{PID: "0x0000", Type: "psi", Codec: "pat", BitrateBPS: 15000},
+			{PID: "0x012E", Type: "psi", Codec: "pmt", BitrateBPS: 15000},
+			{PID: "0x1FFF", Type: "null", Codec: "stuffing", BitrateBPS: 500000},
+		},
+		ServiceInfo: tsp.ServiceInfo{ServiceName: "Silk Way", Provider: "OTCNET", ServiceType: "HD"},
+		CCErrors:    map[string]int64{"0x00CA": 2},
+		Programs: []tsp.ProgramInfo{{Number: 1000, PMTPID: "0x012E", PCRPID: "0x0066",
+			ServiceName: "Silk Way", Provider: "OTCNET", ServiceType: "HD", PIDs: []string{"0x0066", "0x00CA"}, BitrateBPS: 4207000}},
+		ETR290Checked: true,
+		ETR290Errors: []tsp.ETR290Error{
+			{Priority: 1, Indicator: "CC_error", PID: "0x00CA", Count: 2},
+			{Priority: 1, Indicator: "TS_sync_loss", PID: "none", Count: 1},
+		},
+		PCR: []tsp.PCRInfo{{PID: "0x0066", Count: 25, Intervals: []time.Duration{40 * time.Millisecond},
+			Jitter: []time.Duration{time.Microsecond}, IntervalMax: 40 * time.Millisecond, BitrateBPS: 5000000}},
+		Senders: []tsp.SenderInfo{{Address: "10.20.0.5", Datagrams: 470, Expected: true}, {Address: "10.20.0.6", Datagrams: 3}},
+		RTP:     &tsp.RTPInfo{Packets: 470, Lost: 1},
+		MDI:     &tsp.MDIInfo{Interval: time.Second, DF: 2 * time.Millisecond},
+		Arrival: &tsp.ArrivalInfo{Intervals: []time.Duration{2 * time.Millisecond}, IntervalMax: 3 * time.Millisecond, BurstMax: 4},
+	}
+}
+
+// tspMetrics обновление из вывода tsp без отчёта analyze
+func tspMetrics(url string) *tsp.StreamMetrics {
+	return &tsp.StreamMetrics{
+		StreamURL: url,
+		Status:    true,
+		Bitrate:   tsp.BitrateInfo{TotalBPS: 5000000, NetBPS: 4500000},
+		PIDs:      []tsp.PIDInfo{{PID: "0x0066", PIDDecimal: 102, Type: "video", Codec: "h264"}},
+		CCErrors:  map[string]int64{"0x0066": 1},
+	}
+}
+
+func TestUpdateMetricsLabels(t *testing.T) {
+	const url = "233.198.134.1:3333"
+	e := NewExporter(nil)
+	e.UpdateMetrics(nativeMetrics(url))
+
+	tests := []struct {
+		collector prometheus.Collector
+		name      string
+		want      string
+	}{
+		{e.streamStatus, "ts_stream_status", `
+# HELP ts_stream_status Stream status (1 = online, 0 = offline)
+# TYPE ts_stream_status gauge
+ts_stream_status{stream="233.198.134.1:3333"} 1
+`},
+		{e.streamPIDBitrate, "ts_stream_pid_bitrate_bps", `
+# HELP ts_stream_pid_bitrate_bps PID bitrate in bits per second over the last 1s window
+# TYPE ts_stream_pid_bitrate_bps gauge
+ts_stream_pid_bitrate_bps{codec="ac3",pid="0x00CA",program="1000",stream="233.198.134.1:3333",type="audio"} 192000
+ts_stream_pid_bitrate_bps{codec="h264",pid="0x0066",program="1000",stream="233.198.134.1:3333",type="video"} 4e+06
+ts_stream_pid_bitrate_bps{codec="pat",pid="0x0000",program="",stream="233.198.134.1:3333",type="psi"} 15000
+ts_stream_pid_bitrate_bps{codec="pmt",pid="0x012E",program="1000",stream="233.198.134.1:3333",type="psi"} 15000
+ts_stream_pid_bitrate_bps{codec="stuffing",pid="0x1FFF",program="",stream="233.198.134.1:3333",type="null"} 500000
+`},
+		{e.streamETR290, "ts_stream_etr290_errors_total", `
+# HELP ts_stream_etr290_errors_total Total number of ETSI TR 101 290 errors by priority, indicator and PID
+# TYPE ts_stream_etr290_errors_total counter
+ts_stream_etr290_errors_total{indicator="CC_error",pid="0x0066",priority="1",program="1000",stream="233.198.134.1:3333"} 0
+ts_stream_etr290_errors_total{indicator="CC_error",pid="0x00CA",priority="1",program="1000",stream="233.198.134.1:3333"} 2
+ts_stream_etr290_errors_total{indicator="TS_sync_loss",pid="none",priority="1",program="",stream="233.198.134.1:3333"} 1
+`},
+		{e.streamSenders, "ts_stream_sender_datagrams", `
+# HELP ts_stream_sender_datagrams UDP datagrams per sender address over the last 1s window (expected=false: not the configured source)
+# TYPE ts_stream_sender_datagrams gauge
+ts_stream_sender_datagrams{expected="false",sender="10.20.0.6",stream="233.198.134.1:3333"} 3
+ts_stream_sender_datagrams{expected="true",sender="10.20.0.5",stream="233.198.134.1:3333"} 470
+`},
+		{e.audioDialnorm, "ts_stream_audio_dialnorm_db", `
+# HELP ts_stream_audio_dialnorm_db AC-3/E-AC-3 dialogue normalization in dB
+# TYPE ts_stream_audio_dialnorm_db gauge
+ts_stream_audio_dialnorm_db{pid="0x00CA",program="1000",stream="233.198.134.1:3333"} -27
+`},
+		{e.mdiDF, "ts_stream_mdi_df_ms", `
+# HELP ts_stream_mdi_df_ms MDI delay factor (RFC 4445) over the last mdi_interval: receive buffer needed to absorb arrival jitter, ms
+# TYPE ts_stream_mdi_df_ms gauge
+ts_stream_mdi_df_ms{stream="233.198.134.1:3333"} 2
+`},
+	}
+	for _, tt := range tests {
+		if err := testutil.CollectAndCompare(tt.collector, strings.NewReader(tt.want), tt.name); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	// Гистограмма времени прихода датаграмм
+	if n := testutil.CollectAndCount(e.udpInterarrival, "ts_stream_udp_interarrival_seconds"); n != 1 {
+		t.Errorf("ts_stream_udp_interarrival_seconds series = %d, want 1", n)
+	}
+}
+
+func TestUpdateMetricsTSP(t *testing.T) {
+	e := NewExporter(nil)
+	e.UpdateMetrics(tspMetrics("233.198.134.1:3333"))
+
+	// ETR 290 из вывода tsp не проверяется: семейства нет, ошибки CC только в cc_errors
+	if n := testutil.CollectAndCount(e.streamETR290); n != 0 {
+		t.Errorf("ts_stream_etr290_errors_total series = %d, want 0", n)
+	}
+	if v := testutil.ToFloat64(e.streamCCErrors.WithLabelValues("233.198.134.1:3333", "0x0066")); v != 1 {
+		t.Errorf("ts_stream_cc_errors_total = %v, want 1", v)
+	}
+	// Без отчёта analyze битрейт по PID не публикуется
+	if n := testutil.CollectAndCount(e.streamPIDBitrate) + testutil.CollectAndCount(e.streamNullRatio); n != 0 {
+		t.Errorf("per-PID bitrate series = %d, want 0", n)
+	}
+}
+
+func TestSetStreamInfo(t *testing.T) {
+	e := NewExporter([]string{"headend", "region"})
+	e.SetStreamInfo(config.Stream{
+		URL: "233.198.134.1:3333", Description: "Silk Way| OTCNET| HD| multicast| ID001",
+		Name: "Silk Way", Provider: "OTCNET", Format: "HD", ChannelID: "ID001", Source: "10.20.0.5",
+		Labels: map[string]string{"region": "msk", "tier": "premium"},
+	})
+	e.SetStreamInfo(config.Stream{URL: "233.198.134.2:3333", Name: "Two"})
+
+	// Ключи labels, не известные при создании экспортера (tier), не экспортируются
+	want := `
+# HELP ts_stream_info Stream information from config (value always 1, info in labels)
+# TYPE ts_stream_info gauge
+ts_stream_info{channel_id="ID001",description="Silk Way| OTCNET| HD| multicast| ID001",format="HD",headend="",name="Silk Way",provider="OTCNET",region="msk",source="10.20.0.5",stream="233.198.134.1:3333"} 1
+ts_stream_info{channel_id="",description="",format="",headend="",name="Two",provider="",region="",source="",stream="233.198.134.2:3333"} 1
+`
+	if err := testutil.CollectAndCompare(e.streamInfo, strings.NewReader(want), "ts_stream_info"); err != nil {
+		t.Error(err)
+	}
+
+	// Повторный вызов заменяет серию, а не добавляет вторую
+	e.SetStreamInfo(config.Stream{URL: "233.198.134.2:3333", Name: "Renamed"})
+	if n := testutil.CollectAndCount(e.streamInfo); n != 2 {
+		t.Errorf("ts_stream_info series = %d, want 2", n)
+	}
+}
+
+func TestClearStreamMetrics(t *testing.T) {
+	const first, second = "233.198.134.1:3333", "233.198.134.2:3333"
+	e := NewExporter(nil)
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(e.collectors()...)
+
+	for _, url := range []string{first, second} {
+		e.SetStreamInfo(config.Stream{URL: url, Name: url})
+		e.UpdateMetrics(nativeMetrics(url))
+	}
+
+	// families возвращает семейства, в которых есть серии потока url
+	families := func(url string) map[string]bool {
+		t.Helper()
+		gathered, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := make(map[string]bool)
+		for _, family := range gathered {
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "stream" && label.GetValue() == url {
+						found[family.GetName()] = true
+					}
+				}
+			}
+		}
+		return found
+	}
+
+	// Обновление заполняет все семейства, иначе проверка очистки неполная
+	if got, want := len(families(first)), len(e.collectors()); got != want {
+		t.Fatalf("fixture fills %d of %d families", got, want)
+	}
+
+	e.ClearStreamMetrics(first)
+
+	for name := range families(first) {
+		t.Errorf("%s: series of the cleared stream left", name)
+	}
+	if got := len(families(second)); got != len(e.collectors()) {
+		t.Errorf("other stream has %d families after clear, want %d", got, len(e.collectors()))
+	}
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/events"
+	"github.com/otcnet/tsmonitor/internal/sla"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// StreamState состояние потока для JSON API
+type StreamState struct {
+	URL         string             `json:"url"`
+	Description string             `json:"description"`
+	Name        string             `json:"name"`
+	Provider    string             `json:"provider"`
+	Format      string             `json:"format"`
+	ChannelID   string             `json:"channel_id"`
+	Labels      map[string]string  `json:"labels,omitempty"`
+	Input       string             `json:"input"`
+	Source      string             `json:"source,omitempty"` // SSM источник
+	Running     bool               `json:"running"`          // runner запущен
+	Restarts    int                `json:"restarts"`         // перезапуски tsp / переподключения
+	Metrics     *tsp.StreamMetrics `json:"metrics"`          // null до первого обновления
+}
+
+// apiError тело ответа с ошибкой
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// registerAPI регистрирует обработчики /api/v1
+func (o *Orchestrator) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/streams", o.handleStreams)
+	mux.HandleFunc("GET /api/v1/streams/{url}", o.handleStream)
+	mux.HandleFunc("GET /api/v1/feed", o.handleFeed)
+	mux.HandleFunc("GET /api/v1/events", o.handleEvents)
+	mux.HandleFunc("GET /api/v1/sla", o.handleSLA)
+}
+
+// handleStreams отдаёт состояние всех потоков в порядке конфигурации
+func (o *Orchestrator) handleStreams(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, o.StreamStates())
+}
+
+// handleStream отдаёт состояние одного потока по URL (233.198.134.1:3333)
+func (o *Orchestrator) handleStream(w http.ResponseWriter, r *http.Request) {
+	url := r.PathValue("url")
+
+	state, ok := o.StreamState(url)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, apiError{Error: fmt.Sprintf("stream %s not found", url)})
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// handleEvents отдаёт историю событий.
+// Параметры: from, to (RFC3339 или unix время), stream, type (можно повторять
+// или через запятую), limit (последние N событий)
+func (o *Orchestrator) handleEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := parseTimeParam(query, "from")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+	to, err := parseTimeParam(query, "to")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	var limit int
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("invalid limit %q", value)})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, o.history.Query(events.Query{
+		From:    from,
+		To:      to,
+		Streams: listParam(query, "stream"),
+		Types:   listParam(query, "type"),
+		Limit:   limit,
+	}))
+}
+
+// handleSLA отдаёт отчёт о доступности потоков и провайдеров.
+// Параметры: month (2025-01) или from/to, group (stream, provider), format (json, csv)
+func (o *Orchestrator) handleSLA(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, to, err := sla.ParsePeriod(query.Get("from"), query.Get("to"), query.Get("month"), time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	o.mu.Lock()
+	streams := sla.StreamsFromConfig(o.config.Streams)
+	o.mu.Unlock()
+
+	history := o.history.Query(events.Query{
+		To:    to,
+		Types: []string{events.TypeOnline, events.TypeOffline, events.TypeStopped},
+	})
+
+	summary, err := sla.Build(streams, history, from, to, query.Get("group"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	switch format := query.Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, summary)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=sla_%s_%s.csv",
+			from.Format("20060102"), to.Format("20060102")))
+		if err := summary.WriteCSV(w); err != nil {
+			fmt.Printf("❌ SLA csv error: %v\n", err)
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("invalid format %q (json or csv)", format)})
+	}
+}
+
+// parseTimeParam разбирает время в формате RFC3339 или unix секундах
+func parseTimeParam(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q (RFC3339 or unix time expected)", name, value)
+}
+
+// listParam возвращает значения параметра, повторяющегося или через запятую
+func listParam(query url.Values, name string) []string {
+	set := toSet(query[name])
+	list := make([]string, 0, len(set))
+	for value := range set {
+		list = append(list, value)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// StreamStates возвращает состояние всех потоков в порядке конфигурации
+func (o *Orchestrator) StreamStates() []StreamState {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	states := make([]StreamState, 0, len(o.runners))
+	for _, stream := range o.config.Streams {
+		if handle, ok := o.runners[stream.URL]; ok {
+			states = append(states, handle.state())
+		}
+	}
+	return states
+}
+
+// StreamState возвращает состояние потока по URL
+func (o *Orchestrator) StreamState(url string) (StreamState, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	handle, ok := o.runners[url]
+	if !ok {
+		return StreamState{}, false
+	}
+	return handle.state(), true
+}
+
+// state собирает StreamState, вызывается под Orchestrator.mu
+func (h *streamHandle) state() StreamState {
+	input := h.stream.Input
+	if input == "" {
+		input = config.InputTSP
+	}
+
+	return StreamState{
+		URL:         h.stream.URL,
+		Description: h.stream.Description,
+		Name:        h.stream.Name,
+		Provider:    h.stream.Provider,
+		Format:      h.stream.Format,
+		ChannelID:   h.stream.ChannelID,
+		Labels:      h.stream.Labels,
+		Input:       input,
+		Source:      h.stream.Source,
+		Running:     h.runner.IsRunning(),
+		Restarts:    h.runner.Restarts(),
+		Metrics:     h.last,
+	}
+}
+
+// writeJSON пишет ответ в формате JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("❌ API encode error: %v\n", err)
+	}
+}
@@ -0,0 +1,253 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/events"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// fakeRunner runner без сети для тестов
+type fakeRunner struct {
+	ch       chan *tsp.StreamMetrics
+	restarts int
+	started  bool
+	stopped  bool
+	startErr error // ошибка запуска
+	stop     sync.Once
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{ch: make(chan *tsp.StreamMetrics, 10)}
+}
+
+func (r *fakeRunner) Start(ctx context.Context) error {
+	if r.startErr != nil {
+		return r.startErr
+	}
+	r.started = true
+	return nil
+}
+
+// Stop закрывает канал метрик, как настоящий runner после остановки
+func (r *fakeRunner) Stop() error {
+	r.stop.Do(func() {
+		r.stopped = true
+		close(r.ch)
+	})
+	return nil
+}
+
+func (r *fakeRunner) IsRunning() bool                    { return true }
+func (r *fakeRunner) Restarts() int                      { return r.restarts }
+func (r *fakeRunner) Metrics() <-chan *tsp.StreamMetrics { return r.ch }
+
+// newTestOrchestrator создаёт orchestrator с fake runner'ами для потоков cfg.
+// Runner'ы потоков, запущенных позже (при reload), тоже попадают в fakes
+func newTestOrchestrator(cfg *config.Config) (*Orchestrator, map[string]*fakeRunner) {
+	o := NewOrchestrator(cfg)
+	o.ctx = context.Background()
+	fakes := make(map[string]*fakeRunner)
+	o.newRunner = func(stream config.Stream) tsp.Runner {
+		runner := newFakeRunner()
+		fakes[stream.URL] = runner
+		return runner
+	}
+
+	for _, stream := range cfg.Streams {
+		runner := newFakeRunner()
+		fakes[stream.URL] = runner
+		o.runners[stream.URL] = &streamHandle{
+			stream: stream,
+			runner: runner,
+			cancel: func() {},
+			done:   make(chan struct{}),
+		}
+	}
+	return o, fakes
+}
+
+func TestStreamsAPI(t *testing.T) {
+	cfg := &config.Config{
+		Streams: []config.Stream{
+			{URL: "233.198.134.2:3333", Description: "Second", Input: config.InputNative},
+			{URL: "233.198.134.1:3333", Description: "First"},
+		},
+	}
+	o, fakes := newTestOrchestrator(cfg)
+	fakes["233.198.134.1:3333"].restarts = 3
+
+	handle := o.runners["233.198.134.1:3333"]
+	fakes["233.198.134.1:3333"].ch <- &tsp.StreamMetrics{
+		StreamURL: "233.198.134.1:3333",
+		Status:    true,
+		LastSeen:  time.Now(),
+		Bitrate:   tsp.BitrateInfo{TotalBPS: 5000000},
+		PIDs:      []tsp.PIDInfo{{PID: "0x0066", PIDDecimal: 102, Type: "video", Codec: "h264"}},
+		CCErrors:  map[string]int64{"0x0066": 2},
+	}
+	close(fakes["233.198.134.1:3333"].ch)
+	o.processMetrics(handle)
+
+	mux := http.NewServeMux()
+	o.registerAPI(mux)
+
+	// Список в порядке конфигурации
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/streams", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: status = %d", rec.Code)
+	}
+	var states []StreamState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(states) != 2 || states[0].URL != "233.198.134.2:3333" || states[1].URL != "233.198.134.1:3333" {
+		t.Fatalf("list: unexpected streams %+v", states)
+	}
+	if states[0].Metrics != nil || states[0].Input != config.InputNative {
+		t.Errorf("list: stream without updates = %+v", states[0])
+	}
+
+	// Один поток
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/streams/233.198.134.1:3333", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: status = %d", rec.Code)
+	}
+	var state StreamState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if state.Input != config.InputTSP || state.Restarts != 3 || !state.Running {
+		t.Errorf("get: state = %+v", state)
+	}
+	if state.Metrics == nil || state.Metrics.Bitrate.TotalBPS != 5000000 ||
+		len(state.Metrics.PIDs) != 1 || state.Metrics.CCErrors["0x0066"] != 2 {
+		t.Errorf("get: metrics = %+v", state.Metrics)
+	}
+
+	// Неизвестный поток
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/streams/239.0.0.1:1234", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestEventsAPI(t *testing.T) {
+	cfg := &config.Config{
+		Streams: []config.Stream{{URL: "233.198.134.1:3333", Description: "First"}},
+	}
+	o, fakes := newTestOrchestrator(cfg)
+
+	runner := fakes["233.198.134.1:3333"]
+	runner.ch <- &tsp.StreamMetrics{
+		StreamURL: "233.198.134.1:3333",
+		Status:    true,
+		Bitrate:   tsp.BitrateInfo{TotalBPS: 5000000},
+		PIDs:      []tsp.PIDInfo{{PID: "0x0066", Type: "video", Codec: "h264"}},
+	}
+	runner.ch <- &tsp.StreamMetrics{StreamURL: "233.198.134.1:3333"}
+	close(runner.ch)
+	o.processMetrics(o.runners["233.198.134.1:3333"])
+
+	mux := http.NewServeMux()
+	o.registerAPI(mux)
+
+	tests := []struct {
+		query  string
+		status int
+		types  []string
+	}{
+		{"", http.StatusOK, []string{"online", "offline"}},
+		{"?type=offline", http.StatusOK, []string{"offline"}},
+		{"?stream=233.198.134.2:3333", http.StatusOK, nil},
+		{"?limit=1", http.StatusOK, []string{"offline"}},
+		{"?from=2000-01-01T00:00:00Z&to=2000-01-02T00:00:00Z", http.StatusOK, nil},
+		{"?from=yesterday", http.StatusBadRequest, nil},
+		{"?limit=-1", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events"+tt.query, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got []events.Event
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.types) {
+				t.Fatalf("got %d events %+v, want %v", len(got), got, tt.types)
+			}
+			for i, ev := range got {
+				if ev.Type != tt.types[i] {
+					t.Errorf("event %d type = %s, want %s", i, ev.Type, tt.types[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSLAAPI(t *testing.T) {
+	cfg := &config.Config{
+		Streams: []config.Stream{{URL: "233.198.134.1:3333", Description: "First| Provider", Name: "First", Provider: "Provider"}},
+	}
+	o, _ := newTestOrchestrator(cfg)
+	o.history.Append(
+		events.Event{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Stream: "233.198.134.1:3333", Type: events.TypeOnline},
+		events.Event{Time: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), Stream: "233.198.134.1:3333", Type: events.TypeOffline},
+	)
+
+	mux := http.NewServeMux()
+	o.registerAPI(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/sla?from=2025-01-01T00:00:00Z&to=2025-01-02T00:00:00Z", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("json: status = %d: %s", rec.Code, rec.Body)
+	}
+	var summary struct {
+		Streams []struct {
+			Stream       string   `json:"stream"`
+			Provider     string   `json:"provider"`
+			Availability *float64 `json:"availability_percent"`
+		} `json:"streams"`
+		Providers []json.RawMessage `json:"providers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Streams) != 1 || len(summary.Providers) != 1 || summary.Streams[0].Provider != "Provider" ||
+		summary.Streams[0].Availability == nil || *summary.Streams[0].Availability != 50 {
+		t.Errorf("json: summary = %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/sla?month=2025-01&group=provider&format=csv", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("csv: status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for _, query := range []string{"?month=jan", "?group=channel", "?format=xml"} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/sla"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/events"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+// Типы событий потока обновлений
+const (
+	EventMetrics = "metrics" // очередное обновление StreamMetrics
+	EventStatus  = "status"  // переход online/offline
+	EventLog     = "event"   // запись в истории событий (PID, сервис, статус)
+)
+
+// feedBuffer сколько событий может ждать медленный подписчик,
+// дальше события для него отбрасываются
+const feedBuffer = 256
+
+// feedHeartbeat интервал комментариев SSE, чтобы прокси не закрывали соединение
+const feedHeartbeat = 15 * time.Second
+
+// Event событие в потоке обновлений /api/v1/feed
+type Event struct {
+	Type    string             `json:"type"`
+	Stream  string             `json:"stream"`
+	Time    time.Time          `json:"time"`
+	Online  *bool              `json:"online,omitempty"`  // для status: новое состояние
+	Metrics *tsp.StreamMetrics `json:"metrics,omitempty"` // для metrics
+	Entry   *events.Event      `json:"event,omitempty"`   // для event
+}
+
+// feedHub раздаёт события всем подписчикам, не блокируя отправителя
+type feedHub struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// subscriber подписчик с фильтрами по потокам и типам событий
+type subscriber struct {
+	ch      chan Event
+	streams map[string]bool // пусто = все потоки
+	types   map[string]bool // пусто = все типы
+	dropped atomic.Int64    // отброшено из-за переполнения буфера
+}
+
+// newFeedHub создает пустой feedHub
+func newFeedHub() *feedHub {
+	return &feedHub{subscribers: make(map[*subscriber]struct{})}
+}
+
+// subscribe регистрирует подписчика
+func (h *feedHub) subscribe(streams, types []string) *subscriber {
+	sub := &subscriber{
+		ch:      make(chan Event, feedBuffer),
+		streams: toSet(streams),
+		types:   toSet(types),
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// unsubscribe удаляет подписчика
+func (h *feedHub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+// publish отправляет событие подходящим подписчикам.
+// Если буфер подписчика полон, событие для него теряется
+func (h *feedHub) publish(ev Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if !sub.matches(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// matches проверяет фильтры подписчика
+func (s *subscriber) matches(ev Event) bool {
+	if len(s.streams) > 0 && !s.streams[ev.Stream] {
+		return false
+	}
+	if len(s.types) > 0 && !s.types[ev.Type] {
+		return false
+	}
+	return true
+}
+
+// toSet превращает список (в том числе через запятую) в множество
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				set[item] = true
+			}
+		}
+	}
+	return set
+}
+
+// publishMetrics публикует обновление и, если статус поменялся, переход online/offline
+func (o *Orchestrator) publishMetrics(previous, metrics *tsp.StreamMetrics) {
+	now := time.Now()
+
+	if previous != nil && previous.Status != metrics.Status {
+		online := metrics.Status
+		o.feed.publish(Event{
+			Type:   EventStatus,
+			Stream: metrics.StreamURL,
+			Time:   now,
+			Online: &online,
+		})
+	}
+
+	o.feed.publish(Event{
+		Type:    EventMetrics,
+		Stream:  metrics.StreamURL,
+		Time:    now,
+		Metrics: metrics,
+	})
+}
+
+// publishEvent публикует запись истории событий
+func (o *Orchestrator) publishEvent(entry events.Event) {
+	o.feed.publish(Event{
+		Type:   EventLog,
+		Stream: entry.Stream,
+		Time:   entry.Time,
+		Entry:  &entry,
+	})
+}
+
+// handleFeed отдаёт события в формате Server-Sent Events.
+// Фильтры: ?stream=233.198.134.1:3333&type=status (можно повторять или через запятую)
+func (o *Orchestrator) handleFeed(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "streaming not supported"})
+		return
+	}
+
+	query := r.URL.Query()
+	for t := range toSet(query["type"]) {
+		if t != EventMetrics && t != EventStatus && t != EventLog {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("unknown event type %q", t)})
+			return
+		}
+	}
+
+	sub := o.feed.subscribe(query["stream"], query["type"])
+	defer func() {
+		o.feed.unsubscribe(sub)
+		if dropped := sub.dropped.Load(); dropped > 0 {
+			fmt.Printf("⚠️  Feed client %s was too slow, %d events dropped\n", r.RemoteAddr, dropped)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(feedHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": ping\n\n")
+			flusher.Flush()
+
+		case ev := <-sub.ch:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				fmt.Printf("❌ Feed encode error: %v\n", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+func TestFeedHubFilters(t *testing.T) {
+	hub := newFeedHub()
+	all := hub.subscribe(nil, nil)
+	status := hub.subscribe([]string{"233.198.134.1:3333"}, []string{"status"})
+
+	hub.publish(Event{Type: EventMetrics, Stream: "233.198.134.1:3333"})
+	hub.publish(Event{Type: EventStatus, Stream: "233.198.134.2:3333"})
+	hub.publish(Event{Type: EventStatus, Stream: "233.198.134.1:3333"})
+
+	if len(all.ch) != 3 {
+		t.Errorf("unfiltered subscriber got %d events, want 3", len(all.ch))
+	}
+	if len(status.ch) != 1 {
+		t.Fatalf("filtered subscriber got %d events, want 1", len(status.ch))
+	}
+	if ev := <-status.ch; ev.Type != EventStatus || ev.Stream != "233.198.134.1:3333" {
+		t.Errorf("filtered subscriber got %+v", ev)
+	}
+
+	// Переполненный подписчик не блокирует публикацию
+	for i := 0; i < feedBuffer+10; i++ {
+		hub.publish(Event{Type: EventMetrics, Stream: "233.198.134.1:3333"})
+	}
+	if got := all.dropped.Load(); got != 13 {
+		t.Errorf("dropped = %d, want 13", got)
+	}
+
+	hub.unsubscribe(all)
+	hub.unsubscribe(status)
+	if len(hub.subscribers) != 0 {
+		t.Errorf("subscribers left after unsubscribe: %d", len(hub.subscribers))
+	}
+}
+
+func TestFeedSSE(t *testing.T) {
+	cfg := &config.Config{
+		Streams: []config.Stream{{URL: "233.198.134.1:3333", Description: "First"}},
+	}
+	o, fakes := newTestOrchestrator(cfg)
+
+	mux := http.NewServeMux()
+	o.registerAPI(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/feed?type=status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	// Ждём, пока обработчик подпишется
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		o.feed.mu.RLock()
+		n := len(o.feed.subscribers)
+		o.feed.mu.RUnlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("feed handler did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	runner := fakes["233.198.134.1:3333"]
+	runner.ch <- &tsp.StreamMetrics{StreamURL: "233.198.134.1:3333", Status: true}
+	runner.ch <- &tsp.StreamMetrics{StreamURL: "233.198.134.1:3333", Status: false}
+	close(runner.ch)
+	o.processMetrics(o.runners["233.198.134.1:3333"])
+
+	reader := bufio.NewReader(resp.Body)
+	var eventType, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	if eventType != EventStatus {
+		t.Fatalf("event type = %q, want %q", eventType, EventStatus)
+	}
+	var ev Event
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Stream != "233.198.134.1:3333" || ev.Online == nil || *ev.Online {
+		t.Errorf("event = %s", data)
+	}
+}
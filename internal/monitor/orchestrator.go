@@ -4,30 +4,59 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
 	"sync"
+	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/otcnet/tsmonitor/internal/alert"
 	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/events"
 	"github.com/otcnet/tsmonitor/internal/metrics"
 	"github.com/otcnet/tsmonitor/internal/tsp"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Orchestrator управляет всеми StreamingRunner'ами и метриками
 type Orchestrator struct {
 	config   *config.Config
 	exporter *metrics.Exporter
-	runners  map[string]*tsp.StreamingRunner
+	runners  map[string]*streamHandle
+	feed     *feedHub
+	alerts   *alert.Engine
+	history  *events.Store
+	ctx      context.Context
+
+	newRunner func(stream config.Stream) tsp.Runner // создание runner'а, подменяется в тестах
+
 	mu       sync.Mutex
+	reloadMu sync.Mutex
 	wg       sync.WaitGroup
 }
 
+// streamHandle runner одного потока и средства для его остановки
+type streamHandle struct {
+	stream config.Stream
+	runner tsp.Runner
+	cancel context.CancelFunc
+	done   chan struct{} // закрывается, когда обработаны последние метрики
+
+	last *tsp.StreamMetrics // последнее обновление, под Orchestrator.mu
+}
+
 // NewOrchestrator создаёт новый orchestrator
 func NewOrchestrator(cfg *config.Config) *Orchestrator {
-	return &Orchestrator{
+	o := &Orchestrator{
 		config:   cfg,
-		exporter: metrics.NewExporter(),
-		runners:  make(map[string]*tsp.StreamingRunner),
+		exporter: metrics.NewExporter(cfg.LabelKeys()),
+		runners:  make(map[string]*streamHandle),
+		feed:     newFeedHub(),
+		alerts:   alert.NewEngine(cfg.Alerts),
+		history:  events.NewStore(cfg.Events.Retention),
+
+		newRunner: newRunner,
 	}
+	o.alerts.SetStreams(cfg.Streams)
+	return o
 }
 
 // Start запускает мониторинг всех потоков
@@ -37,12 +66,27 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to register metrics: %w", err)
 	}
 
+	// Интерфейсы потоков должны существовать на хосте
+	if err := tsp.CheckInterfaces(o.config.Interfaces()); err != nil {
+		return fmt.Errorf("invalid interfaces: %w", err)
+	}
+
+	// Загружаем историю событий
+	if err := o.history.Open(o.config.Events.Path); err != nil {
+		return fmt.Errorf("failed to open events history: %w", err)
+	}
+
+	o.ctx = ctx
+
+	// Отправка уведомлений алертов
+	go o.alerts.Run(ctx)
+
 	// Запускаем HTTP сервер для метрик
-	go o.startMetricsServer()
+	go o.startMetricsServer(o.config.MetricsPort)
 
 	// Запускаем runner для каждого потока
 	for _, stream := range o.config.Streams {
-		if err := o.startStreamMonitoring(ctx, stream); err != nil {
+		if err := o.startStreamMonitoring(stream); err != nil {
 			return fmt.Errorf("failed to start monitoring for %s: %w", stream.URL, err)
 		}
 	}
@@ -54,72 +98,284 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 }
 
 // startStreamMonitoring запускает мониторинг одного потока
-func (o *Orchestrator) startStreamMonitoring(ctx context.Context, stream config.Stream) error {
-	runner := tsp.NewStreamingRunner(
-		o.config.Interface,
-		stream.URL,
-		stream.Description,
-	)
+func (o *Orchestrator) startStreamMonitoring(stream config.Stream) error {
+	runner := o.newRunner(stream)
 
-	// Сохраняем runner
-	o.mu.Lock()
-	o.runners[stream.URL] = runner
-	o.mu.Unlock()
+	// У каждого потока свой контекст, чтобы его можно было остановить при reload
+	ctx, cancel := context.WithCancel(o.ctx)
+	handle := &streamHandle{
+		stream: stream,
+		runner: runner,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
 
 	// Запускаем runner
 	if err := runner.Start(ctx); err != nil {
+		cancel()
 		return err
 	}
 
+	// Сохраняем runner
+	o.mu.Lock()
+	o.runners[stream.URL] = handle
+	o.mu.Unlock()
+
+	o.exporter.SetStreamInfo(stream)
+
 	// Запускаем горутину для чтения метрик
 	o.wg.Add(1)
 	go func() {
 		defer o.wg.Done()
-		o.processMetrics(runner)
+		defer close(handle.done)
+		o.processMetrics(handle)
 	}()
 
 	return nil
 }
 
-// processMetrics читает метрики из канала и обновляет Prometheus
-func (o *Orchestrator) processMetrics(runner *tsp.StreamingRunner) {
-	for metrics := range runner.MetricsChan {
+// stopStreamMonitoring останавливает мониторинг потока и удаляет его метрики
+func (o *Orchestrator) stopStreamMonitoring(url string) {
+	o.mu.Lock()
+	handle, ok := o.runners[url]
+	delete(o.runners, url)
+	o.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	handle.cancel()
+	handle.runner.Stop()
+
+	// Метрики удаляем только после того, как обработано последнее обновление,
+	// иначе оно создаст их заново
+	<-handle.done
+	o.exporter.ClearStreamMetrics(url)
+	o.recordStopped(url, "stream removed or restarted")
+}
+
+// newRunner создаёт runner в зависимости от способа приёма потока
+func newRunner(stream config.Stream) tsp.Runner {
+	if stream.Input == config.InputNative || stream.Input == config.InputRTP {
+		runner := tsp.NewNativeRunner(stream.Interface, stream.URL, stream.Description)
+		runner.Source = stream.Source
+		runner.RTP = stream.Input == config.InputRTP
+		runner.MDIInterval = stream.MDIInterval
+		runner.SilenceLevel = stream.SilenceLevel
+		runner.SilenceDuration = stream.SilenceDuration
+		return runner
+	}
+	runner := tsp.NewStreamingRunner(stream.Interface, stream.URL, stream.Description)
+	runner.Source = stream.Source
+	runner.PIDBitrate = stream.PIDBitrate
+	return runner
+}
+
+// processMetrics читает метрики из канала, обновляет Prometheus,
+// сохраняет последний снимок для API и рассылает его подписчикам feed
+func (o *Orchestrator) processMetrics(handle *streamHandle) {
+	for metrics := range handle.runner.Metrics() {
 		// Обновляем Prometheus метрики
 		o.exporter.UpdateMetrics(metrics)
+
+		o.mu.Lock()
+		previous := handle.last
+		handle.last = metrics
+		o.mu.Unlock()
+
+		o.publishMetrics(previous, metrics)
+		o.recordEvents(previous, metrics)
+
+		// Проверяем правила алертов
+		o.alerts.Evaluate(metrics)
+	}
+}
+
+// recordEvents записывает в историю переходы online/offline и изменения состава потока
+func (o *Orchestrator) recordEvents(previous, metrics *tsp.StreamMetrics) {
+	// После запуска сравниваем с последним статусом из истории
+	var known *bool
+	if previous == nil {
+		known = o.history.LastStatus(metrics.StreamURL)
+	}
+
+	detected := events.Detect(previous, metrics, known, time.Now())
+	if err := o.history.Append(detected...); err != nil {
+		fmt.Printf("❌ [%s] failed to record events: %v\n", metrics.StreamURL, err)
+	}
+	for i := range detected {
+		o.publishEvent(detected[i])
+	}
+}
+
+// recordStopped записывает в историю остановку мониторинга потока: до следующего
+// online/offline его статус неизвестен и время не входит в SLA
+func (o *Orchestrator) recordStopped(url, reason string) {
+	if o.history.LastStatus(url) == nil {
+		return // статус и так неизвестен
+	}
+	ev := events.Stopped(url, reason, time.Now())
+	if err := o.history.Append(ev); err != nil {
+		fmt.Printf("❌ [%s] failed to record events: %v\n", url, err)
+	}
+	o.publishEvent(ev)
+}
+
+// Reload применяет новую конфигурацию без перезапуска процесса:
+// запускает добавленные потоки, останавливает удалённые и перезапускает
+// только те, у которых изменились настройки
+func (o *Orchestrator) Reload(cfg *config.Config) error {
+	o.reloadMu.Lock()
+	defer o.reloadMu.Unlock()
+
+	// Конфиг с несуществующим интерфейсом не применяется целиком
+	if err := tsp.CheckInterfaces(cfg.Interfaces()); err != nil {
+		return fmt.Errorf("invalid interfaces, keeping current config: %w", err)
 	}
+
+	o.mu.Lock()
+	old := o.config
+	current := make(map[string]config.Stream, len(o.runners))
+	for url, handle := range o.runners {
+		current[url] = handle.stream
+	}
+	o.mu.Unlock()
+
+	if cfg.MetricsPort != old.MetricsPort {
+		fmt.Printf("⚠️  metrics_port change (%d -> %d) requires restart\n", old.MetricsPort, cfg.MetricsPort)
+	}
+	if cfg.Events != old.Events {
+		fmt.Printf("⚠️  events settings change requires restart\n")
+	}
+	if !reflect.DeepEqual(cfg.LabelKeys(), old.LabelKeys()) {
+		fmt.Printf("⚠️  stream label names change (%v -> %v) requires restart, values of new labels are not exported\n",
+			old.LabelKeys(), cfg.LabelKeys())
+	}
+
+	wanted := make(map[string]config.Stream, len(cfg.Streams))
+	for _, stream := range cfg.Streams {
+		wanted[stream.URL] = stream
+	}
+
+	var removed, changed, updated []string
+	for url, stream := range current {
+		next, ok := wanted[url]
+		switch {
+		case !ok:
+			removed = append(removed, url)
+		case runnerChanged(stream, next):
+			changed = append(changed, url)
+		case !reflect.DeepEqual(stream, next):
+			updated = append(updated, url) // пороги, метки: без перезапуска runner
+		}
+	}
+
+	// Останавливаем удалённые и изменённые потоки параллельно
+	var stopWG sync.WaitGroup
+	for _, url := range append(removed, changed...) {
+		stopWG.Add(1)
+		go func(url string) {
+			defer stopWG.Done()
+			o.stopStreamMonitoring(url)
+		}(url)
+	}
+	stopWG.Wait()
+
+	// Активные алерты удалённых потоков снимаются
+	for _, url := range removed {
+		o.alerts.Forget(url)
+	}
+	if !reflect.DeepEqual(old.Alerts, cfg.Alerts) {
+		o.alerts.Update(cfg.Alerts)
+	}
+	o.alerts.SetStreams(cfg.Streams)
+
+	o.mu.Lock()
+	o.config = cfg
+	for _, url := range updated {
+		if handle, ok := o.runners[url]; ok {
+			handle.stream = wanted[url]
+		}
+	}
+	o.mu.Unlock()
+
+	for _, url := range updated {
+		o.exporter.SetStreamInfo(wanted[url])
+	}
+
+	var added int
+	var errs []error
+	for _, stream := range cfg.Streams {
+		if _, running := current[stream.URL]; running && !contains(changed, stream.URL) {
+			continue
+		}
+		if err := o.startStreamMonitoring(stream); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", stream.URL, err))
+			// Изменённый поток не должен пропасть: возвращаем прежние настройки
+			if previous, running := current[stream.URL]; running {
+				if err := o.startStreamMonitoring(previous); err != nil {
+					errs = append(errs, fmt.Errorf("%s: restore previous settings: %w", stream.URL, err))
+				}
+			}
+			continue
+		}
+		if _, running := current[stream.URL]; !running {
+			added++
+		}
+	}
+
+	fmt.Printf("🔄 Config reloaded: +%d added, -%d removed, ~%d restarted, %d updated, %d total\n",
+		added, len(removed), len(changed), len(updated), o.GetRunnerCount())
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to start %d streams: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// runnerChanged проверяет, изменились ли настройки, с которыми запущен runner
+func runnerChanged(stream, next config.Stream) bool {
+	return stream.Input != next.Input ||
+		stream.Interface != next.Interface ||
+		stream.Source != next.Source ||
+		stream.Description != next.Description ||
+		stream.PIDBitrate != next.PIDBitrate ||
+		stream.MDIInterval != next.MDIInterval ||
+		stream.SilenceLevel != next.SilenceLevel ||
+		stream.SilenceDuration != next.SilenceDuration
+}
+
+// contains проверяет наличие строки в списке
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
 
 // startMetricsServer запускает HTTP сервер для Prometheus метрик
-func (o *Orchestrator) startMetricsServer() {
+func (o *Orchestrator) startMetricsServer(port int) {
 	mux := http.NewServeMux()
-	
+
 	// Endpoint для метрик
 	mux.Handle("/metrics", promhttp.Handler())
-	
+
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintf(w, "OK\n")
 	})
-	
-	// Информация о статусе
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		o.mu.Lock()
-		runnerCount := len(o.runners)
-		o.mu.Unlock()
-		
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, "<html><body>")
-		fmt.Fprintf(w, "<h1>TSMonitor</h1>")
-		fmt.Fprintf(w, "<p>Monitoring %d streams</p>", runnerCount)
-		fmt.Fprintf(w, "<ul>")
-		fmt.Fprintf(w, "<li><a href='/metrics'>/metrics</a> - Prometheus metrics</li>")
-		fmt.Fprintf(w, "<li><a href='/health'>/health</a> - Health check</li>")
-		fmt.Fprintf(w, "</ul>")
-		fmt.Fprintf(w, "</body></html>")
-	})
 
-	addr := fmt.Sprintf(":%d", o.config.MetricsPort)
+	// JSON API состояния потоков
+	o.registerAPI(mux)
+
+	// Web-интерфейс
+	o.registerWeb(mux)
+
+	addr := fmt.Sprintf(":%d", port)
 	server := &http.Server{
 		Addr:    addr,
 		Handler: mux,
@@ -133,16 +389,28 @@ func (o *Orchestrator) startMetricsServer() {
 // Stop останавливает все runner'ы
 func (o *Orchestrator) Stop() {
 	fmt.Println("🛑 Stopping all runners...")
-	
+
 	o.mu.Lock()
-	for _, runner := range o.runners {
-		runner.Stop()
+	urls := make([]string, 0, len(o.runners))
+	for url, handle := range o.runners {
+		handle.cancel()
+		handle.runner.Stop()
+		urls = append(urls, url)
 	}
 	o.mu.Unlock()
 
 	// Ждём завершения всех горутин
 	o.wg.Wait()
-	
+
+	// Время, пока tsmonitor выключен, не должно считаться в SLA ни как online, ни как offline
+	for _, url := range urls {
+		o.recordStopped(url, "tsmonitor is shutting down")
+	}
+
+	if err := o.history.Close(); err != nil {
+		fmt.Printf("❌ Failed to close events history: %v\n", err)
+	}
+
 	fmt.Println("✅ All runners stopped")
 }
 
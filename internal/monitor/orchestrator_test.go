@@ -0,0 +1,251 @@
+package monitor
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/events"
+	"github.com/otcnet/tsmonitor/internal/tsp"
+)
+
+func TestReload(t *testing.T) {
+	const (
+		first  = "233.198.134.1:3333"
+		second = "233.198.134.2:3333"
+		third  = "233.198.134.3:3333"
+	)
+	// Тот же loopback, заданный именем вместо адреса
+	ifi, _, err := tsp.ResolveInterface("127.0.0.1")
+	if err != nil {
+		t.Skipf("no loopback address: %v", err)
+	}
+	lo := ifi.Name
+
+	base := func() []config.Stream {
+		return []config.Stream{
+			{URL: first, Description: "First", Interface: "127.0.0.1", Labels: map[string]string{"region": "north"}},
+			{URL: second, Description: "Second", Interface: "127.0.0.1", Input: config.InputNative, MDIInterval: time.Second},
+			{URL: third, Description: "Third", Interface: "127.0.0.1"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(streams []config.Stream) []config.Stream
+		started []string // новые runner'ы
+		stopped []string // остановленные runner'ы
+		updated []string // настройки применены без перезапуска
+	}{
+		{
+			name:   "unchanged",
+			change: func(s []config.Stream) []config.Stream { return s },
+		},
+		{
+			name: "stream added",
+			change: func(s []config.Stream) []config.Stream {
+				return append(s, config.Stream{URL: "233.198.134.4:3333", Interface: "127.0.0.1"})
+			},
+			started: []string{"233.198.134.4:3333"},
+		},
+		{
+			name:    "stream removed",
+			change:  func(s []config.Stream) []config.Stream { return s[:2] },
+			stopped: []string{third},
+		},
+		{
+			name:    "interface changed",
+			change:  func(s []config.Stream) []config.Stream { s[0].Interface = lo; return s },
+			started: []string{first},
+			stopped: []string{first},
+		},
+		{
+			name:    "source changed",
+			change:  func(s []config.Stream) []config.Stream { s[0].Source = "10.0.0.1"; return s },
+			started: []string{first},
+			stopped: []string{first},
+		},
+		{
+			name:    "input changed",
+			change:  func(s []config.Stream) []config.Stream { s[0].Input = config.InputNative; return s },
+			started: []string{first},
+			stopped: []string{first},
+		},
+		{
+			name:    "description changed",
+			change:  func(s []config.Stream) []config.Stream { s[2].Description = "Renamed"; return s },
+			started: []string{third},
+			stopped: []string{third},
+		},
+		{
+			name:    "pid bitrate changed",
+			change:  func(s []config.Stream) []config.Stream { s[0].PIDBitrate = true; return s },
+			started: []string{first},
+			stopped: []string{first},
+		},
+		{
+			name:    "mdi interval changed",
+			change:  func(s []config.Stream) []config.Stream { s[1].MDIInterval = 5 * time.Second; return s },
+			started: []string{second},
+			stopped: []string{second},
+		},
+		{
+			name: "silence settings changed",
+			change: func(s []config.Stream) []config.Stream {
+				s[1].SilenceLevel = -50
+				s[2].SilenceDuration = 30 * time.Second
+				return s
+			},
+			started: []string{second, third},
+			stopped: []string{second, third},
+		},
+		{
+			name: "thresholds, webhooks and labels in place",
+			change: func(s []config.Stream) []config.Stream {
+				s[0].Thresholds = map[string]float64{"cc_errors": 10}
+				s[1].Webhooks = []string{"http://127.0.0.1:9000/hook"}
+				s[2].Labels = map[string]string{"region": "south"}
+				s[2].Bitrate = config.BitrateRange{Min: 1000000}
+				return s
+			},
+			updated: []string{first, second, third},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, fakes := newTestOrchestrator(&config.Config{Streams: base()})
+
+			// Обработчики метрик, как после Start
+			before := make(map[string]*fakeRunner)
+			for url, handle := range o.runners {
+				before[url] = fakes[url]
+				fakes[url].restarts = 2
+				handle.last = &tsp.StreamMetrics{StreamURL: url, CCErrors: map[string]int64{"0x0066": 5}}
+				o.history.Append(events.Event{Time: time.Now(), Stream: url, Type: events.TypeOnline})
+				o.wg.Add(1)
+				go func(handle *streamHandle) {
+					defer o.wg.Done()
+					defer close(handle.done)
+					o.processMetrics(handle)
+				}(handle)
+			}
+
+			next := &config.Config{Streams: tt.change(base())}
+			if err := o.Reload(next); err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+
+			var started, stopped []string
+			for url, runner := range fakes {
+				if runner.started {
+					started = append(started, url)
+				}
+			}
+			for url, runner := range before {
+				if runner.stopped {
+					stopped = append(stopped, url)
+				}
+			}
+			assertURLs(t, "started", started, tt.started)
+			assertURLs(t, "stopped", stopped, tt.stopped)
+
+			// Остановленные потоки получают событие stopped: статус до следующего обновления неизвестен
+			var recorded []string
+			for _, ev := range o.history.Query(events.Query{Types: []string{events.TypeStopped}}) {
+				recorded = append(recorded, ev.Stream)
+			}
+			assertURLs(t, "stopped events", recorded, tt.stopped)
+
+			if o.config != next || o.GetRunnerCount() != len(next.Streams) {
+				t.Errorf("config not applied: %d runners, want %d", o.GetRunnerCount(), len(next.Streams))
+			}
+
+			for _, stream := range next.Streams {
+				handle, ok := o.runners[stream.URL]
+				if !ok {
+					t.Errorf("%s: not running after reload", stream.URL)
+					continue
+				}
+				if contains(tt.started, stream.URL) {
+					// Новый runner: счётчики с нуля
+					if handle.runner == before[stream.URL] || handle.last != nil || handle.runner.Restarts() != 0 {
+						t.Errorf("%s: restarted stream kept the old runner state", stream.URL)
+					}
+					continue
+				}
+
+				// Поток не перезапускался: тот же runner, счётчики и последнее обновление
+				if handle.runner != before[stream.URL] || handle.runner.Restarts() != 2 ||
+					handle.last == nil || handle.last.CCErrors["0x0066"] != 5 {
+					t.Errorf("%s: untouched stream lost its runner or counters", stream.URL)
+				}
+				if contains(tt.updated, stream.URL) && !reflect.DeepEqual(handle.stream, stream) {
+					t.Errorf("%s: settings not applied in place: %+v", stream.URL, handle.stream)
+				}
+			}
+
+			for url := range before {
+				if _, ok := o.runners[url]; !ok && !contains(tt.stopped, url) {
+					t.Errorf("%s: stopped but not expected to", url)
+				}
+			}
+		})
+	}
+}
+
+// assertURLs сравнивает списки потоков без учёта порядка
+func assertURLs(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", what, got, want)
+			return
+		}
+	}
+}
+
+func TestReloadKeepsStreamWhenRestartFails(t *testing.T) {
+	const url = "233.198.134.1:3333"
+	cfg := &config.Config{Streams: []config.Stream{{URL: url, Description: "First", Interface: "127.0.0.1"}}}
+	o, fakes := newTestOrchestrator(cfg)
+	factory := o.newRunner
+	o.newRunner = func(stream config.Stream) tsp.Runner {
+		runner := factory(stream).(*fakeRunner)
+		if stream.Source != "" {
+			runner.startErr = errors.New("start failed")
+		}
+		return runner
+	}
+	old := fakes[url]
+	close(o.runners[url].done)
+
+	next := &config.Config{Streams: []config.Stream{{URL: url, Description: "First", Interface: "127.0.0.1", Source: "10.0.0.1"}}}
+	if err := o.Reload(next); err == nil {
+		t.Fatal("Reload() error = nil, want start failure")
+	}
+
+	// Поток продолжает работать с прежними настройками, следующий reload повторит попытку
+	handle, ok := o.runners[url]
+	if !ok {
+		t.Fatal("stream removed after failed restart")
+	}
+	if !old.stopped || handle.runner == old || !fakes[url].started {
+		t.Error("stream not restarted with previous settings")
+	}
+	if handle.stream.Source != "" {
+		t.Errorf("Source = %q, want previous settings", handle.stream.Source)
+	}
+	if !runnerChanged(handle.stream, next.Streams[0]) {
+		t.Error("failed settings recorded as applied")
+	}
+}
@@ -0,0 +1,21 @@
+package monitor
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFS встроенный web-интерфейс (без внешних CDN)
+//
+//go:embed web
+var webFS embed.FS
+
+// registerWeb отдаёт dashboard с корня HTTP сервера
+func (o *Orchestrator) registerWeb(mux *http.ServeMux) {
+	static, err := fs.Sub(webFS, "web")
+	if err != nil {
+		panic(err) // каталог встроен при сборке
+	}
+	mux.Handle("/", http.FileServerFS(static))
+}
@@ -0,0 +1,281 @@
+// TSMonitor dashboard: loads /api/v1/streams and applies live updates
+// from the /api/v1/feed Server-Sent Events stream.
+"use strict";
+
+const REFRESH_INTERVAL = 30000; // full reload: restarts, added/removed streams
+const RENDER_INTERVAL = 500;    // live updates are batched into one render
+const MAX_EVENTS = 100;
+
+const state = {
+  streams: [],      // latest /api/v1/streams response, patched by the feed
+  selected: null,   // url of the stream shown in the detail panel
+  events: [],       // recent entries of the event log, newest first
+  dirty: false,     // render pending
+};
+
+// el creates a DOM element; text is always set via textContent.
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (key === "class") node.className = value;
+    else if (key === "onclick") node.addEventListener("click", value);
+    else node.setAttribute(key, value);
+  }
+  for (const child of children) {
+    if (child === null || child === undefined) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+function formatBitrate(bps) {
+  if (!bps) return "0";
+  if (bps >= 1e6) return (bps / 1e6).toFixed(2) + " Mbit/s";
+  return (bps / 1e3).toFixed(0) + " kbit/s";
+}
+
+function formatDuration(ns) {
+  if (!ns) return "0";
+  if (Math.abs(ns) >= 1e6) return (ns / 1e6).toFixed(1) + " ms";
+  return (ns / 1e3).toFixed(1) + " µs";
+}
+
+// 1920x1080i25 High@4.0 16:9, GOP 12
+function formatVideo(v) {
+  let text = v.width + "x" + v.height + (v.interlaced ? "i" : "p") + (v.frame_rate || "");
+  if (v.profile) text += " " + v.profile + (v.level ? "@" + v.level : "");
+  if (v.aspect) text += " " + v.aspect;
+  if (v.gop_length) text += ", GOP " + v.gop_length;
+  if (v.suspect_frozen) text += ", FROZEN? (" + v.frozen_reason.replace(/_/g, " ") + ")";
+  return text;
+}
+
+// MPEG-1 Layer II 48 kHz stereo 192 kbit/s
+function formatAudio(a) {
+  let text = a.format + " " + a.sample_rate / 1000 + " kHz " + a.layout;
+  if (a.bitrate_bps) text += " " + Math.round(a.bitrate_bps / 1000) + " kbit/s";
+  if (a.dialnorm_db) text += ", dialnorm " + a.dialnorm_db + " dB";
+  if (a.level) text += ", RMS " + a.level.rms_dbfs.toFixed(1) + " dBFS" + (a.level.silence ? ", SILENCE" : "");
+  return text;
+}
+
+function formatTime(value) {
+  const date = new Date(value);
+  if (isNaN(date) || date.getFullYear() < 2000) return "—";
+  return date.toLocaleTimeString();
+}
+
+function streamName(stream) {
+  return stream.name || stream.url;
+}
+
+function errorCount(metrics) {
+  if (!metrics) return 0;
+  let total = 0;
+  for (const count of Object.values(metrics.cc_errors || {})) total += count;
+  for (const e of metrics.etr290_errors || []) {
+    if (e.indicator !== "CC_error") total += e.count;
+  }
+  return total;
+}
+
+// streamClass maps the stream state to a tile colour.
+function streamClass(stream) {
+  const m = stream.metrics;
+  if (!m) return "nodata";
+  if (!m.status) return "offline";
+  if (errorCount(m) > 0) return "warn";
+  return "online";
+}
+
+function addEvent(entry) {
+  state.events.unshift(entry);
+  state.events.length = Math.min(state.events.length, MAX_EVENTS);
+}
+
+function renderSummary() {
+  const counts = { online: 0, warn: 0, offline: 0, nodata: 0 };
+  for (const stream of state.streams) counts[streamClass(stream)]++;
+  const summary = document.getElementById("summary");
+  summary.replaceChildren(
+    el("span", {}, state.streams.length + " streams"),
+    el("span", {}, "🟢 " + counts.online),
+    el("span", {}, "🟡 " + counts.warn),
+    el("span", {}, "🔴 " + counts.offline),
+    el("span", {}, "⚪ " + counts.nodata),
+  );
+}
+
+function matchesFilter(stream) {
+  const filter = document.getElementById("filter").value.trim().toLowerCase();
+  if (!filter) return true;
+  const service = stream.metrics ? stream.metrics.service_info : {};
+  return [stream.url, stream.description, stream.name, stream.provider, stream.channel_id, service.service_name, service.provider]
+    .some((value) => (value || "").toLowerCase().includes(filter));
+}
+
+function renderGrid() {
+  const grid = document.getElementById("grid");
+  const tiles = state.streams.filter(matchesFilter).map((stream) => {
+    const m = stream.metrics;
+    const classes = ["tile", streamClass(stream)];
+    if (stream.url === state.selected) classes.push("selected");
+    return el("div", { class: classes.join(" "), title: stream.description, onclick: () => select(stream.url) },
+      el("div", { class: "name" }, streamName(stream)),
+      el("div", { class: "addr" }, stream.url),
+      el("div", { class: "rate" }, m ? formatBitrate(m.bitrate.total_bps) : "no data"),
+    );
+  });
+  grid.replaceChildren(...tiles);
+}
+
+function row(...cells) {
+  return el("tr", {}, ...cells.map((cell) => (cell instanceof Node ? cell : el("td", {}, cell))));
+}
+
+function fillTable(id, rows) {
+  document.querySelector("#" + id + " tbody").replaceChildren(...rows);
+}
+
+function renderDetail() {
+  const panel = document.getElementById("detail");
+  const stream = state.streams.find((s) => s.url === state.selected);
+  if (!stream) {
+    panel.hidden = true;
+    return;
+  }
+  panel.hidden = false;
+  document.getElementById("detail-title").textContent = streamName(stream);
+
+  const m = stream.metrics;
+  const service = m ? m.service_info : {};
+  const info = [
+    ["Address", stream.url],
+    ["Description", stream.description],
+    ["Format", stream.format || "—"],
+    ["Channel ID", stream.channel_id || "—"],
+    ["Input", stream.input],
+    ["Status", m ? (m.status ? "online" : "offline") : "no data"],
+    ["Last seen", m ? formatTime(m.last_seen) : "—"],
+    ["Bitrate", m ? formatBitrate(m.bitrate.total_bps) + " (net " + formatBitrate(m.bitrate.net_bps) + ")" : "—"],
+    ["Service", service.service_name || "—"],
+    ["Provider", service.provider || "—"],
+    ["Type", service.service_type || "—"],
+    ["TSID", (m && m.tsid) || "—"],
+    ["Restarts", stream.restarts],
+  ];
+  if (m && m.bitrate.per_pid) info.push(["Null packets", (m.bitrate.null_ratio * 100).toFixed(1) + " %"]);
+  document.getElementById("detail-info").replaceChildren(...info.map(([k, v]) => row(k, v)));
+
+  // the program table is only useful for MPTS
+  const programs = (m && m.programs) || [];
+  document.getElementById("detail-programs-block").hidden = programs.length < 2;
+  fillTable("detail-programs", programs.map((p) => row(
+    p.number, p.service_name || "—", p.provider || "—", p.service_type || "—", p.pmt_pid, p.pids.join(" "),
+    m.bitrate.per_pid ? formatBitrate(p.bitrate_bps) : "",
+  )));
+
+  const cc = (m && m.cc_errors) || {};
+  const pids = m ? (m.pids || []).concat(m.system_pids || []) : [];
+  fillTable("detail-pids", pids.map((p) => {
+    const errors = cc[p.pid] || 0;
+    return row(
+      p.pid + " (" + p.pid_decimal + ")",
+      p.type + (p.is_subtitle ? " (subtitles)" : ""),
+      p.codec + (p.video ? " " + formatVideo(p.video) : "") + (p.audio ? " " + formatAudio(p.audio) : ""),
+      p.language || "",
+      m.bitrate.per_pid ? formatBitrate(p.bitrate_bps) : "",
+      el("td", { class: errors ? "err" : "" }, errors),
+    );
+  }));
+
+  const etr290 = (m && m.etr290_errors) || [];
+  document.getElementById("detail-etr290-block").hidden = etr290.length === 0;
+  fillTable("detail-etr290", etr290.map((e) => row(e.priority, e.indicator, e.pid, e.count)));
+
+  const pcr = (m && m.pcr) || [];
+  document.getElementById("detail-pcr-block").hidden = pcr.length === 0;
+  fillTable("detail-pcr", pcr.map((p) => row(
+    p.pid, formatDuration(p.interval_max_ns), formatDuration(p.jitter_max_ns), formatDuration(p.accuracy_max_ns),
+  )));
+}
+
+function renderEvents() {
+  const list = document.getElementById("event-list");
+  if (state.events.length === 0) {
+    list.replaceChildren(el("li", {}, "No events yet"));
+    return;
+  }
+  list.replaceChildren(...state.events.map((e) => {
+    const stream = state.streams.find((s) => s.url === e.stream) || { url: e.stream };
+    return el("li", { onclick: () => select(e.stream) },
+      el("span", { class: "time" }, formatTime(e.time)),
+      el("span", { class: e.type }, streamName(stream) + ": " + e.message),
+    );
+  }));
+}
+
+function render() {
+  renderSummary();
+  renderGrid();
+  renderDetail();
+  renderEvents();
+}
+
+function select(url) {
+  state.selected = url;
+  render();
+}
+
+function setFooter(text) {
+  document.getElementById("footer").textContent = text;
+}
+
+async function refresh() {
+  try {
+    const response = await fetch("/api/v1/streams", { cache: "no-store" });
+    if (!response.ok) throw new Error("HTTP " + response.status);
+    state.streams = await response.json();
+
+    const history = await fetch("/api/v1/events?limit=" + MAX_EVENTS, { cache: "no-store" });
+    if (!history.ok) throw new Error("HTTP " + history.status);
+    state.events = (await history.json()).reverse();
+    render();
+  } catch (err) {
+    setFooter("Reload failed: " + err.message);
+  }
+  setTimeout(refresh, REFRESH_INTERVAL);
+}
+
+// connect subscribes to the live feed; EventSource reconnects by itself.
+function connect() {
+  const feed = new EventSource("/api/v1/feed");
+
+  feed.onopen = () => setFooter("Live");
+  feed.onerror = () => setFooter("Live updates disconnected, reconnecting…");
+
+  feed.addEventListener("metrics", (msg) => {
+    const event = JSON.parse(msg.data);
+    const stream = state.streams.find((s) => s.url === event.stream);
+    if (!stream) return; // appears on the next full reload
+    stream.metrics = event.metrics;
+    state.dirty = true;
+    setFooter("Live, updated " + new Date().toLocaleTimeString());
+  });
+
+  feed.addEventListener("event", (msg) => {
+    addEvent(JSON.parse(msg.data).event);
+    state.dirty = true;
+  });
+}
+
+setInterval(() => {
+  if (!state.dirty) return;
+  state.dirty = false;
+  render();
+}, RENDER_INTERVAL);
+
+document.getElementById("filter").addEventListener("input", renderGrid);
+document.getElementById("detail-close").addEventListener("click", () => select(null));
+refresh();
+connect();
@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+)
+
+func TestWebDashboard(t *testing.T) {
+	o, _ := newTestOrchestrator(&config.Config{})
+
+	mux := http.NewServeMux()
+	o.registerAPI(mux)
+	o.registerWeb(mux)
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{"/", "text/html", "<title>TSMonitor</title>"},
+		{"/app.js", "javascript", "/api/v1/streams"},
+		{"/style.css", "text/css", ".tile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("body does not contain %q", tt.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package mpegts
+
+// aacSampleRates частота дискретизации по sampling_frequency_index (ISO/IEC 14496-3 1.6.3.4)
+var aacSampleRates = [...]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// aacChannels основные каналы и LFE по channel_configuration; 0 — раскладка в PCE
+var aacChannels = [...][2]int{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {5, 1}, {7, 1}}
+
+// aacObjectTypes названия audio object type
+var aacObjectTypes = map[uint32]string{1: "AAC Main", 2: "AAC LC", 3: "AAC SSR", 4: "AAC LTP", 5: "HE-AAC", 29: "HE-AACv2"}
+
+// aacChannelInfo заполняет каналы и раскладку по channel_configuration
+func aacChannelInfo(info *AudioInfo, config uint32) {
+	if int(config) >= len(aacChannels) || config == 0 {
+		return
+	}
+	channels := aacChannels[config]
+	info.Channels = channels[0] + channels[1]
+	info.Layout = channelLayout(channels[0], channels[1])
+}
+
+// parseADTSHeader разбирает заголовок кадра ADTS (ISO/IEC 13818-7 6.2).
+// Для HE-AAC в ADTS частота — частота ядра AAC, вдвое ниже выходной: SBR в ADTS не сигнализируется
+func parseADTSHeader(data []byte) (audioFrame, bool) {
+	if len(data) < 7 || data[0] != 0xFF || data[1]&0xF6 != 0xF0 {
+		return audioFrame{}, false // sync word и layer = 0
+	}
+	r := &bitReader{data: data[2:7]}
+	profile := r.u(2)
+	rateIndex := r.u(4)
+	r.skip(1) // private_bit
+	config := r.u(3)
+	r.skip(4) // original_copy, home, copyright_id_bit, copyright_id_start
+	size := int(r.u(13))
+	r.skip(11) // adts_buffer_fullness
+	blocks := int(r.u(2)) + 1
+
+	headerSize := 7
+	if data[1]&0x01 == 0 {
+		headerSize = 9 // protection_absent = 0: CRC
+	}
+	if int(rateIndex) >= len(aacSampleRates) || size <= headerSize {
+		return audioFrame{}, false
+	}
+
+	f := audioFrame{size: size, samples: 1024 * blocks, primary: true}
+	f.info.Format = aacObjectTypes[profile+1]
+	f.info.SampleRate = aacSampleRates[rateIndex]
+	aacChannelInfo(&f.info, config)
+	return f, true
+}
+
+// parseLOASHeader разбирает заголовок AudioSyncStream LOAS (ISO/IEC 14496-3 1.7.2).
+// Параметры потока — в StreamMuxConfig внутри кадра, их разбирает parseLATMConfig
+func parseLOASHeader(data []byte) (audioFrame, bool) {
+	if len(data) < 3 || data[0] != 0x56 || data[1]&0xE0 != 0xE0 {
+		return audioFrame{}, false
+	}
+	length := int(data[1]&0x1F)<<8 | int(data[2])
+	if length == 0 {
+		return audioFrame{}, false
+	}
+	return audioFrame{size: 3 + length, primary: true}, true
+}
+
+// parseLATMConfig разбирает StreamMuxConfig в начале AudioMuxElement(1), если она есть
+// (useSameStreamMux = 0), и возвращает параметры первого слоя первой программы
+func parseLATMConfig(element []byte) (AudioInfo, bool) {
+	r := &bitReader{data: element}
+	if r.flag() { // useSameStreamMux
+		return AudioInfo{}, false
+	}
+	version := r.u(1)
+	if version == 1 && r.flag() { // audioMuxVersionA
+		return AudioInfo{}, false
+	}
+	if version == 1 {
+		latmValue(r) // taraBufferFullness
+	}
+	r.skip(1 + 6 + 4 + 3) // allStreamsSameTimeFraming, numSubFrames, numProgram, numLayer
+	if version == 1 {
+		latmValue(r) // ascLen
+	}
+
+	info, ok := parseAudioSpecificConfig(r)
+	if !ok || r.exhausted() {
+		return AudioInfo{}, false
+	}
+	return info, true
+}
+
+// latmValue читает LatmGetValue
+func latmValue(r *bitReader) uint32 {
+	bytes := int(r.u(2))
+	var value uint32
+	for i := 0; i <= bytes; i++ {
+		value = value<<8 | r.u(8)
+	}
+	return value
+}
+
+// parseAudioSpecificConfig разбирает начало AudioSpecificConfig (1.6.2.1).
+// Для HE-AAC с явным SBR частота — выходная, после SBR
+func parseAudioSpecificConfig(r *bitReader) (AudioInfo, bool) {
+	var info AudioInfo
+	objectType := audioObjectType(r)
+	sampleRate := aacSampleRate(r)
+	config := r.u(4)
+	if objectType == 5 || objectType == 29 {
+		sampleRate = aacSampleRate(r) // extensionSamplingFrequency
+	}
+	if sampleRate == 0 {
+		return info, false
+	}
+
+	info.Format = aacObjectTypes[objectType]
+	if info.Format == "" {
+		info.Format = "AAC"
+	}
+	info.SampleRate = sampleRate
+	aacChannelInfo(&info, config)
+	return info, true
+}
+
+// audioObjectType читает GetAudioObjectType()
+func audioObjectType(r *bitReader) uint32 {
+	objectType := r.u(5)
+	if objectType == 31 {
+		objectType = 32 + r.u(6)
+	}
+	return objectType
+}
+
+// aacSampleRate читает samplingFrequencyIndex и при необходимости явную частоту
+func aacSampleRate(r *bitReader) int {
+	index := r.u(4)
+	if index == 0x0F {
+		return int(r.u(24))
+	}
+	if int(index) < len(aacSampleRates) {
+		return aacSampleRates[index]
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package mpegts
+
+// ac3Bitrates битрейт AC-3 в кбит/с по frmsizecod/2 (ATSC A/52 таблица 5.18)
+var ac3Bitrates = [...]int{32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 448, 512, 576, 640}
+
+// ac3SampleRates частота дискретизации по fscod; для E-AC-3 с fscod = 3 — по fscod2
+var (
+	ac3SampleRates     = [3]int{48000, 44100, 32000}
+	ac3HalfSampleRates = [3]int{24000, 22050, 16000}
+)
+
+// ac3Channels основные каналы по acmod: 1+1, 1/0, 2/0, 3/0, 2/1, 3/1, 2/2, 3/2
+var ac3Channels = [8]int{2, 1, 2, 3, 3, 4, 4, 5}
+
+// eac3Blocks аудиоблоков по 256 отсчётов в кадре по numblkscod
+var eac3Blocks = [4]int{1, 2, 3, 6}
+
+// parseAC3Header разбирает заголовок синхрокадра AC-3 (A/52 5.3) или E-AC-3 (A/52 E.1.2);
+// формат определяется по bsid
+func parseAC3Header(data []byte) (audioFrame, bool) {
+	if len(data) < 8 || data[0] != 0x0B || data[1] != 0x77 {
+		return audioFrame{}, false
+	}
+	bsid := data[5] >> 3
+	switch {
+	case bsid <= 10:
+		return parseAC3(data)
+	case bsid <= 16:
+		return parseEAC3(data)
+	}
+	return audioFrame{}, false
+}
+
+// parseAC3 разбирает syncinfo и начало bsi AC-3
+func parseAC3(data []byte) (audioFrame, bool) {
+	r := &bitReader{data: data[4:]}
+	fscod := r.u(2)
+	frmsizecod := int(r.u(6))
+	if fscod == 3 || frmsizecod >= 2*len(ac3Bitrates) {
+		return audioFrame{}, false
+	}
+	r.skip(5 + 3) // bsid, bsmod
+	acmod := r.u(3)
+	if acmod&0x01 != 0 && acmod != 1 {
+		r.skip(2) // cmixlev
+	}
+	if acmod&0x04 != 0 {
+		r.skip(2) // surmixlev
+	}
+	if acmod == 2 {
+		r.skip(2) // dsurmod
+	}
+	lfe := int(r.u(1))
+	dialnorm := int(r.u(5))
+
+	sampleRate := ac3SampleRates[fscod]
+	kbps := ac3Bitrates[frmsizecod/2]
+	// Размер кадра в 16-битных словах (таблица 5.18): при 44.1 кГц не делится нацело,
+	// нечётный frmsizecod добавляет слово
+	words := kbps * 1536 * 1000 / sampleRate / 16
+	if fscod == 1 {
+		words += frmsizecod & 1
+	}
+
+	f := audioFrame{size: words * 2, samples: 1536, primary: true}
+	f.info.Format = "AC-3"
+	f.info.SampleRate = sampleRate
+	f.info.Bitrate = int64(kbps) * 1000
+	ac3ChannelInfo(&f.info, acmod, lfe, dialnorm)
+	return f, true
+}
+
+// parseEAC3 разбирает syncinfo и начало bsi E-AC-3
+func parseEAC3(data []byte) (audioFrame, bool) {
+	r := &bitReader{data: data[2:]}
+	strmtyp := r.u(2)
+	substreamID := r.u(3)
+	frmsiz := int(r.u(11))
+	fscod := r.u(2)
+	var sampleRate, blocks int
+	if fscod == 3 {
+		fscod2 := r.u(2)
+		if fscod2 == 3 {
+			return audioFrame{}, false
+		}
+		sampleRate = ac3HalfSampleRates[fscod2]
+		blocks = 6
+	} else {
+		sampleRate = ac3SampleRates[fscod]
+		blocks = eac3Blocks[r.u(2)]
+	}
+	acmod := r.u(3)
+	lfe := int(r.u(1))
+	r.skip(5) // bsid
+	dialnorm := int(r.u(5))
+	if strmtyp == 3 {
+		return audioFrame{}, false
+	}
+
+	f := audioFrame{size: (frmsiz + 1) * 2, samples: 256 * blocks}
+	// Параметры программы — в независимом подпотоке 0; зависимые добавляют каналы к нему
+	f.primary = strmtyp != 1 && substreamID == 0
+	f.info.Format = "E-AC-3"
+	f.info.SampleRate = sampleRate
+	ac3ChannelInfo(&f.info, acmod, lfe, dialnorm)
+	return f, true
+}
+
+// ac3ChannelInfo заполняет каналы, раскладку и dialnorm
+func ac3ChannelInfo(info *AudioInfo, acmod uint32, lfe, dialnorm int) {
+	info.Channels = ac3Channels[acmod] + lfe
+	info.Layout = channelLayout(ac3Channels[acmod], lfe)
+	if acmod == 0 {
+		info.Layout = "dual mono"
+	}
+	// dialnorm 0 зарезервирован и трактуется как -31 dB
+	if dialnorm == 0 {
+		dialnorm = 31
+	}
+	info.Dialnorm = -dialnorm
+}
@@ -0,0 +1,211 @@
+package mpegts
+
+import (
+	"fmt"
+	"time"
+)
+
+// AudioInfo параметры аудио элементарного потока из заголовков кадров
+type AudioInfo struct {
+	Format     string // MPEG-1 Layer II, AAC LC, HE-AAC, AC-3, E-AC-3
+	SampleRate int    // Гц
+	Channels   int    // включая LFE
+	Layout     string // mono, stereo, joint stereo, dual mono, 5.1
+	Bitrate    int64  // бит/с: номинальный из заголовка или, если его нет, по размеру кадров
+	Dialnorm   int    // dB (-31..-1), только AC-3 и E-AC-3; 0 — не передаётся
+
+	Level *AudioLevel // только MPEG audio Layer II; nil, если кадров за окно не было
+}
+
+// maxAudioBuffer больше не бывает ни одного кадра: без синхронизации буфер сбрасывается
+const maxAudioBuffer = 16 * 1024
+
+// audioHeaderSize хватает на заголовок кадра любого формата (кроме StreamMuxConfig LATM,
+// которая разбирается из полного кадра)
+const audioHeaderSize = 8
+
+// audioCodec формат аудио, кадры которого разбирает audioTracker
+type audioCodec int
+
+const (
+	codecMPEGAudio audioCodec = iota
+	codecADTS
+	codecLATM
+	codecAC3 // AC-3 и E-AC-3 различаются по bsid
+)
+
+// audioCodecOf возвращает разбираемый формат для stream_type
+func audioCodecOf(streamType uint8) (audioCodec, bool) {
+	switch streamType {
+	case 0x03, 0x04:
+		return codecMPEGAudio, true
+	case 0x0F:
+		return codecADTS, true
+	case 0x11:
+		return codecLATM, true
+	case 0x81, 0x87:
+		return codecAC3, true
+	}
+	return 0, false
+}
+
+// audioFrame заголовок одного кадра
+type audioFrame struct {
+	info    AudioInfo // Bitrate — номинальный, 0 если в заголовке его нет
+	size    int       // байт в кадре вместе с заголовком
+	samples int       // отсчётов на канал
+	primary bool      // параметры потока: зависимые подпотоки E-AC-3 их не меняют
+}
+
+// audioTracker делит payload PES одного аудио PID на кадры по sync word и длине
+// из заголовка. Без синхронизации кадр принимается, только если сразу за ним
+// начинается следующий: sync word может случайно встретиться внутри кадра
+type audioTracker struct {
+	codec audioCodec
+
+	buf    []byte
+	synced bool
+
+	info     *AudioInfo // nil, пока не разобран ни один кадр
+	latm     *AudioInfo // из последней StreamMuxConfig LATM
+	bytes    int64      // байт кадров за окно
+	duration float64    // секунд звука в кадрах за окно
+	measured int64      // битрейт по размеру кадров за последнее окно с кадрами
+
+	level *levelMeter // только MPEG audio
+}
+
+// newAudioTracker создаёт audioTracker для формата codec; для MPEG audio измеряется
+// уровень и тишина с порогом silenceLevel dBFS и длительностью silenceDuration
+func newAudioTracker(codec audioCodec, silenceLevel float64, silenceDuration time.Duration) *audioTracker {
+	a := &audioTracker{codec: codec}
+	if codec == codecMPEGAudio {
+		a.level = newLevelMeter(silenceLevel, silenceDuration)
+	}
+	return a
+}
+
+// pes начинает новый PES пакет
+func (a *audioTracker) pes(p *PES) {
+	a.write(p.Payload)
+}
+
+// discontinuity сбрасывает незаконченный кадр после потери пакетов
+func (a *audioTracker) discontinuity() {
+	a.buf = a.buf[:0]
+	a.synced = false
+}
+
+// write делит очередной кусок элементарного потока на кадры
+func (a *audioTracker) write(data []byte) {
+	a.buf = append(a.buf, data...)
+	pos := 0
+	for len(a.buf)-pos >= audioHeaderSize {
+		rest := a.buf[pos:]
+		frame, ok := a.parse(rest)
+		if !ok {
+			a.synced = false
+			pos++
+			continue
+		}
+		if len(rest) < frame.size {
+			break
+		}
+		if !a.synced {
+			if len(rest) < frame.size+audioHeaderSize {
+				break
+			}
+			if _, ok := a.parse(rest[frame.size:]); !ok {
+				pos++
+				continue
+			}
+			a.synced = true
+		}
+		a.frame(frame, rest[:frame.size])
+		pos += frame.size
+	}
+
+	a.buf = append(a.buf[:0], a.buf[pos:]...)
+	if len(a.buf) > maxAudioBuffer {
+		a.discontinuity()
+	}
+}
+
+// parse разбирает заголовок кадра в начале data
+func (a *audioTracker) parse(data []byte) (audioFrame, bool) {
+	switch a.codec {
+	case codecMPEGAudio:
+		return parseMPEGAudioHeader(data)
+	case codecADTS:
+		return parseADTSHeader(data)
+	case codecLATM:
+		return parseLOASHeader(data)
+	default:
+		return parseAC3Header(data)
+	}
+}
+
+// frame учитывает кадр data с заголовком f
+func (a *audioTracker) frame(f audioFrame, data []byte) {
+	if a.codec == codecLATM {
+		// Параметры LATM передаются не в каждом кадре, а в StreamMuxConfig
+		if config, ok := parseLATMConfig(data[3:]); ok {
+			a.latm = &config
+		}
+		if a.latm == nil {
+			return
+		}
+		f.info = *a.latm
+		f.samples = 1024
+		if f.info.Format == "HE-AAC" || f.info.Format == "HE-AACv2" {
+			f.samples = 2048 // SBR удваивает частоту дискретизации при том же кадре
+		}
+	}
+
+	// Байты зависимых подпотоков входят в битрейт, а время звука идёт только по основному
+	a.bytes += int64(f.size)
+	if !f.primary {
+		return
+	}
+	if f.info.SampleRate > 0 {
+		a.duration += float64(f.samples) / float64(f.info.SampleRate)
+	}
+	info := f.info
+	a.info = &info
+	if a.level != nil {
+		a.level.frame(data)
+	}
+}
+
+// report возвращает параметры потока и начинает новое окно измерения битрейта;
+// nil, пока не разобран ни один кадр
+func (a *audioTracker) report() *AudioInfo {
+	if a.duration > 0 {
+		a.measured = int64(float64(a.bytes*8) / a.duration)
+	}
+	a.bytes = 0
+	a.duration = 0
+
+	if a.info == nil {
+		return nil
+	}
+	info := *a.info
+	if info.Bitrate == 0 {
+		info.Bitrate = a.measured
+	}
+	if a.level != nil {
+		info.Level = a.level.report()
+	}
+	return &info
+}
+
+// channelLayout возвращает название раскладки по числу основных каналов и LFE
+func channelLayout(channels, lfe int) string {
+	switch {
+	case channels == 1 && lfe == 0:
+		return "mono"
+	case channels == 2 && lfe == 0:
+		return "stereo"
+	}
+	return fmt.Sprintf("%d.%d", channels, lfe)
+}
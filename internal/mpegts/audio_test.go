@@ -0,0 +1,329 @@
+package mpegts
+
+import (
+	"math"
+	"math/bits"
+	"testing"
+	"time"
+)
+
+// audioTestFrame дополняет заголовок кадра до size байт
+func audioTestFrame(header []byte, size int) []byte {
+	frame := make([]byte, size)
+	copy(frame, header)
+	for i := len(header); i < size; i++ {
+		frame[i] = 0x55
+	}
+	return frame
+}
+
+// mp2TestFrame собирает кадр MPEG-1 Layer II без CRC
+func mp2TestFrame(bitrateIndex, rateIndex, mode byte, size int) []byte {
+	return audioTestFrame([]byte{0xFF, 0xFD, bitrateIndex<<4 | rateIndex<<2, mode << 6}, size)
+}
+
+// adtsTestFrame собирает кадр ADTS без CRC
+func adtsTestFrame(profile, rateIndex, config uint32, size int) []byte {
+	w := &bitWriter{}
+	w.u(12, 0xFFF)
+	w.u(4, 0x1) // MPEG-4, layer 0, protection_absent
+	w.u(2, profile)
+	w.u(4, rateIndex)
+	w.u(1, 0)
+	w.u(3, config)
+	w.u(4, 0)
+	w.u(13, uint32(size))
+	w.u(11, 0x7FF)
+	w.u(2, 0)
+	return audioTestFrame(w.data, size)
+}
+
+// ac3TestFrame собирает синхрокадр AC-3 с bsi до dialnorm
+func ac3TestFrame(fscod, frmsizecod, acmod, lfe, dialnorm uint32, size int) []byte {
+	w := &bitWriter{}
+	w.u(16, 0x0B77)
+	w.u(16, 0) // crc1
+	w.u(2, fscod)
+	w.u(6, frmsizecod)
+	w.u(5, 8) // bsid
+	w.u(3, 0) // bsmod
+	w.u(3, acmod)
+	if acmod&1 != 0 && acmod != 1 {
+		w.u(2, 0)
+	}
+	if acmod&4 != 0 {
+		w.u(2, 0)
+	}
+	if acmod == 2 {
+		w.u(2, 0)
+	}
+	w.u(1, lfe)
+	w.u(5, dialnorm)
+	return audioTestFrame(w.data, size)
+}
+
+// eac3TestFrame собирает синхрокадр E-AC-3 48 кГц из 6 блоков
+func eac3TestFrame(strmtyp, acmod, dialnorm uint32, size int) []byte {
+	w := &bitWriter{}
+	w.u(16, 0x0B77)
+	w.u(2, strmtyp)
+	w.u(3, 0) // substreamid
+	w.u(11, uint32(size/2-1))
+	w.u(2, 0) // 48 кГц
+	w.u(2, 3) // 6 блоков
+	w.u(3, acmod)
+	w.u(1, 0)  // lfeon
+	w.u(5, 16) // bsid
+	w.u(5, dialnorm)
+	return audioTestFrame(w.data, size)
+}
+
+// loasTestFrame собирает кадр LOAS с StreamMuxConfig версии 0
+func loasTestFrame(objectType, rateIndex, config, extRateIndex uint32, size int) []byte {
+	w := &bitWriter{}
+	w.u(11, 0x2B7)
+	w.u(13, uint32(size-3))
+	w.u(1, 0) // useSameStreamMux
+	w.u(1, 0) // audioMuxVersion
+	w.u(1, 1) // allStreamsSameTimeFraming
+	w.u(6, 0) // numSubFrames
+	w.u(4, 0) // numProgram
+	w.u(3, 0) // numLayer
+	w.u(5, objectType)
+	w.u(4, rateIndex)
+	w.u(4, config)
+	if objectType == 5 {
+		w.u(4, extRateIndex)
+		w.u(5, 2)
+	}
+	return audioTestFrame(w.data, size)
+}
+
+func TestAudioTracker(t *testing.T) {
+	repeat := func(frames ...[]byte) []byte {
+		var data []byte
+		for i := 0; i < 3; i++ {
+			for _, f := range frames {
+				data = append(data, f...)
+			}
+		}
+		return data
+	}
+
+	tests := []struct {
+		name  string
+		codec audioCodec
+		data  []byte
+		want  AudioInfo
+	}{
+		{
+			name:  "MP2 stereo 48 kHz",
+			codec: codecMPEGAudio,
+			data:  repeat(mp2TestFrame(10, 1, 0, 576)),
+			want:  AudioInfo{Format: "MPEG-1 Layer II", SampleRate: 48000, Channels: 2, Layout: "stereo", Bitrate: 192000},
+		},
+		{
+			name:  "MP2 mono 32 kHz",
+			codec: codecMPEGAudio,
+			data:  repeat(mp2TestFrame(4, 2, 3, 288)),
+			want:  AudioInfo{Format: "MPEG-1 Layer II", SampleRate: 32000, Channels: 1, Layout: "mono", Bitrate: 64000},
+		},
+		{
+			name:  "ADTS AAC LC",
+			codec: codecADTS,
+			data:  repeat(adtsTestFrame(1, 3, 2, 384)),
+			want:  AudioInfo{Format: "AAC LC", SampleRate: 48000, Channels: 2, Layout: "stereo", Bitrate: 144000},
+		},
+		{
+			name:  "LATM HE-AAC",
+			codec: codecLATM,
+			data:  repeat(loasTestFrame(5, 6, 2, 3, 256)),
+			want:  AudioInfo{Format: "HE-AAC", SampleRate: 48000, Channels: 2, Layout: "stereo", Bitrate: 48000},
+		},
+		{
+			name:  "AC-3 5.1",
+			codec: codecAC3,
+			data:  repeat(ac3TestFrame(0, 28, 7, 1, 27, 1536)),
+			want:  AudioInfo{Format: "AC-3", SampleRate: 48000, Channels: 6, Layout: "5.1", Bitrate: 384000, Dialnorm: -27},
+		},
+		{
+			name:  "AC-3 44.1 kHz odd frmsizecod",
+			codec: codecAC3,
+			data:  repeat(ac3TestFrame(1, 21, 2, 0, 31, 836)),
+			want:  AudioInfo{Format: "AC-3", SampleRate: 44100, Channels: 2, Layout: "stereo", Bitrate: 192000, Dialnorm: -31},
+		},
+		{
+			name:  "E-AC-3 with dependent substream",
+			codec: codecAC3,
+			data:  repeat(eac3TestFrame(0, 2, 24, 1024), eac3TestFrame(1, 3, 24, 256)),
+			want:  AudioInfo{Format: "E-AC-3", SampleRate: 48000, Channels: 2, Layout: "stereo", Bitrate: 320000, Dialnorm: -24},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAudioTracker(tt.codec, DefaultSilenceLevel, DefaultSilenceDuration)
+			// Мусор перед первым кадром и запись кусками по TS пакету
+			data := append([]byte{0xFF, 0xFF, 0x0B, 0x56, 0x00}, tt.data...)
+			for len(data) > 0 {
+				n := min(len(data), 184)
+				a.write(data[:n])
+				data = data[n:]
+			}
+
+			info := a.report()
+			if info == nil {
+				t.Fatal("no frames parsed")
+			}
+			info.Level = nil // уровень проверяет TestLevelMeter
+			if *info != tt.want {
+				t.Errorf("info = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
+
+func TestDemuxerAudio(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+	for _, pkt := range testPSI() {
+		d.FeedPacket(pkt, start)
+	}
+
+	var cc uint8
+	pts := uint64(90000)
+	for i := 0; i < 10; i++ {
+		for _, pkt := range pesPackets(0x00CA, pts, mp2TestFrame(10, 1, 1, 576), &cc) {
+			d.FeedPacket(pkt, start)
+		}
+		pts += 2160
+	}
+
+	r := d.Flush(start.Add(time.Second))
+	want := AudioInfo{Format: "MPEG-1 Layer II", SampleRate: 48000, Channels: 2, Layout: "joint stereo", Bitrate: 192000}
+	info := r.Audio[0x00CA]
+	if info == nil || info.Level == nil {
+		t.Fatalf("Audio[0x00CA] = %+v, want level", info)
+	}
+	info.Level = nil
+	if *info != want {
+		t.Errorf("Audio[0x00CA] = %+v, want %+v", info, want)
+	}
+	if r.Audio[0x0066] != nil {
+		t.Errorf("video PID has audio info: %+v", r.Audio[0x0066])
+	}
+}
+
+// mp2LevelFrame собирает кадр MPEG-1 Layer II 48 кГц моно 64 кбит/с (таблица B.2a),
+// в котором передаётся только подполоса sb с индексом распределения index,
+// одним scalefactor scf и всеми отсчётами с кодом code; index 0 — цифровая тишина
+func mp2LevelFrame(sb int, index, scf, code uint32) []byte {
+	w := &bitWriter{}
+	w.u(32, 0xFFFD44C0)
+	levels := 0
+	band := 0
+	for _, class := range mp2AllocTables[0] {
+		for i := 0; i < class.count; i, band = i+1, band+1 {
+			if band == sb {
+				w.u(class.nbal, index)
+				if index > 0 {
+					levels = class.levels[index-1]
+				}
+			} else {
+				w.u(class.nbal, 0)
+			}
+		}
+	}
+	if levels > 0 {
+		w.u(2, 2) // scfsi: один scalefactor на кадр
+		w.u(6, scf)
+		for gr := 0; gr < 12; gr++ {
+			if n, grouped := mp2GroupBits[levels]; grouped {
+				w.u(n, code+code*uint32(levels)+code*uint32(levels*levels))
+			} else {
+				for i := 0; i < 3; i++ {
+					w.u(bits.Len(uint(levels)), code)
+				}
+			}
+		}
+	}
+	frame := make([]byte, 192)
+	copy(frame, w.data)
+	return frame
+}
+
+func TestLevelMeter(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   []byte
+		rms     float64
+		peak    float64
+		silence bool
+	}{
+		{
+			name:  "16 bit samples at half scale",
+			frame: mp2LevelFrame(0, 15, 3, 49151), // (2*49151 - 65534) / 65535 ≈ 0.5
+			rms:   -6.02,
+			peak:  -6.02,
+		},
+		{
+			name:  "grouped samples with scalefactor 0.5",
+			frame: mp2LevelFrame(3, 1, 6, 2), // 3 уровня: 2/3 * 0.5
+			rms:   -9.54,
+			peak:  -9.54,
+		},
+		{
+			name:    "digital silence",
+			frame:   mp2LevelFrame(0, 0, 0, 0),
+			rms:     minAudioLevel,
+			peak:    minAudioLevel,
+			silence: true,
+		},
+		{
+			name:    "below threshold",
+			frame:   mp2LevelFrame(0, 15, 60, 49151), // 0.5 * 2^-19
+			rms:     minAudioLevel,
+			peak:    minAudioLevel,
+			silence: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 10 кадров по 24 мс: 240 мс тишины при пороге 200 мс
+			m := newLevelMeter(DefaultSilenceLevel, 200*time.Millisecond)
+			for i := 0; i < 10; i++ {
+				m.frame(tt.frame)
+			}
+
+			level := m.report()
+			if level == nil {
+				t.Fatal("no level")
+			}
+			if math.Abs(level.RMS-tt.rms) > 0.01 || math.Abs(level.Peak-tt.peak) > 0.01 {
+				t.Errorf("RMS = %.2f, peak = %.2f, want %.2f, %.2f", level.RMS, level.Peak, tt.rms, tt.peak)
+			}
+			if level.Silence != tt.silence {
+				t.Errorf("Silence = %v (silent %v), want %v", level.Silence, level.Silent, tt.silence)
+			}
+			if m.report() != nil {
+				t.Error("level reported for an empty window")
+			}
+		})
+	}
+}
+
+func TestLevelMeterSilenceReset(t *testing.T) {
+	m := newLevelMeter(DefaultSilenceLevel, 100*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		m.frame(mp2LevelFrame(0, 0, 0, 0))
+	}
+	if level := m.report(); !level.Silence || level.Silent != 120*time.Millisecond {
+		t.Fatalf("level = %+v, want 120ms of silence", level)
+	}
+
+	m.frame(mp2LevelFrame(0, 15, 3, 49151))
+	if level := m.report(); level.Silence || level.Silent != 0 {
+		t.Errorf("level = %+v, want silence cleared", level)
+	}
+}
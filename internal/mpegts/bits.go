@@ -0,0 +1,78 @@
+package mpegts
+
+// bitReader читает поля заголовков элементарных потоков старшими битами вперёд.
+// Выход за конец данных не паникует: поля читаются нулями, а exhausted
+// сообщает, что разбор дошёл до конца данных
+type bitReader struct {
+	data []byte
+	pos  int // номер следующего бита
+}
+
+// exhausted проверяет, что прочитано больше бит, чем было в данных
+func (r *bitReader) exhausted() bool {
+	return r.pos > len(r.data)*8
+}
+
+// u читает n бит (n <= 32) как беззнаковое число
+func (r *bitReader) u(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if byteIdx := r.pos >> 3; byteIdx < len(r.data) {
+			v |= uint32(r.data[byteIdx]>>(7-r.pos&7)) & 1
+		}
+		r.pos++
+	}
+	return v
+}
+
+// flag читает один бит
+func (r *bitReader) flag() bool {
+	return r.u(1) == 1
+}
+
+// skip пропускает n бит
+func (r *bitReader) skip(n int) {
+	r.pos += n
+}
+
+// ue читает exp-Golomb ue(v)
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for !r.flag() {
+		zeros++
+		if zeros > 31 || r.exhausted() {
+			r.pos = len(r.data)*8 + 1
+			return 0
+		}
+	}
+	return 1<<zeros - 1 + r.u(zeros)
+}
+
+// se читает exp-Golomb se(v)
+func (r *bitReader) se() int32 {
+	v := r.ue()
+	if v&1 == 1 {
+		return int32((v + 1) / 2)
+	}
+	return -int32(v / 2)
+}
+
+// unescapeRBSP убирает emulation prevention байты 0x03 из NAL unit H.264/HEVC
+func unescapeRBSP(nal []byte) []byte {
+	rbsp := make([]byte, 0, len(nal))
+	zeros := 0
+	for _, b := range nal {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
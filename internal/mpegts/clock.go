@@ -0,0 +1,67 @@
+package mpegts
+
+import "time"
+
+// maxPCRStep больший скачок PCR считается разрывом записи, а не ходом времени
+const maxPCRStep = time.Second
+
+// PCRClock восстанавливает время записанного потока по PCR первого PID, на котором
+// он встретился. Пакеты между PCR получают время, интерполированное по скорости
+// потока между двумя последними PCR. Не потокобезопасен
+type PCRClock struct {
+	pid     uint16
+	started bool
+	lastPCR uint64
+	index   uint64 // номер текущего пакета
+	lastIdx uint64 // номер пакета с последним PCR
+	offset  time.Duration
+	rate    float64 // пакетов в секунду; 0, пока не было двух PCR
+}
+
+// Packet учитывает очередной пакет и возвращает его время от начала записи.
+// До первого PCR время равно нулю
+func (c *PCRClock) Packet(pkt []byte) time.Duration {
+	c.index++
+
+	h, err := ParseHeader(pkt)
+	if err != nil || (c.started && h.PID != c.pid) {
+		return c.interpolate()
+	}
+	pcr, ok := PCR(pkt, h)
+	if !ok {
+		return c.interpolate()
+	}
+	if !c.started {
+		c.started = true
+		c.pid = h.PID
+		c.lastPCR = pcr
+		c.lastIdx = c.index
+		return c.offset
+	}
+
+	packets := float64(c.index - c.lastIdx)
+	step := pcrDuration(float64(pcrDelta(c.lastPCR, pcr)))
+	if step > 0 && step <= maxPCRStep && !Discontinuity(pkt, h) {
+		c.offset += step
+		c.rate = packets / step.Seconds()
+	} else {
+		// Разрыв или склейка записи: время продолжается по последней скорости
+		c.offset = c.interpolate()
+	}
+	c.lastPCR = pcr
+	c.lastIdx = c.index
+	return c.offset
+}
+
+// Started сообщает, встретился ли PCR: без него время записи неизвестно
+func (c *PCRClock) Started() bool {
+	return c.started
+}
+
+// interpolate возвращает время текущего пакета по скорости потока
+func (c *PCRClock) interpolate() time.Duration {
+	if c.rate <= 0 {
+		return c.offset
+	}
+	return c.offset + time.Duration(float64(c.index-c.lastIdx)/c.rate*float64(time.Second))
+}
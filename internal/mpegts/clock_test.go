@@ -0,0 +1,44 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPCRClock(t *testing.T) {
+	var clock PCRClock
+	const step = 40 * time.Millisecond // PCR каждые 10 пакетов
+
+	// До первого PCR время нулевое
+	if got := clock.Packet(payloadPacket(0x0100, 0)); got != 0 {
+		t.Errorf("time before PCR = %v, want 0", got)
+	}
+
+	pcr := pcrWrap - pcrTicks(step) // переход через переполнение PCR
+	var times []time.Duration
+	for i := 0; i < 30; i++ {
+		if i%10 == 0 {
+			times = append(times, clock.Packet(pcrPacket(0x0100, 0, pcr, false)))
+			pcr = (pcr + pcrTicks(step)) % pcrWrap
+		} else {
+			times = append(times, clock.Packet(payloadPacket(0x0200, 0)))
+		}
+	}
+
+	// PCR задают время, пакеты между ними интерполируются после второго PCR
+	for i, want := range map[int]time.Duration{0: 0, 5: 0, 10: step, 15: step + step/2, 20: 2 * step, 29: 2*step + step*9/10} {
+		if diff := times[i] - want; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("packet %d time = %v, want %v", i, times[i], want)
+		}
+	}
+
+	// Разрыв PCR не ломает ход времени: продолжаем по скорости потока
+	got := clock.Packet(pcrPacket(0x0100, 0, 12345, true))
+	if diff := got - 3*step; diff < -time.Microsecond || diff > time.Microsecond {
+		t.Errorf("time at discontinuity = %v, want %v", got, 3*step)
+	}
+	// PCR другого PID не учитывается
+	if got := clock.Packet(pcrPacket(0x0300, 0, 0, false)); got <= 3*step {
+		t.Errorf("time after foreign PCR = %v, want > %v", got, 3*step)
+	}
+}
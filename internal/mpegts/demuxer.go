@@ -0,0 +1,587 @@
+package mpegts
+
+import (
+	"sort"
+	"time"
+)
+
+// Program описывает программу из PAT вместе с её PMT
+type Program struct {
+	Number  uint16
+	PMTPID  uint16
+	PCRPID  uint16
+	Streams []ElementaryStream
+	PMT     bool // PMT получена; до этого PCRPID и Streams неизвестны
+}
+
+// Report содержит состояние потока и статистику за окно между вызовами Flush
+type Report struct {
+	Start      time.Time // начало окна
+	End        time.Time // конец окна
+	LastPacket time.Time // время последнего принятого пакета
+
+	Packets     int64            // пакетов за окно
+	NullPackets int64            // null пакетов (PID 0x1FFF) за окно
+	PIDPackets  map[uint16]int64 // пакетов за окно по PID
+	CCErrors    map[uint16]int64 // ошибок continuity counter за окно по PID
+
+	TSID     uint16
+	HasTSID  bool
+	Programs []Program // отсортированы по номеру программы
+	Services []Service // из SDT actual
+
+	ETR290 map[ETR290Error]int64 // ошибки TR 101 290 за окно
+	PCR    map[uint16]*PCRStats  // статистика PCR за окно по PID
+	MDI    *MDIStats             // MDI за последний завершённый интервал (если включён)
+	Video  map[uint16]*VideoInfo // параметры видео по PID, для которых разобран заголовок
+	Audio  map[uint16]*AudioInfo // параметры аудио по PID, для которых разобран кадр
+}
+
+// Duration возвращает длительность окна
+func (r *Report) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Bitrate возвращает битрейт TS за окно (бит/с)
+func (r *Report) Bitrate() int64 {
+	return r.bitrate(r.Packets)
+}
+
+// NetBitrate возвращает битрейт без null пакетов (бит/с)
+func (r *Report) NetBitrate() int64 {
+	return r.bitrate(r.Packets - r.NullPackets)
+}
+
+// PIDBitrate возвращает битрейт одного PID за окно (бит/с)
+func (r *Report) PIDBitrate(pid uint16) int64 {
+	return r.bitrate(r.PIDPackets[pid])
+}
+
+// NullRatio возвращает долю null пакетов за окно (0..1)
+func (r *Report) NullRatio() float64 {
+	if r.Packets == 0 {
+		return 0
+	}
+	return float64(r.NullPackets) / float64(r.Packets)
+}
+
+func (r *Report) bitrate(packets int64) int64 {
+	seconds := r.Duration().Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return int64(float64(packets*PacketSize*8) / seconds)
+}
+
+// pidState состояние одного PID
+type pidState struct {
+	hasCC  bool
+	lastCC uint8
+	dupCC  bool // предыдущий пакет уже был дубликатом
+}
+
+// Demuxer разбирает TS пакеты одного потока и накапливает статистику.
+// Не потокобезопасен: все вызовы должны идти из одной горутины.
+type Demuxer struct {
+	carry    []byte
+	pids     map[uint16]*pidState
+	sections map[uint16]*sectionAssembler
+
+	pat         *PAT
+	pmts        map[uint16]*PMT // program_number -> PMT
+	sdtTSID     uint16
+	sdtVersion  uint8
+	sdtSections map[uint8][]Service
+	catPIDs     []uint16 // EMM PID из CAT
+
+	// Индексы, пересчитываемые при изменении PAT/PMT/CAT
+	pmtPIDs    map[uint16]uint16        // PMT PID -> program_number
+	esTypes    map[uint16]uint8         // PID элементарного потока -> stream_type
+	referenced map[uint16]bool          // PID, на которые есть ссылки в PSI
+	videos     map[uint16]*videoTracker // видео PID с разбираемыми заголовками
+	audios     map[uint16]*audioTracker // аудио PID с разбираемыми кадрами
+
+	etr         *etr290
+	pcr         map[uint16]*pcrTracker
+	packetIndex uint64      // номер пакета в потоке, для расчётов по PCR
+	mdi         *mdiTracker // nil, пока не вызван SetMDIInterval
+
+	silenceLevel    float64 // порог тишины аудио, dBFS
+	silenceDuration time.Duration
+
+	windowStart time.Time
+	lastPacket  time.Time
+	packets     int64
+	nullPackets int64
+	pidPackets  map[uint16]int64
+	ccErrors    map[uint16]int64
+}
+
+// NewDemuxer создаёт новый Demuxer
+func NewDemuxer() *Demuxer {
+	d := &Demuxer{
+		pids:        make(map[uint16]*pidState),
+		sections:    make(map[uint16]*sectionAssembler),
+		pmts:        make(map[uint16]*PMT),
+		sdtSections: make(map[uint8][]Service),
+		pmtPIDs:     make(map[uint16]uint16),
+		esTypes:     make(map[uint16]uint8),
+		referenced:  make(map[uint16]bool),
+		videos:      make(map[uint16]*videoTracker),
+		audios:      make(map[uint16]*audioTracker),
+		pcr:         make(map[uint16]*pcrTracker),
+		pidPackets:  make(map[uint16]int64),
+		ccErrors:    make(map[uint16]int64),
+
+		silenceLevel:    DefaultSilenceLevel,
+		silenceDuration: DefaultSilenceDuration,
+	}
+	d.etr = newETR290(d)
+	return d
+}
+
+// Write разбирает произвольный кусок TS (UDP датаграмму или блок файла).
+// Неполный пакет в конце сохраняется до следующего вызова.
+func (d *Demuxer) Write(data []byte, at time.Time) {
+	if d.mdi != nil {
+		d.mdi.arrival(len(data), at)
+	}
+
+	if len(d.carry) > 0 {
+		need := PacketSize - len(d.carry)
+		if len(data) < need {
+			d.carry = append(d.carry, data...)
+			return
+		}
+		d.carry = append(d.carry, data[:need]...)
+		if d.carry[0] == SyncByte {
+			d.FeedPacket(d.carry, at)
+		}
+		data = data[need:]
+		d.carry = d.carry[:0]
+	}
+
+	for len(data) > 0 {
+		if data[0] != SyncByte {
+			next := resync(data)
+			d.etr.syncLoss(next)
+			data = data[next:]
+			continue
+		}
+		if len(data) < PacketSize {
+			d.carry = append(d.carry[:0], data...)
+			return
+		}
+		d.FeedPacket(data[:PacketSize], at)
+		data = data[PacketSize:]
+	}
+}
+
+// resync возвращает смещение следующего sync byte, за которым через 188 байт
+// тоже идёт sync byte (если данных хватает)
+func resync(data []byte) int {
+	for i := 1; i < len(data); i++ {
+		if data[i] != SyncByte {
+			continue
+		}
+		if i+PacketSize >= len(data) || data[i+PacketSize] == SyncByte {
+			return i
+		}
+	}
+	return len(data)
+}
+
+// FeedPacket обрабатывает один 188-байтовый TS пакет
+func (d *Demuxer) FeedPacket(pkt []byte, at time.Time) {
+	h, err := ParseHeader(pkt)
+	if err != nil {
+		return
+	}
+
+	if d.windowStart.IsZero() {
+		d.windowStart = at
+	}
+	d.lastPacket = at
+	d.packets++
+	d.packetIndex++
+	d.pidPackets[h.PID]++
+
+	d.etr.packet(pkt, h, at)
+
+	if h.PID == NullPID {
+		d.nullPackets++
+		return
+	}
+	if h.TEI {
+		return
+	}
+
+	d.checkContinuity(pkt, h)
+
+	if pcr, ok := PCR(pkt, h); ok {
+		d.handlePCR(h.PID, pcr, Discontinuity(pkt, h), at)
+	}
+
+	if d.isPSI(h.PID) {
+		asm := d.sections[h.PID]
+		if asm == nil {
+			asm = &sectionAssembler{}
+			d.sections[h.PID] = asm
+		}
+		asm.push(Payload(pkt, h), h, func(s *Section) {
+			d.handleSection(h.PID, s, at)
+		})
+	}
+
+	// Повтор пакета при дубликате CC уже разобран
+	if es := d.esParser(h.PID); es != nil && h.HasPayload && h.Scrambling == 0 && !d.pids[h.PID].dupCC {
+		payload := Payload(pkt, h)
+		if !h.PUSI {
+			es.write(payload)
+		} else if pes, ok := ParsePES(payload); ok {
+			es.pes(pes)
+		} else {
+			es.discontinuity()
+		}
+	}
+}
+
+// esParser разбирает payload PES одного элементарного потока
+type esParser interface {
+	pes(p *PES)
+	write(data []byte)
+	discontinuity()
+}
+
+// esParser возвращает разборщик элементарного потока PID или nil
+func (d *Demuxer) esParser(pid uint16) esParser {
+	if v := d.videos[pid]; v != nil {
+		return v
+	}
+	if a := d.audios[pid]; a != nil {
+		return a
+	}
+	return nil
+}
+
+// checkContinuity проверяет continuity counter по ISO/IEC 13818-1 2.4.3.3
+func (d *Demuxer) checkContinuity(pkt []byte, h Header) {
+	st := d.pids[h.PID]
+	if st == nil {
+		st = &pidState{}
+		d.pids[h.PID] = st
+	}
+
+	if !st.hasCC || Discontinuity(pkt, h) {
+		st.hasCC = true
+		st.lastCC = h.CC
+		st.dupCC = false
+		return
+	}
+
+	if !h.HasPayload {
+		// Без payload счётчик не должен увеличиваться
+		if h.CC != st.lastCC {
+			d.ccError(h.PID, 1)
+		}
+		st.lastCC = h.CC
+		return
+	}
+
+	switch {
+	case h.CC == (st.lastCC+1)&0x0F:
+		st.dupCC = false
+	case h.CC == st.lastCC && !st.dupCC:
+		// Один дубликат допустим
+		st.dupCC = true
+	default:
+		// Пропущено пакетов по разнице счётчиков; повторный дубликат — один
+		lost := int64((h.CC - st.lastCC - 1) & 0x0F)
+		if lost == 0x0F {
+			lost = 1
+		}
+		d.ccError(h.PID, lost)
+		st.dupCC = false
+	}
+	st.lastCC = h.CC
+}
+
+// ccError учитывает ошибку continuity counter и lost пропущенных пакетов для MDI
+func (d *Demuxer) ccError(pid uint16, lost int64) {
+	d.ccErrors[pid]++
+	d.etr.ccError(pid)
+	if es := d.esParser(pid); es != nil {
+		es.discontinuity()
+	}
+	if d.mdi != nil {
+		d.mdi.ccLost += lost
+	}
+}
+
+// handlePCR обновляет статистику PCR и передаёт результат анализатору TR 101 290
+func (d *Demuxer) handlePCR(pid uint16, pcr uint64, discontinuity bool, at time.Time) {
+	t := d.pcr[pid]
+	if t == nil {
+		t = &pcrTracker{}
+		d.pcr[pid] = t
+	}
+
+	if s, ok := t.update(pcr, d.packetIndex, discontinuity, at); ok {
+		d.etr.pcrSample(pid, s)
+	}
+}
+
+// isPSI проверяет, несёт ли PID разбираемые таблицы PSI/SI
+func (d *Demuxer) isPSI(pid uint16) bool {
+	switch pid {
+	case PATPID, CATPID, NITPID, SDTPID, EITPID, TDTPID:
+		return true
+	}
+	return d.isPMTPID(pid)
+}
+
+// isPMTPID проверяет, указан ли PID в PAT как PMT PID
+func (d *Demuxer) isPMTPID(pid uint16) bool {
+	_, ok := d.pmtPIDs[pid]
+	return ok
+}
+
+// hasTimestamps проверяет, что PID элементарного потока обязан нести PTS
+func (d *Demuxer) hasTimestamps(pid uint16) bool {
+	streamType, ok := d.esTypes[pid]
+	return ok && hasTimestamps(streamType)
+}
+
+// isReferenced проверяет, есть ли ссылка на PID в PSI
+func (d *Demuxer) isReferenced(pid uint16) bool {
+	return pid < 0x0020 || pid == NullPID || d.referenced[pid]
+}
+
+// complete проверяет, что получены PAT и PMT всех программ
+func (d *Demuxer) complete() bool {
+	if d.pat == nil {
+		return false
+	}
+	for _, program := range d.pmtPIDs {
+		if d.pmts[program] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSilence задаёт детектор тишины MPEG audio Layer II: тишина — звук тише level dBFS
+// во всех каналах дольше duration. По умолчанию DefaultSilenceLevel и DefaultSilenceDuration
+func (d *Demuxer) SetSilence(level float64, duration time.Duration) {
+	d.silenceLevel = level
+	d.silenceDuration = duration
+	for _, a := range d.audios {
+		if a.level != nil {
+			a.level.threshold = level
+			a.level.duration = duration
+		}
+	}
+}
+
+// rebuildIndex пересчитывает индексы PID после изменения PAT/PMT/CAT
+func (d *Demuxer) rebuildIndex() {
+	d.pmtPIDs = make(map[uint16]uint16)
+	d.esTypes = make(map[uint16]uint8)
+	d.referenced = make(map[uint16]bool)
+	videos, audios := d.videos, d.audios
+	d.videos = make(map[uint16]*videoTracker)
+	d.audios = make(map[uint16]*audioTracker)
+
+	for _, pid := range d.catPIDs {
+		d.referenced[pid] = true
+	}
+	if d.pat == nil {
+		return
+	}
+
+	for program, pmtPID := range d.pat.Programs {
+		d.referenced[pmtPID] = true
+		if program != 0 {
+			d.pmtPIDs[pmtPID] = program
+		}
+	}
+
+	for _, pmt := range d.pmts {
+		d.referenced[pmt.PCRPID] = true
+		for _, pid := range caPIDs(pmt.Descriptors) {
+			d.referenced[pid] = true
+		}
+		for _, es := range pmt.Streams {
+			d.esTypes[es.PID] = es.Type
+			d.referenced[es.PID] = true
+			// Разобранные заголовки сохраняются, пока не сменился кодек PID
+			if codec, ok := videoCodecOf(es.Type); ok {
+				if v := videos[es.PID]; v != nil && v.codec == codec {
+					d.videos[es.PID] = v
+				} else {
+					d.videos[es.PID] = newVideoTracker(codec)
+				}
+			}
+			if codec, ok := audioCodecOf(es.Type); ok {
+				if a := audios[es.PID]; a != nil && a.codec == codec {
+					d.audios[es.PID] = a
+				} else {
+					d.audios[es.PID] = newAudioTracker(codec, d.silenceLevel, d.silenceDuration)
+				}
+			}
+			for _, pid := range caPIDs(es.Descriptors) {
+				d.referenced[pid] = true
+			}
+		}
+	}
+}
+
+// handleSection обрабатывает собранную секцию
+func (d *Demuxer) handleSection(pid uint16, s *Section, at time.Time) {
+	d.etr.section(pid, s, at)
+
+	if !s.CRCValid || !s.CurrentNext {
+		return
+	}
+
+	switch {
+	case pid == PATPID && s.TableID == TableIDPAT:
+		if d.pat != nil && d.pat.TSID == s.TableIDExt && d.pat.Version == s.Version {
+			return
+		}
+		d.pat = ParsePAT(s)
+		// Удаляем PMT программ, которых больше нет в PAT
+		for program := range d.pmts {
+			if _, ok := d.pat.Programs[program]; !ok {
+				delete(d.pmts, program)
+			}
+		}
+		d.rebuildIndex()
+
+	case pid == CATPID && s.TableID == TableIDCAT:
+		body := s.Body()
+		d.catPIDs = caPIDs(parseDescriptors(body))
+		d.rebuildIndex()
+
+	case pid == SDTPID && s.TableID == TableIDSDTActual:
+		sdt := ParseSDT(s)
+		if sdt.TSID != d.sdtTSID || s.Version != d.sdtVersion {
+			d.sdtSections = make(map[uint8][]Service)
+		}
+		d.sdtTSID = sdt.TSID
+		d.sdtVersion = s.Version
+		d.sdtSections[s.Number] = sdt.Services
+
+	case s.TableID == TableIDPMT:
+		if d.pmtPIDs[pid] != s.TableIDExt {
+			return
+		}
+		if old := d.pmts[s.TableIDExt]; old != nil && old.Version == s.Version {
+			return
+		}
+		if pmt := ParsePMT(s); pmt != nil {
+			d.pmts[pmt.Program] = pmt
+			d.rebuildIndex()
+		}
+	}
+}
+
+// Flush возвращает отчёт за окно, закончившееся в момент at, и начинает новое окно
+func (d *Demuxer) Flush(at time.Time) *Report {
+	r := &Report{
+		Start:       d.windowStart,
+		End:         at,
+		LastPacket:  d.lastPacket,
+		Packets:     d.packets,
+		NullPackets: d.nullPackets,
+		PIDPackets:  d.pidPackets,
+		CCErrors:    d.ccErrors,
+		ETR290:      d.etr.flush(),
+		PCR:         make(map[uint16]*PCRStats),
+	}
+	for pid, t := range d.pcr {
+		if t.count > 0 {
+			r.PCR[pid] = t.flush()
+		}
+	}
+	if r.Start.IsZero() {
+		r.Start = at
+	}
+	// Без датаграмм за окно MDI прошлого интервала уже не описывает поток
+	if d.mdi != nil && d.mdi.last != nil && r.Packets > 0 {
+		mdi := *d.mdi.last
+		r.MDI = &mdi
+	}
+	for pid, v := range d.videos {
+		if info := v.report(); info != nil {
+			if r.Video == nil {
+				r.Video = make(map[uint16]*VideoInfo)
+			}
+			r.Video[pid] = info
+		}
+	}
+	for pid, a := range d.audios {
+		if info := a.report(); info != nil {
+			if r.Audio == nil {
+				r.Audio = make(map[uint16]*AudioInfo)
+			}
+			r.Audio[pid] = info
+		}
+	}
+
+	if d.pat != nil {
+		r.TSID = d.pat.TSID
+		r.HasTSID = true
+		r.Programs = d.programs()
+	}
+	if len(d.sdtSections) > 0 {
+		r.TSID = d.sdtTSID
+		r.HasTSID = true
+		r.Services = d.services()
+	}
+
+	d.windowStart = at
+	d.packets = 0
+	d.nullPackets = 0
+	d.pidPackets = make(map[uint16]int64)
+	d.ccErrors = make(map[uint16]int64)
+
+	return r
+}
+
+// programs собирает программы из PAT и полученных PMT
+func (d *Demuxer) programs() []Program {
+	var programs []Program
+	for number, pmtPID := range d.pat.Programs {
+		if number == 0 {
+			continue // NIT
+		}
+		p := Program{Number: number, PMTPID: pmtPID}
+		if pmt := d.pmts[number]; pmt != nil {
+			p.PCRPID = pmt.PCRPID
+			p.Streams = pmt.Streams
+			p.PMT = true
+		}
+		programs = append(programs, p)
+	}
+
+	sort.Slice(programs, func(i, j int) bool {
+		return programs[i].Number < programs[j].Number
+	})
+	return programs
+}
+
+// services собирает сервисы из всех секций SDT
+func (d *Demuxer) services() []Service {
+	numbers := make([]int, 0, len(d.sdtSections))
+	for n := range d.sdtSections {
+		numbers = append(numbers, int(n))
+	}
+	sort.Ints(numbers)
+
+	var services []Service
+	for _, n := range numbers {
+		services = append(services, d.sdtSections[uint8(n)]...)
+	}
+	return services
+}
@@ -0,0 +1,185 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+// buildSection собирает секцию с длинным заголовком и CRC
+func buildSection(tableID uint8, ext uint16, version uint8, body []byte) []byte {
+	length := 5 + len(body) + 4
+	s := []byte{
+		tableID,
+		0xB0 | byte(length>>8), byte(length),
+		byte(ext >> 8), byte(ext),
+		0xC1 | version<<1,
+		0x00, 0x00,
+	}
+	s = append(s, body...)
+	crc := CRC32(s)
+	return append(s, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// sectionPackets раскладывает секцию по TS пакетам
+func sectionPackets(pid uint16, section []byte, cc *uint8) [][]byte {
+	var packets [][]byte
+	data := append([]byte{0x00}, section...) // pointer_field
+	first := true
+	for len(data) > 0 {
+		pkt := make([]byte, PacketSize)
+		for i := range pkt {
+			pkt[i] = 0xFF
+		}
+		pkt[0] = SyncByte
+		pkt[1] = byte(pid>>8) & 0x1F
+		if first {
+			pkt[1] |= 0x40
+		}
+		pkt[2] = byte(pid)
+		pkt[3] = 0x10 | *cc&0x0F
+		*cc++
+		n := copy(pkt[4:], data)
+		data = data[n:]
+		packets = append(packets, pkt)
+		first = false
+	}
+	return packets
+}
+
+// payloadPacket создаёт пакет с payload и заданным CC
+func payloadPacket(pid uint16, cc uint8) []byte {
+	pkt := make([]byte, PacketSize)
+	pkt[0] = SyncByte
+	pkt[1] = byte(pid>>8) & 0x1F
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | cc&0x0F
+	return pkt
+}
+
+// testPSI возвращает PAT, PMT и SDT тестового потока:
+// программа 1000, PMT PID 0x012E, видео 0x0066 (h264), аудио 0x00CA (rus)
+func testPSI() [][]byte {
+	var packets [][]byte
+	var patCC, pmtCC, sdtCC uint8
+
+	pat := buildSection(TableIDPAT, 12, 0, []byte{
+		0x03, 0xE8, 0xE1, 0x2E, // программа 1000 -> PID 0x012E
+	})
+	packets = append(packets, sectionPackets(PATPID, pat, &patCC)...)
+
+	pmt := buildSection(TableIDPMT, 1000, 0, []byte{
+		0xE0, 0x66, 0xF0, 0x00, // PCR PID 0x0066, program_info_length 0
+		0x1B, 0xE0, 0x66, 0xF0, 0x00,
+		0x03, 0xE0, 0xCA, 0xF0, 0x06,
+		DescriptorLanguage, 0x04, 'r', 'u', 's', 0x00,
+	})
+	packets = append(packets, sectionPackets(0x012E, pmt, &pmtCC)...)
+
+	provider := []byte("OTCNET")
+	name := []byte{0x01, 0xC1, 0xD8, 0xDB, 0xDA} // "Силк" в ISO 8859-5
+	svcDesc := []byte{DescriptorService, byte(3 + len(provider) + len(name)), 0x19, byte(len(provider))}
+	svcDesc = append(svcDesc, provider...)
+	svcDesc = append(svcDesc, byte(len(name)))
+	svcDesc = append(svcDesc, name...)
+	sdtBody := []byte{0x00, 0x01, 0xFF, 0x03, 0xE8, 0xFC, 0x80, byte(len(svcDesc))}
+	sdtBody = append(sdtBody, svcDesc...)
+	sdt := buildSection(TableIDSDTActual, 12, 0, sdtBody)
+	packets = append(packets, sectionPackets(SDTPID, sdt, &sdtCC)...)
+
+	return packets
+}
+
+func TestDemuxerTables(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+	for _, pkt := range testPSI() {
+		d.FeedPacket(pkt, start)
+	}
+
+	r := d.Flush(start.Add(time.Second))
+
+	if !r.HasTSID || r.TSID != 12 {
+		t.Errorf("TSID = %d (%v), want 12", r.TSID, r.HasTSID)
+	}
+	if len(r.Programs) != 1 {
+		t.Fatalf("Programs count = %d, want 1", len(r.Programs))
+	}
+
+	p := r.Programs[0]
+	if p.Number != 1000 || p.PMTPID != 0x012E || p.PCRPID != 0x0066 {
+		t.Errorf("Program = %+v, want 1000/0x012E/0x0066", p)
+	}
+	if len(p.Streams) != 2 {
+		t.Fatalf("Streams count = %d, want 2", len(p.Streams))
+	}
+	if p.Streams[0].Type != 0x1B || p.Streams[0].PID != 0x0066 {
+		t.Errorf("Streams[0] = %+v, want h264 on 0x0066", p.Streams[0])
+	}
+	if p.Streams[1].Language != "rus" {
+		t.Errorf("Streams[1] language = %s, want rus", p.Streams[1].Language)
+	}
+
+	if len(r.Services) != 1 {
+		t.Fatalf("Services count = %d, want 1", len(r.Services))
+	}
+	if r.Services[0].Name != "Силк" || r.Services[0].Provider != "OTCNET" {
+		t.Errorf("Service = %+v, want Силк/OTCNET", r.Services[0])
+	}
+	if r.Services[0].Type != 0x19 {
+		t.Errorf("Service type = 0x%02X, want 0x19", r.Services[0].Type)
+	}
+}
+
+func TestDemuxerContinuity(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+
+	// 0, 1, 1 (дубликат допустим), 2, 5 (разрыв), 6
+	for _, cc := range []uint8{0, 1, 1, 2, 5, 6} {
+		d.FeedPacket(payloadPacket(0x0100, cc), start)
+	}
+	// Null пакеты не проверяются
+	d.FeedPacket(payloadPacket(NullPID, 3), start)
+	d.FeedPacket(payloadPacket(NullPID, 9), start)
+
+	r := d.Flush(start.Add(time.Second))
+
+	if r.CCErrors[0x0100] != 1 {
+		t.Errorf("CCErrors[0x0100] = %d, want 1", r.CCErrors[0x0100])
+	}
+	if r.CCErrors[NullPID] != 0 {
+		t.Errorf("CCErrors[NullPID] = %d, want 0", r.CCErrors[NullPID])
+	}
+	if r.Packets != 8 || r.NullPackets != 2 {
+		t.Errorf("Packets = %d/%d, want 8/2", r.Packets, r.NullPackets)
+	}
+	if r.Bitrate() != 8*PacketSize*8 {
+		t.Errorf("Bitrate = %d, want %d", r.Bitrate(), 8*PacketSize*8)
+	}
+
+	// Новое окно начинается с нуля
+	r = d.Flush(start.Add(2 * time.Second))
+	if r.Packets != 0 || len(r.CCErrors) != 0 {
+		t.Errorf("second window = %d packets, %d CC errors, want empty", r.Packets, len(r.CCErrors))
+	}
+}
+
+func TestDemuxerWriteResync(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+
+	var data []byte
+	data = append(data, 0x00, 0x01) // мусор до sync byte
+	for cc := uint8(0); cc < 3; cc++ {
+		data = append(data, payloadPacket(0x0100, cc)...)
+	}
+
+	// Пакет разрезан между двумя вызовами
+	d.Write(data[:100], start)
+	d.Write(data[100:], start)
+
+	r := d.Flush(start.Add(time.Second))
+	if r.PIDPackets[0x0100] != 3 {
+		t.Errorf("PIDPackets[0x0100] = %d, want 3", r.PIDPackets[0x0100])
+	}
+}
@@ -0,0 +1,351 @@
+package mpegts
+
+import "time"
+
+// Indicator индикатор ETSI TR 101 290
+type Indicator struct {
+	Name     string
+	Priority int
+}
+
+// Индикаторы TR 101 290, которые проверяет анализатор
+var (
+	// Priority 1
+	TSSyncLoss    = Indicator{"TS_sync_loss", 1}
+	SyncByteError = Indicator{"Sync_byte_error", 1}
+	PATError      = Indicator{"PAT_error", 1}
+	CCError       = Indicator{"CC_error", 1}
+	PMTError      = Indicator{"PMT_error", 1}
+	PIDError      = Indicator{"PID_error", 1}
+
+	// Priority 2
+	TransportError     = Indicator{"Transport_error", 2}
+	CRCError           = Indicator{"CRC_error", 2}
+	PCRRepetitionError = Indicator{"PCR_repetition_error", 2}
+	PCRDiscontinuity   = Indicator{"PCR_discontinuity_indicator_error", 2}
+	PCRAccuracyError   = Indicator{"PCR_accuracy_error", 2}
+	PTSError           = Indicator{"PTS_error", 2}
+	CATError           = Indicator{"CAT_error", 2}
+
+	// Priority 3
+	NITActualError    = Indicator{"NIT_actual_error", 3}
+	SIRepetitionError = Indicator{"SI_repetition_error", 3}
+	UnreferencedPID   = Indicator{"Unreferenced_PID", 3}
+	SDTActualError    = Indicator{"SDT_actual_error", 3}
+	EITActualError    = Indicator{"EIT_actual_error", 3}
+	TDTError          = Indicator{"TDT_error", 3}
+)
+
+// NoPID используется для ошибок, относящихся ко всему потоку
+const NoPID = -1
+
+// ETR290Error ключ счётчика ошибок: индикатор и PID
+type ETR290Error struct {
+	Indicator Indicator
+	PID       int // NoPID для ошибок всего потока
+}
+
+// Интервалы TR 101 290
+const (
+	patInterval          = 500 * time.Millisecond
+	pmtInterval          = 500 * time.Millisecond
+	pidInterval          = 5 * time.Second // "user specified period", берём типовое значение
+	ptsInterval          = 700 * time.Millisecond
+	unreferencedInterval = 500 * time.Millisecond
+	nitInterval          = 10 * time.Second
+	sdtInterval          = 2 * time.Second
+	eitInterval          = 2 * time.Second
+	tdtInterval          = 30 * time.Second
+	siMinInterval        = 25 * time.Millisecond
+	timerCheckInterval   = 100 * time.Millisecond
+)
+
+// Таблицы SI
+const (
+	tableIDNITOther    = 0x41
+	tableIDSDTOther    = 0x46
+	tableIDBAT         = 0x4A
+	tableIDEITActualPF = 0x4E
+	tableIDTDT         = 0x70
+	tableIDTOT         = 0x73
+	tableIDST          = 0x72
+	TDTPID             = 0x0014
+)
+
+// sectionKey идентифицирует секцию для проверки частоты повторения
+type sectionKey struct {
+	pid     uint16
+	tableID uint8
+	ext     uint16
+	number  uint8
+}
+
+// etr290 анализатор TR 101 290 для одного потока.
+// Проверки отсутствия таблиц и PID выполняются только пока поток идёт:
+// полное пропадание потока отражается статусом offline, а не ошибками ETR 290.
+// Таблицы priority 3 (NIT, SDT, EIT, TDT) проверяются только после того,
+// как они хотя бы раз появились в потоке.
+type etr290 struct {
+	demux  *Demuxer
+	errors map[ETR290Error]int64
+
+	lastCheck time.Time
+	lastPAT   time.Time
+	lastPMT   map[uint16]time.Time // PMT PID -> последняя секция PMT
+	lastPID   map[uint16]time.Time // PID -> последний пакет
+	lastPTS   map[uint16]time.Time // PID -> последний PES с PTS
+	unrefFrom map[uint16]time.Time // PID -> начало периода без ссылки на PID
+	lastNIT   time.Time
+	lastSDT   time.Time
+	lastEIT   time.Time
+	lastTDT   time.Time
+	sections  map[sectionKey]time.Time
+
+	hasCAT    bool
+	scrambled bool
+}
+
+func newETR290(d *Demuxer) *etr290 {
+	return &etr290{
+		demux:     d,
+		errors:    make(map[ETR290Error]int64),
+		lastPMT:   make(map[uint16]time.Time),
+		lastPID:   make(map[uint16]time.Time),
+		lastPTS:   make(map[uint16]time.Time),
+		unrefFrom: make(map[uint16]time.Time),
+		sections:  make(map[sectionKey]time.Time),
+	}
+}
+
+// add увеличивает счётчик ошибки
+func (e *etr290) add(ind Indicator, pid int) {
+	e.errors[ETR290Error{Indicator: ind, PID: pid}]++
+}
+
+// flush возвращает ошибки за окно и начинает новое окно
+func (e *etr290) flush() map[ETR290Error]int64 {
+	errors := e.errors
+	e.errors = make(map[ETR290Error]int64)
+	return errors
+}
+
+// syncLoss вызывается, когда для поиска sync byte пришлось пропустить байты
+func (e *etr290) syncLoss(skipped int) {
+	e.add(SyncByteError, NoPID)
+	if skipped >= PacketSize {
+		e.add(TSSyncLoss, NoPID)
+	}
+}
+
+// packet проверяет очередной пакет с корректным sync byte
+func (e *etr290) packet(pkt []byte, h Header, at time.Time) {
+	if e.lastPAT.IsZero() {
+		e.lastPAT = at
+		e.lastCheck = at
+	}
+	e.lastPID[h.PID] = at
+
+	if h.TEI {
+		e.add(TransportError, int(h.PID))
+		return
+	}
+
+	if h.Scrambling != 0 {
+		switch {
+		case h.PID == PATPID:
+			e.add(PATError, int(h.PID))
+		case e.demux.isPMTPID(h.PID):
+			e.add(PMTError, int(h.PID))
+		default:
+			e.scrambled = true
+		}
+	}
+
+	if h.PUSI && h.Scrambling == 0 && e.demux.hasTimestamps(h.PID) {
+		if pes, ok := ParsePES(Payload(pkt, h)); ok && pes.HasPTS {
+			e.lastPTS[h.PID] = at
+		}
+	}
+
+	if at.Sub(e.lastCheck) >= timerCheckInterval {
+		e.checkTimers(at)
+		e.lastCheck = at
+	}
+}
+
+// pcrSample проверяет интервал, разрывы и точность очередного PCR
+func (e *etr290) pcrSample(pid uint16, s pcrSample) {
+	if s.discontinuity {
+		e.add(PCRDiscontinuity, int(pid))
+		return
+	}
+	if s.interval > pcrRepetitionLimit {
+		e.add(PCRRepetitionError, int(pid))
+	}
+	if s.hasAccuracy && (s.accuracy > pcrAccuracyLimit || s.accuracy < -pcrAccuracyLimit) {
+		e.add(PCRAccuracyError, int(pid))
+	}
+}
+
+// ccError вызывается демультиплексором при ошибке continuity counter
+func (e *etr290) ccError(pid uint16) {
+	e.add(CCError, int(pid))
+}
+
+// section проверяет собранную секцию PSI/SI
+func (e *etr290) section(pid uint16, s *Section, at time.Time) {
+	crcChecked := s.SyntaxIndicator || s.TableID == tableIDTOT
+	if crcChecked && !s.CRCValid {
+		e.add(CRCError, int(pid))
+		return
+	}
+
+	switch pid {
+	case PATPID:
+		if s.TableID != TableIDPAT {
+			e.add(PATError, int(pid))
+			return
+		}
+		e.lastPAT = at
+	case CATPID:
+		if s.TableID != TableIDCAT {
+			e.add(CATError, int(pid))
+			return
+		}
+		e.hasCAT = true
+	case NITPID:
+		switch s.TableID {
+		case TableIDNITActual:
+			e.lastNIT = at
+		case tableIDNITOther, tableIDST:
+		default:
+			e.add(NITActualError, int(pid))
+			return
+		}
+	case SDTPID:
+		switch s.TableID {
+		case TableIDSDTActual:
+			e.lastSDT = at
+		case tableIDSDTOther, tableIDBAT, tableIDST:
+		default:
+			e.add(SDTActualError, int(pid))
+			return
+		}
+	case EITPID:
+		switch {
+		case s.TableID == tableIDEITActualPF:
+			e.lastEIT = at
+		case s.TableID >= 0x4F && s.TableID <= 0x6F, s.TableID == tableIDST:
+		default:
+			e.add(EITActualError, int(pid))
+			return
+		}
+	case TDTPID:
+		if s.TableID == tableIDTDT {
+			e.lastTDT = at
+		}
+	default:
+		if s.TableID == TableIDPMT && e.demux.isPMTPID(pid) {
+			e.lastPMT[pid] = at
+		}
+	}
+
+	// Минимальный интервал повторения одной и той же секции SI
+	if pid != NITPID && pid != SDTPID && pid != EITPID && pid != TDTPID {
+		return
+	}
+	key := sectionKey{pid: pid, tableID: s.TableID, ext: s.TableIDExt, number: s.Number}
+	if last, ok := e.sections[key]; ok && at.Sub(last) < siMinInterval {
+		e.add(SIRepetitionError, int(pid))
+	}
+	e.sections[key] = at
+}
+
+// checkTimers проверяет отсутствие таблиц и PID дольше допустимого
+func (e *etr290) checkTimers(at time.Time) {
+	d := e.demux
+
+	if at.Sub(e.lastPAT) > patInterval {
+		e.add(PATError, PATPID)
+		e.lastPAT = at
+	}
+
+	if d.pat != nil {
+		for pmtPID := range d.pmtPIDs {
+			last, ok := e.lastPMT[pmtPID]
+			if !ok || at.Sub(last) > pmtInterval {
+				if ok {
+					e.add(PMTError, int(pmtPID))
+				}
+				e.lastPMT[pmtPID] = at
+			}
+		}
+	}
+
+	for _, pmt := range d.pmts {
+		for _, es := range pmt.Streams {
+			last, ok := e.lastPID[es.PID]
+			if !ok {
+				// Отсчёт начинается с момента появления PID в PMT
+				e.lastPID[es.PID] = at
+				continue
+			}
+			if at.Sub(last) > pidInterval {
+				e.add(PIDError, int(es.PID))
+				e.lastPID[es.PID] = at
+			}
+		}
+	}
+
+	for pid, last := range e.lastPTS {
+		if at.Sub(last) > ptsInterval {
+			e.add(PTSError, int(pid))
+			e.lastPTS[pid] = at
+		}
+	}
+
+	if e.scrambled && !e.hasCAT {
+		e.add(CATError, CATPID)
+	}
+	e.scrambled = false
+
+	e.checkTable(&e.lastNIT, nitInterval, NITActualError, NITPID, at)
+	e.checkTable(&e.lastSDT, sdtInterval, SDTActualError, SDTPID, at)
+	e.checkTable(&e.lastEIT, eitInterval, EITActualError, EITPID, at)
+	e.checkTable(&e.lastTDT, tdtInterval, TDTError, TDTPID, at)
+
+	e.checkUnreferenced(at)
+}
+
+// checkTable проверяет период повторения таблицы, уже встречавшейся в потоке
+func (e *etr290) checkTable(last *time.Time, limit time.Duration, ind Indicator, pid int, at time.Time) {
+	if last.IsZero() || at.Sub(*last) <= limit {
+		return
+	}
+	e.add(ind, pid)
+	*last = at
+}
+
+// checkUnreferenced ищет PID, на которые нет ссылок в PAT/PMT/CAT
+func (e *etr290) checkUnreferenced(at time.Time) {
+	d := e.demux
+	if !d.complete() {
+		return
+	}
+
+	for pid, last := range e.lastPID {
+		if at.Sub(last) > unreferencedInterval || d.isReferenced(pid) {
+			delete(e.unrefFrom, pid)
+			continue
+		}
+		from, ok := e.unrefFrom[pid]
+		if !ok {
+			e.unrefFrom[pid] = at
+			continue
+		}
+		if at.Sub(from) > unreferencedInterval {
+			e.add(UnreferencedPID, int(pid))
+			e.unrefFrom[pid] = at
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+// pcrPacket создаёт пакет с adaptation field, содержащим PCR
+func pcrPacket(pid uint16, cc uint8, pcr uint64, discontinuity bool) []byte {
+	pkt := payloadPacket(pid, cc)
+	pkt[3] |= 0x20
+	pkt[4] = 7
+	pkt[5] = 0x10
+	if discontinuity {
+		pkt[5] |= 0x80
+	}
+	base, ext := pcr/300, pcr%300
+	pkt[6] = byte(base >> 25)
+	pkt[7] = byte(base >> 17)
+	pkt[8] = byte(base >> 9)
+	pkt[9] = byte(base >> 1)
+	pkt[10] = byte(base<<7) | 0x7E | byte(ext>>8)
+	pkt[11] = byte(ext)
+	return pkt
+}
+
+func TestETR290MissingTables(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+	for _, pkt := range testPSI() {
+		d.FeedPacket(pkt, start)
+	}
+
+	// Видео идёт, PAT и PMT больше не повторяются
+	var cc uint8
+	for i := 1; i <= 120; i++ {
+		d.FeedPacket(payloadPacket(0x0066, cc), start.Add(time.Duration(i)*10*time.Millisecond))
+		cc++
+	}
+
+	r := d.Flush(start.Add(1200 * time.Millisecond))
+
+	if r.ETR290[ETR290Error{PATError, PATPID}] != 2 {
+		t.Errorf("PAT_error = %d, want 2", r.ETR290[ETR290Error{PATError, PATPID}])
+	}
+	if r.ETR290[ETR290Error{PMTError, 0x012E}] != 2 {
+		t.Errorf("PMT_error = %d, want 2", r.ETR290[ETR290Error{PMTError, 0x012E}])
+	}
+	if r.ETR290[ETR290Error{CCError, 0x0066}] != 0 {
+		t.Errorf("CC_error = %d, want 0", r.ETR290[ETR290Error{CCError, 0x0066}])
+	}
+}
+
+func TestETR290TransportAndCRC(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+
+	pkt := payloadPacket(0x0100, 0)
+	pkt[1] |= 0x80 // transport_error_indicator
+	d.FeedPacket(pkt, start)
+
+	var cc uint8
+	pat := buildSection(TableIDPAT, 12, 0, []byte{0x03, 0xE8, 0xE1, 0x2E})
+	pat[len(pat)-1] ^= 0xFF
+	for _, p := range sectionPackets(PATPID, pat, &cc) {
+		d.FeedPacket(p, start)
+	}
+
+	// Мусор между пакетами
+	data := append([]byte{0x00, 0x00, 0x00}, payloadPacket(0x0100, 1)...)
+	d.Write(data, start)
+
+	r := d.Flush(start.Add(time.Second))
+
+	if r.ETR290[ETR290Error{TransportError, 0x0100}] != 1 {
+		t.Errorf("Transport_error = %d, want 1", r.ETR290[ETR290Error{TransportError, 0x0100}])
+	}
+	if r.ETR290[ETR290Error{CRCError, PATPID}] != 1 {
+		t.Errorf("CRC_error = %d, want 1", r.ETR290[ETR290Error{CRCError, PATPID}])
+	}
+	if r.ETR290[ETR290Error{SyncByteError, NoPID}] != 1 {
+		t.Errorf("Sync_byte_error = %d, want 1", r.ETR290[ETR290Error{SyncByteError, NoPID}])
+	}
+	if r.ETR290[ETR290Error{TSSyncLoss, NoPID}] != 0 {
+		t.Errorf("TS_sync_loss = %d, want 0", r.ETR290[ETR290Error{TSSyncLoss, NoPID}])
+	}
+}
+
+func TestETR290PCR(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+
+	// PCR каждые 30 мс, затем 60 мс, затем скачок на 1 с без discontinuity_indicator
+	pcrs := []uint64{0, 30, 60, 120, 1120}
+	for i, ms := range pcrs {
+		d.FeedPacket(pcrPacket(0x0066, uint8(i), ms*pcrClock/1000, false), start.Add(time.Duration(ms)*time.Millisecond))
+	}
+	// Скачок с discontinuity_indicator ошибкой не считается
+	d.FeedPacket(pcrPacket(0x0066, 5, 5000*pcrClock/1000, true), start.Add(1150*time.Millisecond))
+
+	r := d.Flush(start.Add(2 * time.Second))
+
+	if r.ETR290[ETR290Error{PCRRepetitionError, 0x0066}] != 1 {
+		t.Errorf("PCR_repetition_error = %d, want 1", r.ETR290[ETR290Error{PCRRepetitionError, 0x0066}])
+	}
+	if r.ETR290[ETR290Error{PCRDiscontinuity, 0x0066}] != 1 {
+		t.Errorf("PCR_discontinuity_indicator_error = %d, want 1", r.ETR290[ETR290Error{PCRDiscontinuity, 0x0066}])
+	}
+}
+
+func TestETR290PCRAccuracy(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+
+	// Постоянный битрейт: 10 пакетов между PCR, PCR каждые 10 мс
+	const step = 10 * pcrClock / 1000
+	var cc uint8
+	pcr := uint64(0)
+	for i := 0; i < 200; i++ {
+		value := pcr
+		if i == 150 {
+			value += 100 // 3.7 мкс ошибки
+		}
+		d.FeedPacket(pcrPacket(0x0066, cc, value, false), start)
+		cc++
+		for j := 0; j < 9; j++ {
+			d.FeedPacket(payloadPacket(0x0066, cc), start)
+			cc++
+		}
+		pcr += step
+	}
+
+	r := d.Flush(start.Add(time.Second))
+
+	// Ошибочный PCR и следующий за ним
+	if got := r.ETR290[ETR290Error{PCRAccuracyError, 0x0066}]; got != 2 {
+		t.Errorf("PCR_accuracy_error = %d, want 2", got)
+	}
+}
@@ -0,0 +1,90 @@
+package mpegts
+
+// Застывшее изображение определяется без декодирования, по размерам кодированных
+// кадров. Кодер, повторяющий один и тот же кадр, передаёт кадры без изменений:
+// P/B-кадры из одних пропущенных макроблоков — крошечные и почти одинаковые, а
+// зависший мультиплексор или кодер перестаёт вставлять I-кадры. Чёрный или
+// неподвижный синтетический кадр (заставка, испытательная таблица) даёт те же
+// признаки, поэтому тоже считается подозрительным
+
+// Причины подозрения на застывшее изображение
+const (
+	FrozenNoKeyFrames  = "no_key_frames"  // I-кадров нет дольше трёх GOP и freezeKeyTimeout
+	FrozenRepeatedSize = "repeated_sizes" // размеры P/B-кадров почти не меняются
+	FrozenTinyFrames   = "tiny_frames"    // все P/B-кадры меньше 1% I-кадра
+)
+
+// freezeWindow последних P/B-кадров, по которым оценивается постоянство размера
+const freezeWindow = 50
+
+// freezeKeyTimeout секунд без I-кадра, раньше которых их отсутствие не подозрительно,
+// даже если GOP короткий
+const freezeKeyTimeout = 10
+
+// freezeDefaultFPS частота кадров, если она ещё не известна
+const freezeDefaultFPS = 25
+
+// freezeDetector собирает размеры кадров одного видео PID
+type freezeDetector struct {
+	sizes    []int // размеры последних P/B-кадров, не больше freezeWindow
+	keySize  int   // размер последнего I-кадра
+	keySeen  bool
+	sinceKey int // кадров с последнего I-кадра
+	pictures int // кадров за окно отчёта
+}
+
+// picture учитывает кодированный кадр (или поле) размером size байт
+func (f *freezeDetector) picture(size int, key bool) {
+	f.pictures++
+	if key {
+		f.keySize = size
+		f.keySeen = true
+		f.sinceKey = 0
+		return
+	}
+	f.sinceKey++
+	if len(f.sizes) == freezeWindow {
+		f.sizes = append(f.sizes[:0], f.sizes[1:]...)
+	}
+	f.sizes = append(f.sizes, size)
+}
+
+// reset забывает размеры после потери данных: кадры с пропусками короче настоящих
+func (f *freezeDetector) reset() {
+	f.sizes = f.sizes[:0]
+}
+
+// suspect возвращает причину подозрения на застывшее изображение или пустую строку
+// и начинает новое окно отчёта. gop — измеренная длина GOP, fps — частота кадров (0, если неизвестны)
+func (f *freezeDetector) suspect(gop int, fps float64) string {
+	pictures := f.pictures
+	f.pictures = 0
+	if pictures == 0 {
+		return "" // кадров нет: PID пропал или скремблирован, это не застывшая картинка
+	}
+
+	if fps <= 0 {
+		fps = freezeDefaultFPS
+	}
+	if f.keySeen && f.sinceKey > max(3*gop, int(freezeKeyTimeout*fps)) {
+		return FrozenNoKeyFrames
+	}
+
+	if len(f.sizes) < freezeWindow {
+		return ""
+	}
+	low, high, total := f.sizes[0], f.sizes[0], 0
+	for _, size := range f.sizes {
+		low, high = min(low, size), max(high, size)
+		total += size
+	}
+	mean := total / len(f.sizes)
+	// Номер кадра и POC в заголовках срезов меняют размер на пару байт
+	if high-low <= max(mean/100, 4) {
+		return FrozenRepeatedSize
+	}
+	if f.keySeen && high*100 < f.keySize {
+		return FrozenTinyFrames
+	}
+	return ""
+}
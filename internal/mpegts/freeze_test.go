@@ -0,0 +1,169 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreezeDetector(t *testing.T) {
+	// varying возвращает размеры P-кадров, меняющиеся как у обычного видео
+	varying := func(base, i int) int {
+		return base + (i*7919)%(base/2)
+	}
+
+	tests := []struct {
+		name   string
+		gop    int
+		fps    float64
+		frames func(f *freezeDetector)
+		want   string
+	}{
+		{
+			name: "normal video",
+			gop:  12,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				for i := 0; i < 120; i++ {
+					if i%12 == 0 {
+						f.picture(60000, true)
+					} else {
+						f.picture(varying(6000, i), false)
+					}
+				}
+			},
+		},
+		{
+			name: "repeated sizes",
+			gop:  12,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				for i := 0; i < 120; i++ {
+					if i%12 == 0 {
+						f.picture(60000, true)
+					} else {
+						f.picture(5000+i%3, false)
+					}
+				}
+			},
+			want: FrozenRepeatedSize,
+		},
+		{
+			name: "tiny frames",
+			gop:  12,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				for i := 0; i < 120; i++ {
+					if i%12 == 0 {
+						f.picture(60000, true)
+					} else {
+						f.picture(varying(300, i), false)
+					}
+				}
+			},
+			want: FrozenTinyFrames,
+		},
+		{
+			name: "no key frames",
+			gop:  12,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				f.picture(60000, true)
+				for i := 0; i < 300; i++ {
+					f.picture(varying(6000, i), false)
+				}
+			},
+			want: FrozenNoKeyFrames,
+		},
+		{
+			name: "long GOP",
+			gop:  100,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				f.picture(60000, true)
+				for i := 0; i < 280; i++ {
+					f.picture(varying(6000, i), false)
+				}
+			},
+		},
+		{
+			name: "too few frames",
+			gop:  12,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				for i := 0; i < 30; i++ {
+					f.picture(5000, false)
+				}
+			},
+		},
+		{
+			name: "reset after discontinuity",
+			gop:  12,
+			fps:  25,
+			frames: func(f *freezeDetector) {
+				for i := 0; i < 60; i++ {
+					f.picture(5000, false)
+				}
+				f.reset()
+				for i := 0; i < 10; i++ {
+					f.picture(5000, false)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &freezeDetector{}
+			tt.frames(f)
+			if got := f.suspect(tt.gop, tt.fps); got != tt.want {
+				t.Errorf("suspect() = %q, want %q", got, tt.want)
+			}
+			// Без кадров за окно подозрения нет
+			if got := f.suspect(tt.gop, tt.fps); got != "" {
+				t.Errorf("suspect() without frames = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestDemuxerVideoNotFrozen(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+	for _, pkt := range testPSI() {
+		d.FeedPacket(pkt, start)
+	}
+
+	nal := func(header byte, rbsp []byte) []byte {
+		return append([]byte{0x00, 0x00, 0x00, 0x01, header}, rbsp...)
+	}
+	// Данные среза после заголовка: размер P-кадров меняется, SPS перед I-кадром в него не входит
+	slice := func(sliceType, frame uint32, size int) []byte {
+		data := h264TestSlice(sliceType, false, frame)
+		for len(data) < size {
+			data = append(data, 0xA5)
+		}
+		return nal(0x41, data)
+	}
+	sps := nal(0x67, h264TestSPS(100, 40, 120, 68, true, 8, 1, 50))
+
+	var cc uint8
+	pts := uint64(90000)
+	for frame := 0; frame < 80; frame++ {
+		es := slice(0, uint32(frame), 500+(frame*37)%400)
+		if frame%12 == 0 {
+			es = append(append([]byte{}, sps...), slice(2, uint32(frame), 4000)...)
+		}
+		for _, pkt := range pesPackets(0x0066, pts, es, &cc) {
+			d.FeedPacket(pkt, start)
+		}
+		pts += 3600
+	}
+
+	info := d.Flush(start.Add(time.Second)).Video[0x0066]
+	if info == nil {
+		t.Fatal("no video info for 0x0066")
+	}
+	if info.Frozen != "" || info.GOPLength != 12 {
+		t.Errorf("info = %+v, want GOP 12 and not frozen", *info)
+	}
+}
@@ -0,0 +1,210 @@
+package mpegts
+
+import "fmt"
+
+// Типы NAL unit H.264 (ISO/IEC 14496-10 таблица 7-1)
+const (
+	h264NALSlice    = 1
+	h264NALSliceIDR = 5
+	h264NALSPS      = 7
+)
+
+// h264SPS поля SPS, нужные для разбора заголовков срезов, и параметры потока
+type h264SPS struct {
+	info                VideoInfo
+	separateColourPlane bool
+	log2MaxFrameNum     int
+	frameMbsOnly        bool
+}
+
+// h264Profiles названия профилей по profile_idc
+var h264Profiles = map[uint32]string{
+	66:  "Baseline",
+	77:  "Main",
+	88:  "Extended",
+	100: "High",
+	110: "High 10",
+	122: "High 4:2:2",
+	244: "High 4:4:4",
+}
+
+// h264Unit разбирает NAL unit H.264
+func (v *videoTracker) h264Unit(nal []byte) {
+	switch nal[0] & 0x1F {
+	case h264NALSPS:
+		if sps, ok := parseH264SPS(unescapeRBSP(nal[1:])); ok {
+			v.h264 = sps
+			v.setInfo(sps.info)
+		}
+	case h264NALSlice, h264NALSliceIDR:
+		if v.h264 == nil {
+			return
+		}
+		r := &bitReader{data: unescapeRBSP(nal[1:])}
+		if r.ue() != 0 {
+			return // не первый срез кадра
+		}
+		sliceType := r.ue() % 5
+		r.ue() // pic_parameter_set_id
+		if v.h264.separateColourPlane {
+			r.skip(2)
+		}
+		r.skip(v.h264.log2MaxFrameNum)
+		fields := 2
+		if !v.h264.frameMbsOnly && r.flag() {
+			fields = 1
+		}
+		if r.exhausted() {
+			return
+		}
+		// I и SI срезы
+		v.picture(fields, sliceType == 2 || sliceType == 4, nal[0]&0x1F == h264NALSliceIDR)
+	}
+}
+
+// parseH264SPS разбирает seq_parameter_set_rbsp (7.3.2.1.1) без nal_unit_header
+func parseH264SPS(rbsp []byte) (*h264SPS, bool) {
+	r := &bitReader{data: rbsp}
+	sps := &h264SPS{}
+
+	profileIDC := r.u(8)
+	constraints := r.u(8)
+	levelIDC := r.u(8)
+	r.ue() // seq_parameter_set_id
+
+	sps.info.Profile = h264Profiles[profileIDC]
+	if profileIDC == 66 && constraints&0x40 != 0 {
+		sps.info.Profile = "Constrained Baseline"
+	}
+	if sps.info.Profile == "" {
+		sps.info.Profile = fmt.Sprintf("profile_%d", profileIDC)
+	}
+	sps.info.Level = fmt.Sprintf("%d.%d", levelIDC/10, levelIDC%10)
+
+	chromaFormat := uint32(1)
+	switch profileIDC {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			sps.separateColourPlane = r.flag()
+		}
+		r.ue()        // bit_depth_luma_minus8
+		r.ue()        // bit_depth_chroma_minus8
+		r.skip(1)     // qpprime_y_zero_transform_bypass_flag
+		if r.flag() { // seq_scaling_matrix_present_flag
+			lists := 8
+			if chromaFormat == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if !r.flag() {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				skipScalingList(r, size)
+			}
+		}
+	}
+
+	sps.log2MaxFrameNum = int(r.ue()) + 4
+	switch r.ue() { // pic_order_cnt_type
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.skip(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		cycle := r.ue()
+		for i := uint32(0); i < cycle && !r.exhausted(); i++ {
+			r.se()
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.skip(1) // gaps_in_frame_num_value_allowed_flag
+
+	widthMbs := int(r.ue()) + 1
+	heightMapUnits := int(r.ue()) + 1
+	sps.frameMbsOnly = r.flag()
+	if !sps.frameMbsOnly {
+		r.skip(1) // mb_adaptive_frame_field_flag
+	}
+	r.skip(1) // direct_8x8_inference_flag
+
+	frameHeight := 2
+	if sps.frameMbsOnly {
+		frameHeight = 1
+	}
+	width := widthMbs * 16
+	height := heightMapUnits * 16 * frameHeight
+	if r.flag() { // frame_cropping_flag
+		// Единицы обрезки по таблице 6-1 и 7.4.2.1.1
+		cropX, cropY := 1, frameHeight
+		if chromaFormat != 0 && !sps.separateColourPlane {
+			if chromaFormat != 3 {
+				cropX = 2
+			}
+			if chromaFormat == 1 {
+				cropY *= 2
+			}
+		}
+		left, right := int(r.ue()), int(r.ue())
+		top, bottom := int(r.ue()), int(r.ue())
+		width -= cropX * (left + right)
+		height -= cropY * (top + bottom)
+	}
+	if r.exhausted() || width <= 0 || height <= 0 {
+		return nil, false
+	}
+	sps.info.Width = width
+	sps.info.Height = height
+	sps.info.Interlaced = !sps.frameMbsOnly
+	sps.info.Aspect = displayAspect(width, height, 1, 1)
+
+	if !r.flag() { // vui_parameters_present_flag
+		return sps, true
+	}
+	if r.flag() { // aspect_ratio_info_present_flag
+		sarW, sarH := readSAR(r)
+		if aspect := displayAspect(width, height, sarW, sarH); aspect != "" {
+			sps.info.Aspect = aspect
+		}
+	}
+	if r.flag() { // overscan_info_present_flag
+		r.skip(1)
+	}
+	if r.flag() { // video_signal_type_present_flag
+		r.skip(4)
+		if r.flag() { // colour_description_present_flag
+			r.skip(24)
+		}
+	}
+	if r.flag() { // chroma_loc_info_present_flag
+		r.ue()
+		r.ue()
+	}
+	if r.flag() { // timing_info_present_flag
+		numUnits := r.u(32)
+		timeScale := r.u(32)
+		// Один кадр — два тика (E.2.1)
+		if !r.exhausted() {
+			sps.info.FrameRate = frameRate(uint64(timeScale), 2*uint64(numUnits))
+		}
+	}
+	return sps, true
+}
+
+// skipScalingList пропускает scaling_list (7.3.2.1.1.1)
+func skipScalingList(r *bitReader, size int) {
+	last, next := int32(8), int32(8)
+	for j := 0; j < size && !r.exhausted(); j++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
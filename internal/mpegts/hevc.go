@@ -0,0 +1,326 @@
+package mpegts
+
+import "fmt"
+
+// Типы NAL unit HEVC (ITU-T H.265 таблица 7-1)
+const (
+	hevcNALIRAPFirst = 16 // BLA_W_LP
+	hevcNALIRAPLast  = 21 // CRA_NUT
+	hevcNALVCLLast   = 31
+	hevcNALSPS       = 33
+	hevcNALPPS       = 34
+)
+
+// hevcSPS параметры потока из SPS HEVC
+type hevcSPS struct {
+	info     VideoInfo
+	fieldSeq bool // каждая картинка — поле (field_seq_flag в VUI)
+}
+
+// hevcPPS поля PPS, нужные для разбора заголовка среза
+type hevcPPS struct {
+	extraSliceHeaderBits int
+}
+
+// hevcProfiles названия профилей по general_profile_idc
+var hevcProfiles = map[uint32]string{
+	1: "Main",
+	2: "Main 10",
+	3: "Main Still Picture",
+	4: "Format Range Extensions",
+}
+
+// hevcUnit разбирает NAL unit HEVC
+func (v *videoTracker) hevcUnit(nal []byte) {
+	if len(nal) < 2 {
+		return
+	}
+	nalType := int(nal[0] >> 1 & 0x3F)
+	switch {
+	case nalType == hevcNALSPS:
+		if sps, ok := parseHEVCSPS(unescapeRBSP(nal[2:])); ok {
+			v.hevc = sps
+			v.setInfo(sps.info)
+		}
+	case nalType == hevcNALPPS:
+		r := &bitReader{data: unescapeRBSP(nal[2:])}
+		id := r.ue()
+		r.ue()    // pps_seq_parameter_set_id
+		r.skip(2) // dependent_slice_segments_enabled_flag, output_flag_present_flag
+		pps := hevcPPS{extraSliceHeaderBits: int(r.u(3))}
+		if !r.exhausted() {
+			v.hevcPPS[id] = pps
+		}
+	case nalType <= hevcNALVCLLast:
+		if v.hevc == nil {
+			return
+		}
+		r := &bitReader{data: unescapeRBSP(nal[2:])}
+		if !r.flag() {
+			return // first_slice_segment_in_pic_flag: не первый срез картинки
+		}
+		irap := nalType >= hevcNALIRAPFirst && nalType <= 23
+		if irap {
+			r.skip(1) // no_output_of_prior_pics_flag
+		}
+		pps := v.hevcPPS[r.ue()]
+		r.skip(pps.extraSliceHeaderBits)
+		sliceType := r.ue()
+		if r.exhausted() {
+			return
+		}
+		fields := 2
+		if v.hevc.fieldSeq {
+			fields = 1
+		}
+		// IRAP (IDR, CRA, BLA) — точка входа в поток, аналог IDR H.264
+		v.picture(fields, sliceType == 2, nalType >= hevcNALIRAPFirst && nalType <= hevcNALIRAPLast)
+	}
+}
+
+// parseHEVCSPS разбирает seq_parameter_set_rbsp (7.3.2.2) без nal_unit_header
+func parseHEVCSPS(rbsp []byte) (*hevcSPS, bool) {
+	r := &bitReader{data: rbsp}
+	sps := &hevcSPS{}
+
+	r.skip(4) // sps_video_parameter_set_id
+	maxSubLayers := int(r.u(3))
+	r.skip(1) // sps_temporal_id_nesting_flag
+
+	// profile_tier_level(1, sps_max_sub_layers_minus1)
+	r.skip(3) // general_profile_space, general_tier_flag
+	profileIDC := r.u(5)
+	r.skip(32) // general_profile_compatibility_flag
+	progressive := r.flag()
+	interlaced := r.flag()
+	r.skip(2 + 43 + 1)
+	levelIDC := r.u(8)
+	profilePresent := make([]bool, maxSubLayers)
+	levelPresent := make([]bool, maxSubLayers)
+	for i := 0; i < maxSubLayers; i++ {
+		profilePresent[i] = r.flag()
+		levelPresent[i] = r.flag()
+	}
+	if maxSubLayers > 0 {
+		r.skip(2 * (8 - maxSubLayers))
+	}
+	for i := 0; i < maxSubLayers; i++ {
+		if profilePresent[i] {
+			r.skip(88)
+		}
+		if levelPresent[i] {
+			r.skip(8)
+		}
+	}
+
+	sps.info.Profile = hevcProfiles[profileIDC]
+	if sps.info.Profile == "" {
+		sps.info.Profile = fmt.Sprintf("profile_%d", profileIDC)
+	}
+	// general_level_idc = 30 × уровень
+	sps.info.Level = fmt.Sprintf("%d.%d", levelIDC/30, levelIDC%30/3)
+	sps.info.Interlaced = interlaced && !progressive
+
+	r.ue() // sps_seq_parameter_set_id
+	chromaFormat := r.ue()
+	if chromaFormat == 3 {
+		r.skip(1) // separate_colour_plane_flag
+	}
+	width := int(r.ue())
+	height := int(r.ue())
+	if r.flag() { // conformance_window_flag
+		subWidth, subHeight := 1, 1
+		switch chromaFormat {
+		case 1:
+			subWidth, subHeight = 2, 2
+		case 2:
+			subWidth = 2
+		}
+		left, right := int(r.ue()), int(r.ue())
+		top, bottom := int(r.ue()), int(r.ue())
+		width -= subWidth * (left + right)
+		height -= subHeight * (top + bottom)
+	}
+	if r.exhausted() || width <= 0 || height <= 0 {
+		return nil, false
+	}
+	sps.info.Width = width
+	sps.info.Height = height
+	sps.info.Aspect = displayAspect(width, height, 1, 1)
+
+	// До VUI много полей переменной длины; если разбор не дойдёт до VUI,
+	// размер и профиль всё равно известны, а частота кадров будет измерена по PTS
+	r.ue() // bit_depth_luma_minus8
+	r.ue() // bit_depth_chroma_minus8
+	log2MaxPOCLsb := int(r.ue()) + 4
+	first := maxSubLayers
+	if r.flag() { // sps_sub_layer_ordering_info_present_flag
+		first = 0
+	}
+	for i := first; i <= maxSubLayers; i++ {
+		r.ue()
+		r.ue()
+		r.ue()
+	}
+	for i := 0; i < 6; i++ { // размеры блоков и глубина дерева преобразований
+		r.ue()
+	}
+	if r.flag() && r.flag() { // scaling_list_enabled_flag, sps_scaling_list_data_present_flag
+		skipHEVCScalingListData(r)
+	}
+	r.skip(2)     // amp_enabled_flag, sample_adaptive_offset_enabled_flag
+	if r.flag() { // pcm_enabled_flag
+		r.skip(8)
+		r.ue()
+		r.ue()
+		r.skip(1)
+	}
+	if !skipShortTermRefPicSets(r, int(r.ue())) {
+		return sps, true
+	}
+	if r.flag() { // long_term_ref_pics_present_flag
+		count := r.ue()
+		for i := uint32(0); i < count && !r.exhausted(); i++ {
+			r.skip(log2MaxPOCLsb + 1)
+		}
+	}
+	r.skip(2)                       // sps_temporal_mvp_enabled_flag, strong_intra_smoothing_enabled_flag
+	if r.exhausted() || !r.flag() { // vui_parameters_present_flag
+		return sps, true
+	}
+
+	vui := *sps
+	if r.flag() { // aspect_ratio_info_present_flag
+		sarW, sarH := readSAR(r)
+		if aspect := displayAspect(width, height, sarW, sarH); aspect != "" {
+			vui.info.Aspect = aspect
+		}
+	}
+	if r.flag() { // overscan_info_present_flag
+		r.skip(1)
+	}
+	if r.flag() { // video_signal_type_present_flag
+		r.skip(4)
+		if r.flag() {
+			r.skip(24)
+		}
+	}
+	if r.flag() { // chroma_loc_info_present_flag
+		r.ue()
+		r.ue()
+	}
+	r.skip(1) // neutral_chroma_indication_flag
+	vui.fieldSeq = r.flag()
+	if vui.fieldSeq {
+		vui.info.Interlaced = true
+	}
+	r.skip(1)     // frame_field_info_present_flag
+	if r.flag() { // default_display_window_flag
+		r.ue()
+		r.ue()
+		r.ue()
+		r.ue()
+	}
+	if r.flag() { // vui_timing_info_present_flag
+		numUnits := r.u(32)
+		timeScale := r.u(32)
+		// Тик — одна картинка; при field_seq_flag картинка — поле
+		den := uint64(numUnits)
+		if vui.fieldSeq {
+			den *= 2
+		}
+		vui.info.FrameRate = frameRate(uint64(timeScale), den)
+	}
+	if r.exhausted() {
+		return sps, true
+	}
+	return &vui, true
+}
+
+// skipHEVCScalingListData пропускает scaling_list_data (7.3.4)
+func skipHEVCScalingListData(r *bitReader) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if !r.flag() { // scaling_list_pred_mode_flag
+				r.ue() // scaling_list_pred_matrix_id_delta
+				continue
+			}
+			coefs := min(64, 1<<(4+sizeID<<1))
+			if sizeID > 1 {
+				r.se() // scaling_list_dc_coef_minus8
+			}
+			for i := 0; i < coefs && !r.exhausted(); i++ {
+				r.se()
+			}
+		}
+	}
+}
+
+// skipShortTermRefPicSets пропускает num_short_term_ref_pic_sets наборов
+// st_ref_pic_set (7.3.7). Для предсказанных наборов нужно число картинок
+// опорного набора, поэтому дельты POC каждого набора восстанавливаются (7.4.8)
+func skipShortTermRefPicSets(r *bitReader, count int) bool {
+	type refPicSet struct{ negative, positive []int32 }
+	sets := make([]refPicSet, 0, count)
+
+	for idx := 0; idx < count; idx++ {
+		if r.exhausted() || count > 64 {
+			return false
+		}
+		var set refPicSet
+		if idx != 0 && r.flag() { // inter_ref_pic_set_prediction_flag
+			ref := sets[idx-1]
+			sign := r.flag()
+			deltaRps := int32(r.ue()) + 1
+			if sign {
+				deltaRps = -deltaRps
+			}
+			refDeltas := append(append([]int32{}, ref.negative...), ref.positive...)
+			// Для каждой картинки опорного набора и для самой опорной картинки (dPoc = deltaRps)
+			for j := 0; j <= len(refDeltas); j++ {
+				used := r.flag()
+				useDelta := used
+				if !used {
+					useDelta = r.flag()
+				}
+				if !useDelta {
+					continue
+				}
+				dPoc := deltaRps
+				if j < len(refDeltas) {
+					dPoc += refDeltas[j]
+				}
+				switch {
+				case dPoc < 0:
+					set.negative = append(set.negative, dPoc)
+				case dPoc > 0:
+					set.positive = append(set.positive, dPoc)
+				}
+			}
+		} else {
+			negative := r.ue()
+			positive := r.ue()
+			if negative > 16 || positive > 16 {
+				return false
+			}
+			poc := int32(0)
+			for i := uint32(0); i < negative; i++ {
+				poc -= int32(r.ue()) + 1
+				r.skip(1) // used_by_curr_pic_s0_flag
+				set.negative = append(set.negative, poc)
+			}
+			poc = 0
+			for i := uint32(0); i < positive; i++ {
+				poc += int32(r.ue()) + 1
+				r.skip(1) // used_by_curr_pic_s1_flag
+				set.positive = append(set.positive, poc)
+			}
+		}
+		sets = append(sets, set)
+	}
+	return !r.exhausted()
+}
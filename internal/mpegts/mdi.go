@@ -0,0 +1,94 @@
+package mpegts
+
+import "time"
+
+// MDIStats Media Delivery Index (RFC 4445) за интервал измерения
+type MDIStats struct {
+	Interval time.Duration // длительность интервала
+	DF       time.Duration // delay factor: размах виртуального буфера при средней скорости потока
+	MLR      float64       // media loss rate: потерянных TS пакетов в секунду
+	Lost     int64         // потерянных TS пакетов за интервал
+}
+
+// mdiArrival приход одного куска потока
+type mdiArrival struct {
+	offset time.Duration // от начала интервала
+	bytes  int
+}
+
+// mdiTracker считает MDI по времени прихода датаграмм и потерям пакетов.
+// Скорость слива виртуального буфера — средняя скорость потока за интервал
+type mdiTracker struct {
+	interval time.Duration
+	start    time.Time
+	arrivals []mdiArrival
+	ccLost   int64 // потери по continuity counter
+	netLost  int64 // потери в сети (RTP), в TS пакетах
+
+	last *MDIStats // последний завершённый интервал
+}
+
+// newMDITracker создаёт счётчик MDI с интервалом измерения interval
+func newMDITracker(interval time.Duration) *mdiTracker {
+	return &mdiTracker{interval: interval}
+}
+
+// arrival учитывает bytes байт потока, пришедших в момент at
+func (m *mdiTracker) arrival(bytes int, at time.Time) {
+	if m.start.IsZero() {
+		m.start = at
+	}
+	if end := m.start.Add(m.interval); !at.Before(end) {
+		m.last = m.finish()
+		// После перерыва в потоке пустые интервалы не считаем
+		if at.Sub(end) >= m.interval {
+			m.start = at
+		} else {
+			m.start = end
+		}
+	}
+	m.arrivals = append(m.arrivals, mdiArrival{offset: at.Sub(m.start), bytes: bytes})
+}
+
+// finish вычисляет MDI текущего интервала и начинает новый
+func (m *mdiTracker) finish() *MDIStats {
+	stats := &MDIStats{Interval: m.interval, Lost: max(m.ccLost, m.netLost)}
+	stats.MLR = float64(stats.Lost) / m.interval.Seconds()
+
+	var total int
+	for _, a := range m.arrivals {
+		total += a.bytes
+	}
+	if total > 0 {
+		// VB(t) = принято - слито; DF = (VBmax - VBmin) / скорость
+		rate := float64(total) / float64(m.interval) // байт/нс
+		var received, vbMin, vbMax float64
+		for _, a := range m.arrivals {
+			drained := rate * float64(a.offset)
+			vbMin = min(vbMin, received-drained)
+			received += float64(a.bytes)
+			vbMax = max(vbMax, received-drained)
+		}
+		stats.DF = time.Duration((vbMax - vbMin) / rate)
+	}
+
+	m.arrivals = m.arrivals[:0]
+	m.ccLost = 0
+	m.netLost = 0
+	return stats
+}
+
+// SetMDIInterval включает расчёт MDI (RFC 4445) с интервалом измерения interval.
+// Имеет смысл только для приёма в реальном времени: at в Write — время прихода
+func (d *Demuxer) SetMDIInterval(interval time.Duration) {
+	d.mdi = newMDITracker(interval)
+}
+
+// ReportLoss учитывает packets TS пакетов, потерянных до демультиплексора
+// (например, по номерам RTP). Потери по CC и сетевые за интервал не складываются:
+// берётся большее, так как обычно это одни и те же пакеты
+func (d *Demuxer) ReportLoss(packets int64) {
+	if d.mdi != nil {
+		d.mdi.netLost += packets
+	}
+}
@@ -0,0 +1,111 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+// mdiDatagram собирает датаграмму из 7 пакетов PID 0x0100, продолжая счётчик cc
+func mdiDatagram(cc *uint8) []byte {
+	var data []byte
+	for i := 0; i < 7; i++ {
+		data = append(data, payloadPacket(0x0100, *cc)...)
+		*cc = (*cc + 1) & 0x0F
+	}
+	return data
+}
+
+func TestMDIDelayFactor(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset func(i int) time.Duration // время прихода i-й из 100 датаграмм
+		df     time.Duration
+	}{
+		{
+			name:   "even pacing",
+			offset: func(i int) time.Duration { return time.Duration(i) * 10 * time.Millisecond },
+			df:     10 * time.Millisecond, // одна датаграмма в буфере
+		},
+		{
+			name: "bursts of ten",
+			offset: func(i int) time.Duration {
+				return time.Duration(i/10) * 100 * time.Millisecond
+			},
+			df: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDemuxer()
+			d.SetMDIInterval(time.Second)
+			start := time.Unix(1700000000, 0)
+			var cc uint8
+
+			for i := 0; i < 100; i++ {
+				d.Write(mdiDatagram(&cc), start.Add(tt.offset(i)))
+			}
+			if r := d.Flush(start.Add(990 * time.Millisecond)); r.MDI != nil {
+				t.Fatalf("MDI before the interval ended = %+v, want nil", r.MDI)
+			}
+
+			// Первая датаграмма следующего интервала завершает текущий
+			d.Write(mdiDatagram(&cc), start.Add(time.Second))
+			r := d.Flush(start.Add(time.Second))
+			if r.MDI == nil {
+				t.Fatal("MDI = nil, want stats")
+			}
+			if diff := r.MDI.DF - tt.df; diff < -time.Microsecond || diff > time.Microsecond {
+				t.Errorf("DF = %v, want %v", r.MDI.DF, tt.df)
+			}
+			if r.MDI.MLR != 0 || r.MDI.Interval != time.Second {
+				t.Errorf("MDI = %+v, want no loss over 1s", r.MDI)
+			}
+		})
+	}
+}
+
+func TestMDILossRate(t *testing.T) {
+	d := NewDemuxer()
+	d.SetMDIInterval(500 * time.Millisecond)
+	start := time.Unix(1700000000, 0)
+
+	// Разрыв CC 2 -> 6: пропущено 3 пакета
+	for i, cc := range []uint8{0, 1, 2, 6, 7} {
+		d.Write(payloadPacket(0x0100, cc), start.Add(time.Duration(i)*10*time.Millisecond))
+	}
+	d.Write(payloadPacket(0x0100, 8), start.Add(500*time.Millisecond))
+
+	r := d.Flush(start.Add(500 * time.Millisecond))
+	if r.MDI == nil || r.MDI.Lost != 3 || r.MDI.MLR != 6 {
+		t.Fatalf("MDI = %+v, want 3 lost, MLR 6/s", r.MDI)
+	}
+
+	// Сетевые потери и CC не складываются: берётся большее
+	d.ReportLoss(14)
+	d.Write(payloadPacket(0x0100, 12), start.Add(600*time.Millisecond))
+	d.Write(payloadPacket(0x0100, 13), start.Add(time.Second))
+
+	r = d.Flush(start.Add(time.Second))
+	if r.MDI == nil || r.MDI.Lost != 14 || r.MDI.MLR != 28 {
+		t.Errorf("MDI = %+v, want 14 lost, MLR 28/s", r.MDI)
+	}
+}
+
+func TestMDIEmptyWindow(t *testing.T) {
+	d := NewDemuxer()
+	d.SetMDIInterval(500 * time.Millisecond)
+	start := time.Unix(1700000000, 0)
+	var cc uint8
+
+	d.Write(mdiDatagram(&cc), start)
+	d.Write(mdiDatagram(&cc), start.Add(500*time.Millisecond))
+	if r := d.Flush(start.Add(time.Second)); r.MDI == nil {
+		t.Fatal("MDI = nil, want stats")
+	}
+
+	// Поток пропал: прошлый интервал в отчёт не попадает
+	if r := d.Flush(start.Add(2 * time.Second)); r.MDI != nil {
+		t.Errorf("MDI of an empty window = %+v, want nil", r.MDI)
+	}
+}
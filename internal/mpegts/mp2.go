@@ -0,0 +1,280 @@
+package mpegts
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Уровень звука MPEG-1/2 Layer II измеряется без синтезирующего банка фильтров:
+// по деквантованным отсчётам 32 подполос. Банк фильтров Layer II почти ортогонален,
+// поэтому средний квадрат сигнала равен сумме средних квадратов подполос (с точностью
+// около 1 dB), а синусоида с амплитудой A даёт в своей подполосе отсчёты с амплитудой A.
+// Пик — наибольший отсчёт подполосы: для тональных сигналов совпадает с пиком
+// сигнала, для широкополосных и переходных занижает его
+
+// AudioLevel уровень звука за окно и состояние тишины
+type AudioLevel struct {
+	RMS     float64       // dBFS, громкий канал; синусоида полной шкалы — -3 dBFS
+	Peak    float64       // dBFS
+	Silent  time.Duration // сколько звука подряд тише порога (по длительности кадров)
+	Silence bool          // Silent не меньше заданной длительности
+}
+
+// Значения по умолчанию для детектора тишины
+const (
+	DefaultSilenceLevel    = -60.0 // dBFS
+	DefaultSilenceDuration = 10 * time.Second
+)
+
+// minAudioLevel уровень цифровой тишины: log10(0) не представим в JSON
+const minAudioLevel = -120.0
+
+// mp2Slots отсчётов каждой подполосы в кадре Layer II
+const mp2Slots = 36
+
+// mp2Class подполосы с одинаковой длиной индекса распределения и таблицей квантования
+type mp2Class struct {
+	count  int
+	nbal   int
+	levels []int // число уровней квантования по индексу распределения - 1
+}
+
+// Таблицы квантования подполос (ISO/IEC 11172-3 B.2, 13818-3 B.1)
+var (
+	mp2LevelsA0 = []int{3, 7, 15, 31, 63, 127, 255, 511, 1023, 2047, 4095, 8191, 16383, 32767, 65535}
+	mp2LevelsA1 = []int{3, 5, 7, 9, 15, 31, 63, 127, 255, 511, 1023, 2047, 4095, 8191, 65535}
+	mp2LevelsA2 = []int{3, 5, 7, 9, 15, 31, 65535}
+	mp2LevelsA3 = []int{3, 5, 65535}
+	mp2LevelsC0 = []int{3, 5, 9, 15, 31, 63, 127, 255, 511, 1023, 2047, 4095, 8191, 16383, 32767}
+	mp2LevelsC1 = []int{3, 5, 9, 15, 31, 63, 127}
+	mp2LevelsL0 = []int{3, 5, 7, 9, 15, 31, 63, 127, 255, 511, 1023, 2047, 4095, 8191, 16383}
+	mp2LevelsL1 = []int{3, 5, 9, 15, 31, 63, 127}
+	mp2LevelsL2 = []int{3, 5, 9}
+)
+
+// mp2AllocTables таблицы распределения бит: B.2a, B.2b, B.2c, B.2d и MPEG-2 LSF
+var mp2AllocTables = [5][]mp2Class{
+	{{3, 4, mp2LevelsA0}, {8, 4, mp2LevelsA1}, {12, 3, mp2LevelsA2}, {4, 2, mp2LevelsA3}},
+	{{3, 4, mp2LevelsA0}, {8, 4, mp2LevelsA1}, {12, 3, mp2LevelsA2}, {7, 2, mp2LevelsA3}},
+	{{2, 4, mp2LevelsC0}, {6, 3, mp2LevelsC1}},
+	{{2, 4, mp2LevelsC0}, {10, 3, mp2LevelsC1}},
+	{{4, 4, mp2LevelsL0}, {7, 3, mp2LevelsL1}, {19, 2, mp2LevelsL2}},
+}
+
+// mp2GroupBits длина кода трёх сгруппированных отсчётов по числу уровней
+var mp2GroupBits = map[int]int{3: 5, 5: 7, 9: 10}
+
+// mp2Scalefactors множители по индексу scalefactor: 2^(1 - index/3)
+var mp2Scalefactors = func() (table [64]float64) {
+	for i := range table {
+		table[i] = math.Exp2(1 - float64(i)/3)
+	}
+	return table
+}()
+
+// mp2Table выбирает таблицу распределения по частоте и битрейту на канал (11172-3 B.2)
+func mp2Table(h mpegAudioHeader, channels int) []mp2Class {
+	if h.version != 1 {
+		return mp2AllocTables[4]
+	}
+	perChannel := h.bitrate / 1000 / channels
+	switch {
+	case perChannel >= 56 && (h.sampleRate == 48000 || perChannel <= 80):
+		return mp2AllocTables[0]
+	case h.sampleRate != 48000 && perChannel >= 96:
+		return mp2AllocTables[1]
+	case h.sampleRate != 32000 && perChannel <= 48:
+		return mp2AllocTables[2]
+	}
+	return mp2AllocTables[3]
+}
+
+// mp2Energy деквантует отсчёты подполос кадра Layer II и возвращает по каналам
+// сумму квадратов и наибольший модуль отсчёта
+func mp2Energy(h mpegAudioHeader, frame []byte) (sumSq, peak [2]float64, channels int) {
+	channels = 2
+	if h.mode == 3 {
+		channels = 1
+	}
+	table := mp2Table(h, channels)
+	r := &bitReader{data: frame[4:]}
+	if h.protection {
+		r.skip(16) // CRC
+	}
+
+	sblimit := 0
+	for _, class := range table {
+		sblimit += class.count
+	}
+	bound := sblimit
+	if h.mode == 1 {
+		bound = min(4*(h.modeExt+1), sblimit) // joint stereo: выше bound подполосы общие
+	}
+
+	// Число уровней квантования подполос, 0 — подполоса не передаётся
+	var quant [2][32]int
+	sb := 0
+	for _, class := range table {
+		for i := 0; i < class.count; i, sb = i+1, sb+1 {
+			for ch := 0; ch < channels; ch++ {
+				if ch > 0 && sb >= bound {
+					quant[ch][sb] = quant[0][sb]
+					break
+				}
+				if index := r.u(class.nbal); index > 0 {
+					quant[ch][sb] = class.levels[index-1]
+				}
+			}
+		}
+	}
+
+	var scfsi [2][32]uint32
+	for sb := 0; sb < sblimit; sb++ {
+		for ch := 0; ch < channels; ch++ {
+			if quant[ch][sb] != 0 {
+				scfsi[ch][sb] = r.u(2)
+			}
+		}
+	}
+
+	// Множители трёх частей кадра по 12 отсчётов
+	var scale [2][32][3]float64
+	for sb := 0; sb < sblimit; sb++ {
+		for ch := 0; ch < channels; ch++ {
+			if quant[ch][sb] == 0 {
+				continue
+			}
+			s := &scale[ch][sb]
+			switch scfsi[ch][sb] {
+			case 0:
+				s[0], s[1], s[2] = mp2Scalefactors[r.u(6)], mp2Scalefactors[r.u(6)], mp2Scalefactors[r.u(6)]
+			case 1:
+				s[0] = mp2Scalefactors[r.u(6)]
+				s[1], s[2] = s[0], mp2Scalefactors[r.u(6)]
+			case 2:
+				s[0] = mp2Scalefactors[r.u(6)]
+				s[1], s[2] = s[0], s[0]
+			case 3:
+				s[0], s[1] = mp2Scalefactors[r.u(6)], mp2Scalefactors[r.u(6)]
+				s[2] = s[1]
+			}
+		}
+	}
+
+	for gr := 0; gr < mp2Slots/3; gr++ {
+		part := gr / 4
+		for sb := 0; sb < sblimit; sb++ {
+			var samples [3]float64
+			for ch := 0; ch < channels; ch++ {
+				if quant[ch][sb] == 0 {
+					continue
+				}
+				// Выше bound отсчёты общие, а множители у каналов свои
+				if ch == 0 || sb < bound {
+					samples = mp2Samples(r, quant[ch][sb])
+				}
+				for _, s := range samples {
+					v := s * scale[ch][sb][part]
+					sumSq[ch] += v * v
+					peak[ch] = max(peak[ch], math.Abs(v))
+				}
+			}
+		}
+	}
+	return sumSq, peak, channels
+}
+
+// mp2Samples читает три отсчёта подполосы с levels уровнями квантования
+// и возвращает их в диапазоне (-1, 1) до умножения на scalefactor
+func mp2Samples(r *bitReader, levels int) [3]float64 {
+	var codes [3]int
+	if n, grouped := mp2GroupBits[levels]; grouped {
+		code := int(r.u(n))
+		for i := range codes {
+			codes[i] = code % levels
+			code /= levels
+		}
+	} else {
+		n := bits.Len(uint(levels))
+		for i := range codes {
+			codes[i] = int(r.u(n))
+		}
+	}
+
+	var samples [3]float64
+	for i, code := range codes {
+		samples[i] = float64(2*code-(levels-1)) / float64(levels)
+	}
+	return samples
+}
+
+// levelMeter измеряет уровень кадров Layer II одного PID и длительность тишины
+type levelMeter struct {
+	threshold float64 // dBFS: кадр тише во всех каналах считается тишиной
+	duration  time.Duration
+
+	sumSq  [2]float64 // за окно
+	slots  int
+	peak   float64
+	frames bool // в окне был разобран кадр
+	silent time.Duration
+}
+
+// newLevelMeter создаёт levelMeter с порогом тишины threshold dBFS и длительностью duration
+func newLevelMeter(threshold float64, duration time.Duration) *levelMeter {
+	return &levelMeter{threshold: threshold, duration: duration}
+}
+
+// frame учитывает кадр MPEG audio; кадры Layer I и III пропускаются
+func (m *levelMeter) frame(data []byte) {
+	h, ok := parseMPEGAudio(data)
+	if !ok || h.layer != 2 {
+		return
+	}
+	sumSq, peak, channels := mp2Energy(h, data)
+
+	loud := false
+	for ch := 0; ch < channels; ch++ {
+		m.sumSq[ch] += sumSq[ch]
+		m.peak = max(m.peak, peak[ch])
+		if powerDBFS(sumSq[ch]/mp2Slots) > m.threshold {
+			loud = true
+		}
+	}
+	m.slots += mp2Slots
+	m.frames = true
+
+	if loud {
+		m.silent = 0
+	} else {
+		m.silent += time.Duration(h.samples()) * time.Second / time.Duration(h.sampleRate)
+	}
+}
+
+// report возвращает уровень за окно и начинает новое; nil, если в окне не было кадров Layer II
+func (m *levelMeter) report() *AudioLevel {
+	if !m.frames {
+		return nil
+	}
+	level := &AudioLevel{
+		RMS:     minAudioLevel,
+		Peak:    max(20*math.Log10(m.peak), minAudioLevel),
+		Silent:  m.silent,
+		Silence: m.silent >= m.duration,
+	}
+	for _, sumSq := range m.sumSq {
+		level.RMS = max(level.RMS, powerDBFS(sumSq/float64(m.slots)))
+	}
+
+	m.sumSq = [2]float64{}
+	m.slots = 0
+	m.peak = 0
+	m.frames = false
+	return level
+}
+
+// powerDBFS переводит средний квадрат отсчёта в dBFS, не ниже minAudioLevel
+func powerDBFS(meanSquare float64) float64 {
+	return max(10*math.Log10(meanSquare), minAudioLevel)
+}
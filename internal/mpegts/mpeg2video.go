@@ -0,0 +1,136 @@
+package mpegts
+
+import (
+	"fmt"
+	"math"
+)
+
+// Start code MPEG-2 video (ISO/IEC 13818-2 таблица 6-1)
+const (
+	mpeg2Picture        = 0x00
+	mpeg2SequenceHeader = 0xB3
+	mpeg2Extension      = 0xB5
+)
+
+// Идентификаторы extension_start_code_identifier
+const (
+	mpeg2SequenceExtension      = 1
+	mpeg2PictureCodingExtension = 8
+)
+
+// mpeg2FrameRates frame_rate_code -> частота кадров (числитель, знаменатель)
+var mpeg2FrameRates = [...][2]uint64{
+	{0, 0}, {24000, 1001}, {24, 1}, {25, 1}, {30000, 1001}, {30, 1}, {50, 1}, {60000, 1001}, {60, 1},
+}
+
+// mpeg2Aspects aspect_ratio_information -> отношение сторон изображения
+var mpeg2Aspects = map[uint32]string{2: "4:3", 3: "16:9", 4: "2.21:1"}
+
+// mpeg2Profiles и mpeg2Levels поля profile_and_level_indication
+var (
+	mpeg2Profiles = map[uint32]string{1: "High", 2: "Spatially Scalable", 3: "SNR Scalable", 4: "Main", 5: "Simple"}
+	mpeg2Levels   = map[uint32]string{4: "High", 6: "High 1440", 8: "Main", 10: "Low"}
+)
+
+// mpeg2Unit разбирает start code unit MPEG-2 video
+func (v *videoTracker) mpeg2Unit(unit []byte) {
+	r := &bitReader{data: unit[1:]}
+	switch unit[0] {
+	case mpeg2SequenceHeader:
+		if info, ok := parseMPEG2SequenceHeader(r); ok {
+			// Профиль и развёртка приходят в sequence_extension сразу за заголовком,
+			// до него остаются прежними
+			if v.info != nil {
+				info.Profile, info.Level, info.Interlaced = v.info.Profile, v.info.Level, v.info.Interlaced
+			}
+			v.setInfo(info)
+		}
+
+	case mpeg2Extension:
+		if v.info == nil {
+			return
+		}
+		switch r.u(4) {
+		case mpeg2SequenceExtension:
+			v.mpeg2SequenceExtension(r)
+		case mpeg2PictureCodingExtension:
+			r.skip(16 + 2) // f_code, intra_dc_precision
+			// picture_structure: 3 — кадр, 1 и 2 — поле
+			if structure := r.u(2); structure != 3 && !r.exhausted() {
+				// Картинка оказалась полем: picture уже засчитал её как кадр
+				v.fields--
+			}
+		}
+
+	case mpeg2Picture:
+		r.skip(10) // temporal_reference
+		codingType := r.u(3)
+		if r.exhausted() {
+			return
+		}
+		// В MPEG-2 с каждого I-кадра можно начать декодирование
+		key := codingType == 1
+		v.picture(2, key, key)
+	}
+}
+
+// parseMPEG2SequenceHeader разбирает sequence_header (6.2.2.1) после start code
+func parseMPEG2SequenceHeader(r *bitReader) (VideoInfo, bool) {
+	var info VideoInfo
+	info.Width = int(r.u(12))
+	info.Height = int(r.u(12))
+	aspect := r.u(4)
+	rateCode := r.u(4)
+	if r.exhausted() || info.Width == 0 || info.Height == 0 {
+		return info, false
+	}
+
+	// aspect_ratio_information 1 — квадратные пиксели, остальные значения задают отношение сторон изображения
+	if aspect == 1 {
+		info.Aspect = displayAspect(info.Width, info.Height, 1, 1)
+	} else {
+		info.Aspect = mpeg2Aspects[aspect]
+	}
+	if int(rateCode) < len(mpeg2FrameRates) {
+		info.FrameRate = frameRate(mpeg2FrameRates[rateCode][0], mpeg2FrameRates[rateCode][1])
+	}
+	return info, true
+}
+
+// mpeg2SequenceExtension разбирает sequence_extension (6.2.2.3) после идентификатора
+func (v *videoTracker) mpeg2SequenceExtension(r *bitReader) {
+	profileLevel := r.u(8)
+	progressive := r.flag()
+	r.skip(2) // chroma_format
+	widthExt := int(r.u(2))
+	heightExt := int(r.u(2))
+	r.skip(12 + 1 + 8 + 1) // bit_rate_extension, marker_bit, vbv_buffer_size_extension, low_delay
+	rateN := uint64(r.u(2))
+	rateD := uint64(r.u(5))
+	if r.exhausted() {
+		return
+	}
+
+	info := *v.info
+	info.Width = info.Width&0xFFF | widthExt<<12
+	info.Height = info.Height&0xFFF | heightExt<<12
+	info.Interlaced = !progressive
+	if rateN != 0 || rateD != 0 {
+		info.FrameRate = math.Round(info.FrameRate*float64(rateN+1)/float64(rateD+1)*100) / 100
+	}
+
+	// Старший бит — escape: из таких значений описаны только профили 4:2:2 (8.2)
+	switch {
+	case profileLevel == 0x85:
+		info.Profile, info.Level = "4:2:2", "Main"
+	case profileLevel == 0x82:
+		info.Profile, info.Level = "4:2:2", "High"
+	case profileLevel&0x80 == 0:
+		info.Profile = mpeg2Profiles[profileLevel>>4&0x07]
+		info.Level = mpeg2Levels[profileLevel&0x0F]
+	}
+	if info.Profile == "" {
+		info.Profile = fmt.Sprintf("profile_level_0x%02X", profileLevel)
+	}
+	v.setInfo(info)
+}
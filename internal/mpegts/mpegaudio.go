@@ -0,0 +1,124 @@
+package mpegts
+
+import "fmt"
+
+// mpegAudioBitrates битрейт в кбит/с по bitrate_index (ISO/IEC 11172-3 2.4.2.3, 13818-3 2.4.2.3):
+// MPEG-1 Layer I, II, III и MPEG-2/2.5 Layer I, Layer II и III
+var mpegAudioBitrates = [5][16]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+// mpegAudioSampleRates частота дискретизации MPEG-1 по sampling_frequency
+var mpegAudioSampleRates = [3]int{44100, 48000, 32000}
+
+// mpegAudioModes раскладка по полю mode
+var mpegAudioModes = [4]string{"stereo", "joint stereo", "dual mono", "mono"}
+
+// mpegAudioHeader заголовок кадра MPEG audio и поля, нужные декодеру Layer II
+type mpegAudioHeader struct {
+	version    int // 1 — MPEG-1, 2 — MPEG-2 LSF, 25 — MPEG-2.5
+	layer      int
+	protection bool // после заголовка идёт CRC
+	bitrate    int  // бит/с
+	sampleRate int
+	padding    bool
+	mode       int
+	modeExt    int
+}
+
+// parseMPEGAudio разбирает 4-байтовый заголовок кадра MPEG audio
+func parseMPEGAudio(data []byte) (mpegAudioHeader, bool) {
+	var h mpegAudioHeader
+	if len(data) < 4 || data[0] != 0xFF || data[1]&0xE0 != 0xE0 {
+		return h, false
+	}
+
+	version := data[1] >> 3 & 0x03
+	layer := data[1] >> 1 & 0x03
+	bitrateIndex := data[2] >> 4
+	rateIndex := data[2] >> 2 & 0x03
+	if version == 1 || layer == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return h, false // зарезервированные значения и free format
+	}
+
+	h.layer = int(4 - layer)
+	h.protection = data[1]&0x01 == 0
+	h.padding = data[2]&0x02 != 0
+	h.mode = int(data[3] >> 6)
+	h.modeExt = int(data[3] >> 4 & 0x03)
+	h.sampleRate = mpegAudioSampleRates[rateIndex]
+
+	table := h.layer - 1
+	switch version {
+	case 3:
+		h.version = 1
+	case 2:
+		h.version = 2
+		h.sampleRate /= 2
+	case 0:
+		h.version = 25
+		h.sampleRate /= 4
+	}
+	if h.version != 1 {
+		table = 3
+		if h.layer > 1 {
+			table = 4
+		}
+	}
+	h.bitrate = mpegAudioBitrates[table][bitrateIndex] * 1000
+	return h, true
+}
+
+// samples возвращает число отсчётов на канал в кадре
+func (h mpegAudioHeader) samples() int {
+	switch {
+	case h.layer == 1:
+		return 384
+	case h.layer == 3 && h.version != 1:
+		return 576
+	}
+	return 1152
+}
+
+// size возвращает длину кадра в байтах
+func (h mpegAudioHeader) size() int {
+	padding := 0
+	if h.padding {
+		padding = 1
+	}
+	if h.layer == 1 {
+		return (12*h.bitrate/h.sampleRate + padding) * 4
+	}
+	return h.samples()/8*h.bitrate/h.sampleRate + padding
+}
+
+// parseMPEGAudioHeader разбирает кадр MPEG-1/2 audio
+func parseMPEGAudioHeader(data []byte) (audioFrame, bool) {
+	h, ok := parseMPEGAudio(data)
+	if !ok {
+		return audioFrame{}, false
+	}
+
+	layers := [...]string{"", "I", "II", "III"}
+	versions := map[int]string{1: "MPEG-1", 2: "MPEG-2", 25: "MPEG-2.5"}
+	channels := 2
+	if h.mode == 3 {
+		channels = 1
+	}
+	return audioFrame{
+		info: AudioInfo{
+			Format:     fmt.Sprintf("%s Layer %s", versions[h.version], layers[h.layer]),
+			SampleRate: h.sampleRate,
+			Channels:   channels,
+			Layout:     mpegAudioModes[h.mode],
+			Bitrate:    int64(h.bitrate),
+		},
+		size:    h.size(),
+		samples: h.samples(),
+		primary: true,
+	}, true
+}
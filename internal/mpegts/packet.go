@@ -0,0 +1,110 @@
+package mpegts
+
+import (
+	"errors"
+)
+
+// Константы транспортного потока ISO/IEC 13818-1
+const (
+	PacketSize = 188
+	SyncByte   = 0x47
+
+	PATPID  = 0x0000
+	CATPID  = 0x0001
+	NITPID  = 0x0010
+	SDTPID  = 0x0011
+	EITPID  = 0x0012
+	NullPID = 0x1FFF
+)
+
+// Ошибки разбора пакетов
+var (
+	ErrShortPacket = errors.New("packet is shorter than 188 bytes")
+	ErrSyncByte    = errors.New("sync byte 0x47 not found")
+)
+
+// Header содержит поля заголовка TS пакета
+type Header struct {
+	TEI           bool // transport_error_indicator
+	PUSI          bool // payload_unit_start_indicator
+	Priority      bool // transport_priority
+	PID           uint16
+	Scrambling    uint8 // transport_scrambling_control
+	HasAdaptation bool
+	HasPayload    bool
+	CC            uint8 // continuity_counter
+}
+
+// ParseHeader разбирает 4-байтовый заголовок TS пакета
+func ParseHeader(pkt []byte) (Header, error) {
+	if len(pkt) < PacketSize {
+		return Header{}, ErrShortPacket
+	}
+	if pkt[0] != SyncByte {
+		return Header{}, ErrSyncByte
+	}
+
+	return Header{
+		TEI:           pkt[1]&0x80 != 0,
+		PUSI:          pkt[1]&0x40 != 0,
+		Priority:      pkt[1]&0x20 != 0,
+		PID:           uint16(pkt[1]&0x1F)<<8 | uint16(pkt[2]),
+		Scrambling:    pkt[3] >> 6,
+		HasAdaptation: pkt[3]&0x20 != 0,
+		HasPayload:    pkt[3]&0x10 != 0,
+		CC:            pkt[3] & 0x0F,
+	}, nil
+}
+
+// AdaptationField возвращает adaptation field без байта длины (nil если его нет)
+func AdaptationField(pkt []byte, h Header) []byte {
+	if !h.HasAdaptation {
+		return nil
+	}
+	length := int(pkt[4])
+	if length == 0 || 5+length > PacketSize {
+		return nil
+	}
+	return pkt[5 : 5+length]
+}
+
+// Payload возвращает полезную нагрузку пакета (nil если её нет)
+func Payload(pkt []byte, h Header) []byte {
+	if !h.HasPayload {
+		return nil
+	}
+	offset := 4
+	if h.HasAdaptation {
+		offset += 1 + int(pkt[4])
+	}
+	if offset >= PacketSize {
+		return nil
+	}
+	return pkt[offset:PacketSize]
+}
+
+// Discontinuity возвращает discontinuity_indicator из adaptation field
+func Discontinuity(pkt []byte, h Header) bool {
+	af := AdaptationField(pkt, h)
+	return len(af) > 0 && af[0]&0x80 != 0
+}
+
+// RandomAccess возвращает random_access_indicator из adaptation field
+func RandomAccess(pkt []byte, h Header) bool {
+	af := AdaptationField(pkt, h)
+	return len(af) > 0 && af[0]&0x40 != 0
+}
+
+// PCR извлекает program_clock_reference в единицах 27 МГц
+func PCR(pkt []byte, h Header) (uint64, bool) {
+	af := AdaptationField(pkt, h)
+	if len(af) < 7 || af[0]&0x10 == 0 {
+		return 0, false
+	}
+
+	base := uint64(af[1])<<25 | uint64(af[2])<<17 | uint64(af[3])<<9 |
+		uint64(af[4])<<1 | uint64(af[5])>>7
+	ext := uint64(af[5]&0x01)<<8 | uint64(af[6])
+
+	return base*300 + ext, true
+}
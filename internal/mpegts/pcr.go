@@ -0,0 +1,235 @@
+package mpegts
+
+import (
+	"math"
+	"time"
+)
+
+// Константы системных часов 27 МГц
+const (
+	pcrClock = 27000000
+	pcrWrap  = uint64(1<<33) * 300
+)
+
+// pcrDelta возвращает разницу b-a по модулю переполнения PCR
+func pcrDelta(a, b uint64) uint64 {
+	return (b + pcrWrap - a) % pcrWrap
+}
+
+// pcrTicks переводит длительность в такты 27 МГц
+func pcrTicks(d time.Duration) uint64 {
+	return uint64(d) * pcrClock / uint64(time.Second)
+}
+
+// pcrDuration переводит такты 27 МГц в длительность
+func pcrDuration(ticks float64) time.Duration {
+	return time.Duration(ticks * float64(time.Second) / pcrClock)
+}
+
+// PCRStats статистика PCR одного PID за окно
+type PCRStats struct {
+	Count       int             // PCR за окно
+	Intervals   []time.Duration // интервалы между соседними PCR
+	IntervalMax time.Duration
+	IntervalAvg time.Duration
+	Jitter      []time.Duration // отклонение PCR от времени прихода (PCR overall jitter)
+	JitterMax   time.Duration   // размах отклонения за окно
+	AccuracyMax time.Duration   // максимальное |PCR_AC|
+	Bitrate     int64           // битрейт TS по PCR (бит/с)
+}
+
+// pcrTracker отслеживает PCR одного PID
+type pcrTracker struct {
+	has         bool
+	last        uint64    // последнее значение PCR
+	lastIndex   uint64    // номер пакета в потоке с последним PCR
+	lastArrival time.Time // время прихода последнего PCR
+
+	// Опорная точка для оценки битрейта TS при расчёте точности PCR
+	hasBase   bool
+	base      uint64
+	baseIndex uint64
+
+	// Опорная точка для сравнения PCR со временем прихода
+	refPCR     uint64
+	refArrival time.Time
+
+	// Статистика окна
+	count       int
+	intervals   []time.Duration
+	intervalSum uint64
+	intervalMax uint64
+	packets     uint64 // пакетов между PCR в окне
+	arrivals    []float64
+	offsets     []float64
+	accuracyMax float64
+}
+
+// pcrSample результат обработки очередного PCR
+type pcrSample struct {
+	interval      uint64 // интервал от предыдущего PCR в тактах 27 МГц
+	discontinuity bool   // скачок PCR без discontinuity_indicator
+	hasAccuracy   bool
+	accuracy      float64 // отклонение от ожидаемого значения в тактах 27 МГц
+}
+
+// Пороги TR 101 290
+var (
+	pcrRepetitionLimit    = pcrTicks(40 * time.Millisecond)
+	pcrDiscontinuityLimit = pcrTicks(100 * time.Millisecond)
+	pcrAccuracyLimit      = 0.0000005 * pcrClock // ±500 нс
+	pcrBaseMin            = pcrTicks(time.Second)
+	pcrBaseMax            = pcrTicks(30 * time.Second)
+)
+
+// update обрабатывает новый PCR в пакете с номером index, пришедшем в момент at.
+// Возвращает false для первого PCR и после разрыва.
+func (t *pcrTracker) update(pcr, index uint64, discontinuity bool, at time.Time) (pcrSample, bool) {
+	t.count++
+
+	if !t.has || discontinuity {
+		t.reset(pcr, index, at)
+		return pcrSample{}, false
+	}
+
+	s := pcrSample{interval: pcrDelta(t.last, pcr)}
+	if s.interval > pcrDiscontinuityLimit {
+		// Скачок вперёд больше 100 мс или назад (переполнение разницы)
+		s.discontinuity = true
+		t.reset(pcr, index, at)
+		return s, true
+	}
+
+	if t.hasBase && index > t.baseIndex {
+		span := pcrDelta(t.base, pcr)
+		if span >= pcrBaseMin {
+			// Ожидаемый PCR при постоянном битрейте TS между опорной точкой и текущим PCR
+			ticksPerPacket := float64(span) / float64(index-t.baseIndex)
+			expected := float64(t.last) + ticksPerPacket*float64(index-t.lastIndex)
+			s.hasAccuracy = true
+			s.accuracy = float64(pcr) - expected
+			if s.accuracy > float64(pcrWrap/2) {
+				s.accuracy -= float64(pcrWrap)
+			} else if s.accuracy < -float64(pcrWrap/2) {
+				s.accuracy += float64(pcrWrap)
+			}
+			t.accuracyMax = math.Max(t.accuracyMax, math.Abs(s.accuracy))
+		}
+		if span > pcrBaseMax {
+			t.base = pcr
+			t.baseIndex = index
+		}
+	}
+
+	t.intervals = append(t.intervals, pcrDuration(float64(s.interval)))
+	t.intervalSum += s.interval
+	if s.interval > t.intervalMax {
+		t.intervalMax = s.interval
+	}
+	t.packets += index - t.lastIndex
+	t.addOffset(pcr, at)
+
+	t.last = pcr
+	t.lastIndex = index
+	t.lastArrival = at
+	return s, true
+}
+
+// reset начинает отслеживание заново с текущего PCR
+func (t *pcrTracker) reset(pcr, index uint64, at time.Time) {
+	t.has = true
+	t.last = pcr
+	t.lastIndex = index
+	t.lastArrival = at
+	t.hasBase = true
+	t.base = pcr
+	t.baseIndex = index
+
+	// Отклонения до разрыва несравнимы с последующими
+	t.refPCR = pcr
+	t.refArrival = at
+	t.arrivals = t.arrivals[:0]
+	t.offsets = t.offsets[:0]
+	t.addOffset(pcr, at)
+}
+
+// addOffset запоминает разницу между временем прихода и временем по PCR
+func (t *pcrTracker) addOffset(pcr uint64, at time.Time) {
+	arrival := at.Sub(t.refArrival).Seconds()
+	clock := float64(pcrDelta(t.refPCR, pcr)) / pcrClock
+	t.arrivals = append(t.arrivals, arrival)
+	t.offsets = append(t.offsets, arrival-clock)
+}
+
+// flush возвращает статистику окна и начинает новое окно
+func (t *pcrTracker) flush() *PCRStats {
+	s := &PCRStats{
+		Count:       t.count,
+		Intervals:   t.intervals,
+		IntervalMax: pcrDuration(float64(t.intervalMax)),
+		AccuracyMax: pcrDuration(t.accuracyMax),
+	}
+
+	if n := len(t.intervals); n > 0 {
+		s.IntervalAvg = pcrDuration(float64(t.intervalSum) / float64(n))
+	}
+	if t.intervalSum > 0 {
+		seconds := float64(t.intervalSum) / pcrClock
+		s.Bitrate = int64(float64(t.packets*PacketSize*8) / seconds)
+	}
+
+	s.Jitter, s.JitterMax = jitter(t.arrivals, t.offsets)
+
+	// Последний PCR становится опорной точкой следующего окна,
+	// чтобы разница значений PCR не росла до переполнения
+	t.refPCR = t.last
+	t.refArrival = t.lastArrival
+
+	t.count = 0
+	t.intervals = nil
+	t.intervalSum = 0
+	t.intervalMax = 0
+	t.packets = 0
+	t.arrivals = t.arrivals[:0]
+	t.offsets = t.offsets[:0]
+	t.accuracyMax = 0
+
+	return s
+}
+
+// jitter убирает линейный дрейф часов отправителя относительно локальных
+// (метод наименьших квадратов) и возвращает отклонения от минимума и их размах
+func jitter(arrivals, offsets []float64) ([]time.Duration, time.Duration) {
+	n := float64(len(offsets))
+	if n < 2 {
+		return nil, 0
+	}
+
+	var sx, sy, sxx, sxy float64
+	for i := range offsets {
+		sx += arrivals[i]
+		sy += offsets[i]
+		sxx += arrivals[i] * arrivals[i]
+		sxy += arrivals[i] * offsets[i]
+	}
+
+	slope := 0.0
+	if d := n*sxx - sx*sx; d != 0 {
+		slope = (n*sxy - sx*sy) / d
+	}
+	intercept := (sy - slope*sx) / n
+
+	residuals := make([]float64, len(offsets))
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := range offsets {
+		residuals[i] = offsets[i] - (intercept + slope*arrivals[i])
+		low = math.Min(low, residuals[i])
+		high = math.Max(high, residuals[i])
+	}
+
+	result := make([]time.Duration, len(residuals))
+	for i, r := range residuals {
+		result[i] = time.Duration((r - low) * float64(time.Second))
+	}
+	return result, time.Duration((high - low) * float64(time.Second))
+}
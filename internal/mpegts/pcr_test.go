@@ -0,0 +1,56 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPCRStats(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+
+	// 50 PCR каждые 20 мс, между ними по 100 пакетов: 101 пакет / 20 мс.
+	// Время прихода каждого пятого PCR запаздывает на 2 мс.
+	const step = 20 * pcrClock / 1000
+	var cc uint8
+	for i := 0; i < 50; i++ {
+		at := start.Add(time.Duration(i) * 20 * time.Millisecond)
+		if i%5 == 2 {
+			at = at.Add(2 * time.Millisecond)
+		}
+		d.FeedPacket(pcrPacket(0x0066, cc, uint64(i)*step, false), at)
+		cc++
+		for j := 0; j < 100; j++ {
+			d.FeedPacket(payloadPacket(0x0066, cc), at)
+			cc++
+		}
+	}
+
+	r := d.Flush(start.Add(time.Second))
+	s := r.PCR[0x0066]
+	if s == nil {
+		t.Fatal("no PCR stats for 0x0066")
+	}
+
+	if s.Count != 50 || len(s.Intervals) != 49 {
+		t.Errorf("Count = %d, intervals = %d, want 50/49", s.Count, len(s.Intervals))
+	}
+	if s.IntervalMax != 20*time.Millisecond || s.IntervalAvg != 20*time.Millisecond {
+		t.Errorf("IntervalMax/Avg = %v/%v, want 20ms", s.IntervalMax, s.IntervalAvg)
+	}
+
+	wantBitrate := int64(101 * PacketSize * 8 * 50)
+	if diff := s.Bitrate - wantBitrate; diff < -1 || diff > 1 {
+		t.Errorf("Bitrate = %d, want %d", s.Bitrate, wantBitrate)
+	}
+
+	if s.JitterMax < 1900*time.Microsecond || s.JitterMax > 2100*time.Microsecond {
+		t.Errorf("JitterMax = %v, want ~2ms", s.JitterMax)
+	}
+	if len(s.Jitter) != 50 {
+		t.Errorf("Jitter samples = %d, want 50", len(s.Jitter))
+	}
+	if s.AccuracyMax != 0 {
+		t.Errorf("AccuracyMax = %v, want 0 for constant bitrate", s.AccuracyMax)
+	}
+}
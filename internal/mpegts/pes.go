@@ -0,0 +1,60 @@
+package mpegts
+
+// PES содержит поля заголовка PES пакета
+type PES struct {
+	StreamID uint8
+	HasPTS   bool
+	PTS      uint64 // 90 кГц
+	HasDTS   bool
+	DTS      uint64 // 90 кГц
+	Payload  []byte // данные после заголовка PES (в пределах одного TS пакета)
+}
+
+// ParsePES разбирает начало PES пакета из payload TS пакета с PUSI
+func ParsePES(payload []byte) (*PES, bool) {
+	if len(payload) < 9 || payload[0] != 0x00 || payload[1] != 0x00 || payload[2] != 0x01 {
+		return nil, false
+	}
+
+	pes := &PES{StreamID: payload[3]}
+
+	// Потоки без расширенного заголовка (padding, private_stream_2 и т.п.)
+	switch pes.StreamID {
+	case 0xBC, 0xBE, 0xBF, 0xF0, 0xF1, 0xF2, 0xF8, 0xFF:
+		pes.Payload = payload[6:]
+		return pes, true
+	}
+
+	headerLength := int(payload[8])
+	if 9+headerLength > len(payload) {
+		return nil, false
+	}
+
+	flags := payload[7] >> 6
+	if flags&0x02 != 0 && headerLength >= 5 {
+		pes.HasPTS = true
+		pes.PTS = parseTimestamp(payload[9:14])
+	}
+	if flags == 0x03 && headerLength >= 10 {
+		pes.HasDTS = true
+		pes.DTS = parseTimestamp(payload[14:19])
+	}
+
+	pes.Payload = payload[9+headerLength:]
+	return pes, true
+}
+
+// parseTimestamp разбирает 33-битную метку PTS/DTS
+func parseTimestamp(b []byte) uint64 {
+	return uint64(b[0]>>1&0x07)<<30 | uint64(b[1])<<22 | uint64(b[2]>>1)<<15 |
+		uint64(b[3])<<7 | uint64(b[4]>>1)
+}
+
+// hasTimestamps проверяет, что поток этого типа обязан нести PTS (видео и аудио)
+func hasTimestamps(streamType uint8) bool {
+	switch streamType {
+	case 0x01, 0x02, 0x03, 0x04, 0x0F, 0x10, 0x11, 0x1B, 0x24, 0x81, 0x87:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package mpegts
+
+// Идентификаторы таблиц PSI/SI
+const (
+	TableIDPAT       = 0x00
+	TableIDCAT       = 0x01
+	TableIDPMT       = 0x02
+	TableIDNITActual = 0x40
+	TableIDSDTActual = 0x42
+)
+
+// crcTable таблица для CRC-32/MPEG-2
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC32 считает CRC-32/MPEG-2; для секции вместе с CRC результат равен 0
+func CRC32(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// Section одна секция PSI/SI
+type Section struct {
+	TableID         uint8
+	SyntaxIndicator bool
+	TableIDExt      uint16 // transport_stream_id, program_number, service_id...
+	Version         uint8
+	CurrentNext     bool
+	Number          uint8
+	LastNumber      uint8
+	Data            []byte // полная секция включая заголовок и CRC
+	CRCValid        bool
+}
+
+// Body возвращает данные секции после расширенного заголовка и без CRC
+func (s *Section) Body() []byte {
+	if !s.SyntaxIndicator {
+		return s.Data[3:]
+	}
+	if len(s.Data) < 12 {
+		return nil
+	}
+	return s.Data[8 : len(s.Data)-4]
+}
+
+// sectionAssembler собирает секции из payload пакетов одного PID
+type sectionAssembler struct {
+	buf     []byte
+	started bool
+	hasCC   bool
+	lastCC  uint8
+}
+
+// push добавляет payload пакета и вызывает emit для каждой собранной секции.
+// При разрыве continuity counter недособранная секция отбрасывается.
+func (a *sectionAssembler) push(payload []byte, h Header, emit func(*Section)) {
+	if a.hasCC && h.CC == a.lastCC {
+		// Дублированный пакет
+		return
+	}
+	if a.hasCC && h.CC != (a.lastCC+1)&0x0F {
+		// Разрыв и в пакете с PUSI: хвост до pointer_field принадлежит не той секции,
+		// что в буфере
+		a.buf = a.buf[:0]
+		a.started = false
+	}
+	a.hasCC = true
+	a.lastCC = h.CC
+
+	if len(payload) == 0 {
+		return
+	}
+
+	if h.PUSI {
+		pointer := int(payload[0])
+		if 1+pointer > len(payload) {
+			a.buf = a.buf[:0]
+			a.started = false
+			return
+		}
+		// Хвост предыдущей секции
+		if a.started {
+			a.buf = append(a.buf, payload[1:1+pointer]...)
+			a.drain(emit)
+		}
+		a.buf = append(a.buf[:0], payload[1+pointer:]...)
+		a.started = true
+		a.drain(emit)
+		return
+	}
+
+	if a.started {
+		a.buf = append(a.buf, payload...)
+		a.drain(emit)
+	}
+}
+
+// drain извлекает все полные секции из буфера
+func (a *sectionAssembler) drain(emit func(*Section)) {
+	for {
+		if len(a.buf) == 0 || a.buf[0] == 0xFF {
+			// Stuffing до конца пакета
+			a.buf = a.buf[:0]
+			a.started = false
+			return
+		}
+		if len(a.buf) < 3 {
+			return
+		}
+
+		length := 3 + (int(a.buf[1]&0x0F)<<8 | int(a.buf[2]))
+		if len(a.buf) < length {
+			return
+		}
+
+		data := make([]byte, length)
+		copy(data, a.buf[:length])
+		a.buf = a.buf[length:]
+
+		if s := parseSection(data); s != nil {
+			emit(s)
+		}
+	}
+}
+
+// parseSection разбирает заголовок секции
+func parseSection(data []byte) *Section {
+	s := &Section{
+		TableID:         data[0],
+		SyntaxIndicator: data[1]&0x80 != 0,
+		Data:            data,
+		CRCValid:        true,
+	}
+
+	if !s.SyntaxIndicator {
+		// TOT единственная короткая секция с CRC
+		if s.TableID == tableIDTOT {
+			s.CRCValid = CRC32(data) == 0
+		}
+		return s
+	}
+	if len(data) < 12 {
+		return nil
+	}
+
+	s.TableIDExt = uint16(data[3])<<8 | uint16(data[4])
+	s.Version = (data[5] >> 1) & 0x1F
+	s.CurrentNext = data[5]&0x01 != 0
+	s.Number = data[6]
+	s.LastNumber = data[7]
+	s.CRCValid = CRC32(data) == 0
+
+	return s
+}
@@ -0,0 +1,47 @@
+package mpegts
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSectionAssemblerCCGap(t *testing.T) {
+	x := buildSection(TableIDPAT, 1, 0, bytes.Repeat([]byte{0x11}, 188)) // 200 байт
+	y := buildSection(TableIDPAT, 2, 0, bytes.Repeat([]byte{0x22}, 188)) // 200 байт
+	z := buildSection(TableIDPAT, 3, 0, bytes.Repeat([]byte{0x33}, 88))  // 100 байт
+
+	payload := func(parts ...[]byte) []byte {
+		p := bytes.Repeat([]byte{0xFF}, PacketSize-4)
+		var data []byte
+		for _, part := range parts {
+			data = append(data, part...)
+		}
+		copy(p, data)
+		return p
+	}
+	packets := []struct {
+		payload []byte
+		cc      uint8
+	}{
+		{payload([]byte{0}, x[:183]), 0},
+		{payload([]byte{17}, x[183:], y[:166]), 1}, // потерян
+		{payload([]byte{34}, y[166:], z), 2},
+	}
+
+	var a sectionAssembler
+	var got []*Section
+	for i, p := range packets {
+		if i == 1 {
+			continue
+		}
+		a.push(p.payload, Header{PUSI: true, CC: p.cc}, func(s *Section) { got = append(got, s) })
+	}
+
+	// Начало x и хвост y вместе дают 217 байт — больше длины x; склеивать их нельзя
+	if len(got) != 1 || !got[0].CRCValid || got[0].TableIDExt != 3 {
+		for _, s := range got {
+			t.Logf("section ext %d, CRC valid %v", s.TableIDExt, s.CRCValid)
+		}
+		t.Fatalf("got %d sections, want only the intact section 3", len(got))
+	}
+}
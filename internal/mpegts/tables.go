@@ -0,0 +1,268 @@
+package mpegts
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Теги дескрипторов
+const (
+	DescriptorCA         = 0x09
+	DescriptorLanguage   = 0x0A
+	DescriptorService    = 0x48
+	DescriptorTeletext   = 0x56
+	DescriptorSubtitling = 0x59
+)
+
+// Descriptor дескриптор PSI/SI
+type Descriptor struct {
+	Tag  uint8
+	Data []byte
+}
+
+// PAT содержит Program Association Table
+type PAT struct {
+	TSID     uint16
+	Version  uint8
+	Programs map[uint16]uint16 // program_number -> PMT PID (0 -> NIT)
+}
+
+// PMT содержит Program Map Table одной программы
+type PMT struct {
+	Program     uint16
+	Version     uint8
+	PCRPID      uint16
+	Descriptors []Descriptor // program_info
+	Streams     []ElementaryStream
+}
+
+// ElementaryStream описывает элементарный поток из PMT
+type ElementaryStream struct {
+	Type        uint8
+	PID         uint16
+	Language    string
+	Subtitling  bool
+	Descriptors []Descriptor
+}
+
+// SDT содержит Service Description Table (actual)
+type SDT struct {
+	TSID     uint16
+	Services []Service
+}
+
+// Service описывает сервис из SDT
+type Service struct {
+	ID       uint16
+	Type     uint8
+	Name     string
+	Provider string
+}
+
+// parseDescriptors разбирает цикл дескрипторов
+func parseDescriptors(data []byte) []Descriptor {
+	var descs []Descriptor
+	for len(data) >= 2 {
+		length := int(data[1])
+		if 2+length > len(data) {
+			break
+		}
+		descs = append(descs, Descriptor{Tag: data[0], Data: data[2 : 2+length]})
+		data = data[2+length:]
+	}
+	return descs
+}
+
+// ParsePAT разбирает секцию PAT
+func ParsePAT(s *Section) *PAT {
+	pat := &PAT{
+		TSID:     s.TableIDExt,
+		Version:  s.Version,
+		Programs: make(map[uint16]uint16),
+	}
+
+	body := s.Body()
+	for i := 0; i+4 <= len(body); i += 4 {
+		program := uint16(body[i])<<8 | uint16(body[i+1])
+		pid := uint16(body[i+2]&0x1F)<<8 | uint16(body[i+3])
+		pat.Programs[program] = pid
+	}
+
+	return pat
+}
+
+// ParsePMT разбирает секцию PMT
+func ParsePMT(s *Section) *PMT {
+	body := s.Body()
+	if len(body) < 4 {
+		return nil
+	}
+
+	pmt := &PMT{
+		Program: s.TableIDExt,
+		Version: s.Version,
+		PCRPID:  uint16(body[0]&0x1F)<<8 | uint16(body[1]),
+	}
+
+	infoLength := int(body[2]&0x0F)<<8 | int(body[3])
+	if 4+infoLength > len(body) {
+		return nil
+	}
+	pmt.Descriptors = parseDescriptors(body[4 : 4+infoLength])
+	pos := 4 + infoLength
+	for pos+5 <= len(body) {
+		es := ElementaryStream{
+			Type: body[pos],
+			PID:  uint16(body[pos+1]&0x1F)<<8 | uint16(body[pos+2]),
+		}
+		esInfoLength := int(body[pos+3]&0x0F)<<8 | int(body[pos+4])
+		pos += 5
+		if pos+esInfoLength > len(body) {
+			break
+		}
+
+		es.Descriptors = parseDescriptors(body[pos : pos+esInfoLength])
+		pos += esInfoLength
+
+		for _, d := range es.Descriptors {
+			switch d.Tag {
+			case DescriptorLanguage:
+				if es.Language == "" && len(d.Data) >= 3 {
+					es.Language = decodeLanguage(d.Data[:3])
+				}
+			case DescriptorSubtitling:
+				es.Subtitling = true
+				if es.Language == "" && len(d.Data) >= 3 {
+					es.Language = decodeLanguage(d.Data[:3])
+				}
+			}
+		}
+
+		pmt.Streams = append(pmt.Streams, es)
+	}
+
+	return pmt
+}
+
+// ParseSDT разбирает секцию SDT
+func ParseSDT(s *Section) *SDT {
+	sdt := &SDT{TSID: s.TableIDExt}
+
+	body := s.Body()
+	if len(body) < 3 {
+		return sdt
+	}
+
+	pos := 3 // original_network_id + reserved
+	for pos+5 <= len(body) {
+		svc := Service{ID: uint16(body[pos])<<8 | uint16(body[pos+1])}
+		loopLength := int(body[pos+3]&0x0F)<<8 | int(body[pos+4])
+		pos += 5
+		if pos+loopLength > len(body) {
+			break
+		}
+
+		for _, d := range parseDescriptors(body[pos : pos+loopLength]) {
+			if d.Tag != DescriptorService || len(d.Data) < 2 {
+				continue
+			}
+			svc.Type = d.Data[0]
+			providerLength := int(d.Data[1])
+			if 2+providerLength >= len(d.Data) {
+				continue
+			}
+			svc.Provider = DecodeString(d.Data[2 : 2+providerLength])
+			nameLength := int(d.Data[2+providerLength])
+			start := 3 + providerLength
+			if start+nameLength <= len(d.Data) {
+				svc.Name = DecodeString(d.Data[start : start+nameLength])
+			}
+		}
+
+		pos += loopLength
+		sdt.Services = append(sdt.Services, svc)
+	}
+
+	return sdt
+}
+
+// caPIDs возвращает PID ECM/EMM из CA дескрипторов
+func caPIDs(descs []Descriptor) []uint16 {
+	var pids []uint16
+	for _, d := range descs {
+		if d.Tag == DescriptorCA && len(d.Data) >= 4 {
+			pids = append(pids, uint16(d.Data[2]&0x1F)<<8|uint16(d.Data[3]))
+		}
+	}
+	return pids
+}
+
+// decodeLanguage декодирует ISO 639 код языка
+func decodeLanguage(code []byte) string {
+	return strings.ToLower(strings.TrimRight(string(code), "\x00 "))
+}
+
+// DecodeString декодирует строку DVB (ETSI EN 300 468, Annex A).
+// Поддерживаются UTF-8, ISO 8859-5 (кириллица) и латиница по умолчанию.
+func DecodeString(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	charset := byte(0)
+	switch {
+	case data[0] == 0x10 && len(data) >= 3:
+		charset = 0x10
+		if data[1] == 0x00 && data[2] == 0x05 {
+			charset = 0x01
+		}
+		data = data[3:]
+	case data[0] == 0x1F && len(data) >= 2:
+		data = data[2:]
+	case data[0] < 0x20:
+		charset = data[0]
+		data = data[1:]
+	}
+
+	if charset == 0x15 && utf8.Valid(data) {
+		return strings.TrimSpace(stripControlCodes([]rune(string(data))))
+	}
+
+	runes := make([]rune, 0, len(data))
+	for _, b := range data {
+		runes = append(runes, decodeByte(b, charset))
+	}
+	return strings.TrimSpace(stripControlCodes(runes))
+}
+
+// decodeByte декодирует один байт однобайтовой кодировки DVB
+func decodeByte(b byte, charset byte) rune {
+	if charset != 0x01 || b < 0xA0 {
+		return rune(b)
+	}
+
+	// ISO 8859-5
+	switch b {
+	case 0xA0:
+		return 0x00A0
+	case 0xAD:
+		return 0x00AD
+	case 0xF0:
+		return 0x2116
+	case 0xFD:
+		return 0x00A7
+	}
+	return rune(b) + 0x0360
+}
+
+// stripControlCodes удаляет управляющие коды DVB 0x80-0x9F
+func stripControlCodes(runes []rune) string {
+	var sb strings.Builder
+	for _, r := range runes {
+		if r >= 0x80 && r <= 0x9F {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
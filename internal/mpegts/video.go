@@ -0,0 +1,284 @@
+package mpegts
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// VideoInfo параметры видео элементарного потока из SPS (H.264, HEVC) или
+// sequence header (MPEG-2) и измеренная структура GOP
+type VideoInfo struct {
+	Width      int
+	Height     int
+	FrameRate  float64 // кадров в секунду; из заголовка или, если его нет, измеренная по PTS
+	Interlaced bool
+	Profile    string
+	Level      string
+	Aspect     string // отношение сторон изображения: 16:9, 4:3 или 1.85:1
+
+	GOPLength   int           // кадров между двумя последними I-кадрами; 0, пока не измерено
+	IDRInterval time.Duration // между двумя последними IDR (IRAP в HEVC, I-кадры MPEG-2) по PTS
+
+	Frozen string // причина подозрения на застывшее изображение (FrozenNoKeyFrames...); пусто — нет
+}
+
+// maxUnitSize сколько байт NAL unit или MPEG-2 start code unit сохраняется для разбора:
+// SPS и заголовков срезов заведомо хватает
+const maxUnitSize = 1024
+
+// maxKeyInterval больший интервал между ключевыми кадрами по PTS считается разрывом
+const maxKeyInterval = time.Minute
+
+// videoCodec видео кодек, заголовки которого разбирает videoTracker
+type videoCodec int
+
+const (
+	codecMPEG2 videoCodec = iota
+	codecH264
+	codecHEVC
+)
+
+// videoCodecOf возвращает разбираемый кодек для stream_type
+func videoCodecOf(streamType uint8) (videoCodec, bool) {
+	switch streamType {
+	case 0x01, 0x02:
+		return codecMPEG2, true
+	case 0x1B:
+		return codecH264, true
+	case 0x24:
+		return codecHEVC, true
+	}
+	return 0, false
+}
+
+// videoTracker находит start code в payload PES одного видео PID, разбирает
+// заголовки последовательности и считает кадры между ключевыми кадрами
+type videoTracker struct {
+	codec videoCodec
+
+	pts     uint64 // PTS текущего PES
+	hasPTS  bool
+	zeros   int // нулевых байт подряд перед текущей позицией
+	inUnit  bool
+	unit    []byte
+	unitPTS uint64
+	unitHas bool
+
+	offset    int64 // байт элементарного потока с начала
+	unitStart int64 // смещение start code текущего unit
+
+	info    *VideoInfo // nil, пока не разобран заголовок последовательности
+	h264    *h264SPS
+	hevc    *hevcSPS
+	hevcPPS map[uint32]hevcPPS
+
+	fields  int // полей (полукадров) с последнего I-кадра
+	keySeen bool
+	keyPTS  uint64
+	keyHas  bool
+	idrPTS  uint64
+	idrHas  bool
+
+	measuredFPS float64 // по длине GOP и PTS, если в заголовке нет частоты кадров
+
+	picBytes int  // байт срезов текущего кадра
+	picKey   bool // текущий кадр — I-кадр
+	picOpen  bool // размер текущего кадра считается с его начала
+	freeze   freezeDetector
+}
+
+// newVideoTracker создаёт videoTracker для кодека codec
+func newVideoTracker(codec videoCodec) *videoTracker {
+	return &videoTracker{codec: codec, hevcPPS: make(map[uint32]hevcPPS)}
+}
+
+// pes начинает новый PES пакет
+func (v *videoTracker) pes(p *PES) {
+	v.pts, v.hasPTS = p.PTS, p.HasPTS
+	v.write(p.Payload)
+}
+
+// discontinuity сбрасывает незаконченный unit и размеры кадров после потери пакетов
+func (v *videoTracker) discontinuity() {
+	v.inUnit = false
+	v.zeros = 0
+	v.picOpen = false
+	v.freeze.reset()
+}
+
+// write разбирает очередной кусок элементарного потока
+func (v *videoTracker) write(data []byte) {
+	for _, b := range data {
+		v.offset++
+		if b == 0 {
+			v.zeros++
+			continue
+		}
+		if v.zeros >= 2 && b == 0x01 {
+			// Нули перед префиксом start code (zero_byte, stuffing) остаются в предыдущем unit:
+			// последний байт sequence_extension MPEG-2 часто нулевой
+			v.appendZeros(v.zeros - 2)
+			start := v.offset - 3
+			v.endUnit(int(start - v.unitStart))
+			v.unitStart = start
+			v.inUnit = true
+			v.unit = v.unit[:0]
+			v.unitPTS, v.unitHas = v.pts, v.hasPTS
+			v.zeros = 0
+			continue
+		}
+		v.appendZeros(v.zeros)
+		v.zeros = 0
+		if v.inUnit && len(v.unit) < maxUnitSize {
+			v.unit = append(v.unit, b)
+		}
+	}
+}
+
+// appendZeros добавляет в unit n нулевых байт, отложенных до выяснения, не start code ли это
+func (v *videoTracker) appendZeros(n int) {
+	for ; n > 0 && v.inUnit && len(v.unit) < maxUnitSize; n-- {
+		v.unit = append(v.unit, 0)
+	}
+}
+
+// endUnit разбирает собранный unit; size — его полный размер вместе со start code
+func (v *videoTracker) endUnit(size int) {
+	if !v.inUnit || len(v.unit) == 0 {
+		return
+	}
+	unit := v.unit
+
+	var slice bool
+	switch v.codec {
+	case codecMPEG2:
+		v.mpeg2Unit(unit)
+		slice = unit[0] >= 0x01 && unit[0] <= 0xAF
+	case codecH264:
+		v.h264Unit(unit)
+		slice = unit[0]&0x1F >= h264NALSlice && unit[0]&0x1F <= h264NALSliceIDR
+	case codecHEVC:
+		v.hevcUnit(unit)
+		slice = unit[0]>>1&0x3F <= hevcNALVCLLast
+	}
+	// Размер кадра — только срезы: заголовки и SEI перед кадром относятся к нему, а не
+	// к предыдущему, а filler data CBR кодера выравнивает размеры и скрыл бы застывшую картинку
+	if slice {
+		v.picBytes += size
+	}
+}
+
+// picture учитывает начало кадра (fields = 2) или поля (fields = 1).
+// Ключевой кадр учитывается только на границе кадра: второе поле I-кадра
+// тоже может быть I, но новый GOP не начинает
+func (v *videoTracker) picture(fields int, key, idr bool) {
+	if v.info == nil {
+		return
+	}
+	// Первый срез нового кадра: предыдущий закончился
+	if v.picOpen {
+		v.freeze.picture(v.picBytes, v.picKey)
+	}
+	v.picBytes, v.picKey, v.picOpen = 0, key, true
+
+	frameStart := v.fields%2 == 0
+	if key && frameStart {
+		if v.keySeen {
+			frames := v.fields / 2
+			v.info.GOPLength = frames
+			if span, ok := v.ptsSince(v.keyPTS, v.keyHas); ok && frames > 0 {
+				v.measuredFPS = float64(frames) / span.Seconds()
+			}
+		}
+		v.keySeen = true
+		v.fields = 0
+		v.keyPTS, v.keyHas = v.unitPTS, v.unitHas
+	}
+	if idr && frameStart {
+		if interval, ok := v.ptsSince(v.idrPTS, v.idrHas); ok {
+			v.info.IDRInterval = interval
+		}
+		v.idrPTS, v.idrHas = v.unitPTS, v.unitHas
+	}
+	v.fields += fields
+}
+
+// ptsSince возвращает время от метки prev до PTS текущего unit
+func (v *videoTracker) ptsSince(prev uint64, ok bool) (time.Duration, bool) {
+	if !ok || !v.unitHas {
+		return 0, false
+	}
+	delta := time.Duration((v.unitPTS-prev)&(1<<33-1)) * time.Second / 90000
+	if delta <= 0 || delta > maxKeyInterval {
+		return 0, false
+	}
+	return delta, true
+}
+
+// report возвращает копию параметров потока; nil, пока заголовок не разобран
+func (v *videoTracker) report() *VideoInfo {
+	if v.info == nil {
+		return nil
+	}
+	info := *v.info
+	if info.FrameRate == 0 {
+		info.FrameRate = math.Round(v.measuredFPS*100) / 100
+	}
+	info.Frozen = v.freeze.suspect(info.GOPLength, info.FrameRate)
+	return &info
+}
+
+// setInfo заменяет параметры последовательности, сохраняя измеренный GOP
+func (v *videoTracker) setInfo(info VideoInfo) {
+	if v.info != nil {
+		info.GOPLength = v.info.GOPLength
+		info.IDRInterval = v.info.IDRInterval
+	}
+	v.info = &info
+}
+
+// displayAspect возвращает отношение сторон изображения по размеру и
+// соотношению сторон пикселя. Анаморфное SD (720x576 с SAR 16:11) отличается
+// от точного 16:9 на пару процентов, поэтому близкие значения округляются
+func displayAspect(width, height int, sarW, sarH uint32) string {
+	if width == 0 || height == 0 || sarW == 0 || sarH == 0 {
+		return ""
+	}
+	ratio := float64(width) * float64(sarW) / (float64(height) * float64(sarH))
+	for _, known := range []struct {
+		name  string
+		ratio float64
+	}{{"4:3", 4.0 / 3}, {"16:9", 16.0 / 9}, {"1:1", 1}} {
+		if math.Abs(ratio/known.ratio-1) < 0.03 {
+			return known.name
+		}
+	}
+	return fmt.Sprintf("%.2f:1", ratio)
+}
+
+// frameRate возвращает частоту кадров, округлённую до сотых (29.97, 59.94)
+func frameRate(num, den uint64) float64 {
+	if num == 0 || den == 0 {
+		return 0
+	}
+	return math.Round(float64(num)/float64(den)*100) / 100
+}
+
+// sarTable соотношения сторон пикселя по aspect_ratio_idc (H.264 таблица E-1, HEVC тоже)
+var sarTable = [...][2]uint32{
+	{0, 0}, {1, 1}, {12, 11}, {10, 11}, {16, 11}, {40, 33}, {24, 11}, {20, 11}, {32, 11},
+	{80, 33}, {18, 11}, {15, 11}, {64, 33}, {160, 99}, {4, 3}, {3, 2}, {2, 1},
+}
+
+// readSAR читает aspect_ratio_idc из VUI и возвращает соотношение сторон пикселя
+func readSAR(r *bitReader) (uint32, uint32) {
+	idc := r.u(8)
+	if idc == 255 {
+		return r.u(16), r.u(16)
+	}
+	if int(idc) < len(sarTable) {
+		return sarTable[idc][0], sarTable[idc][1]
+	}
+	return 0, 0
+}
@@ -0,0 +1,360 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+)
+
+// bitWriter собирает поля заголовков для тестов старшими битами вперёд
+type bitWriter struct {
+	data []byte
+	n    int
+}
+
+func (w *bitWriter) u(n int, v uint32) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+func (w *bitWriter) ue(v uint32) {
+	bits := 0
+	for (v+1)>>bits > 1 {
+		bits++
+	}
+	w.u(bits, 0)
+	w.u(bits+1, v+1)
+}
+
+func (w *bitWriter) se(v int32) {
+	if v > 0 {
+		w.ue(uint32(2*v - 1))
+	} else {
+		w.ue(uint32(-2 * v))
+	}
+}
+
+// rbsp завершает данные rbsp_trailing_bits и вставляет emulation prevention байты
+func (w *bitWriter) rbsp() []byte {
+	w.u(1, 1)
+	for w.n%8 != 0 {
+		w.u(1, 0)
+	}
+	var out []byte
+	zeros := 0
+	for _, b := range w.data {
+		if zeros >= 2 && b <= 0x03 {
+			out = append(out, 0x03)
+			zeros = 0
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// h264TestSPS собирает SPS H.264 без nal_unit_header
+func h264TestSPS(profile, level uint32, widthMbs, heightMapUnits uint32, frameMbsOnly bool, cropBottom uint32, sarIDC, timeScale uint32) []byte {
+	w := &bitWriter{}
+	w.u(8, profile)
+	w.u(8, 0)
+	w.u(8, level)
+	w.ue(0) // seq_parameter_set_id
+	if profile == 100 {
+		w.ue(1)   // chroma_format_idc 4:2:0
+		w.ue(0)   // bit_depth_luma_minus8
+		w.ue(0)   // bit_depth_chroma_minus8
+		w.u(1, 0) // qpprime_y_zero_transform_bypass_flag
+		w.u(1, 1) // seq_scaling_matrix_present_flag
+		w.u(1, 1) // первый список присутствует
+		for j := 0; j < 16; j++ {
+			w.se(1)
+		}
+		w.u(7, 0)
+	}
+	w.ue(0) // log2_max_frame_num_minus4
+	w.ue(0) // pic_order_cnt_type
+	w.ue(2) // log2_max_pic_order_cnt_lsb_minus4
+	w.ue(4) // max_num_ref_frames
+	w.u(1, 0)
+	w.ue(widthMbs - 1)
+	w.ue(heightMapUnits - 1)
+	if frameMbsOnly {
+		w.u(1, 1)
+	} else {
+		w.u(1, 0)
+		w.u(1, 1) // mb_adaptive_frame_field_flag
+	}
+	w.u(1, 1) // direct_8x8_inference_flag
+	if cropBottom > 0 {
+		w.u(1, 1)
+		w.ue(0)
+		w.ue(0)
+		w.ue(0)
+		w.ue(cropBottom)
+	} else {
+		w.u(1, 0)
+	}
+	w.u(1, 1) // vui_parameters_present_flag
+	w.u(1, 1) // aspect_ratio_info_present_flag
+	w.u(8, sarIDC)
+	w.u(1, 0) // overscan_info_present_flag
+	w.u(1, 1) // video_signal_type_present_flag
+	w.u(4, 0x5)
+	w.u(1, 1)
+	w.u(24, 0x010101)
+	w.u(1, 0) // chroma_loc_info_present_flag
+	w.u(1, 1) // timing_info_present_flag
+	w.u(32, 1)
+	w.u(32, timeScale)
+	w.u(1, 1)
+	return w.rbsp()
+}
+
+// h264TestSlice собирает начало первого среза кадра
+func h264TestSlice(sliceType uint32, field bool, frameNum uint32) []byte {
+	w := &bitWriter{}
+	w.ue(0) // first_mb_in_slice
+	w.ue(sliceType)
+	w.ue(0) // pic_parameter_set_id
+	w.u(4, frameNum)
+	if field {
+		w.u(1, 1) // field_pic_flag
+		w.u(1, 0) // bottom_field_flag
+	}
+	w.u(16, 0xA5A5) // данные среза
+	return w.rbsp()
+}
+
+func TestParseH264SPS(t *testing.T) {
+	tests := []struct {
+		name string
+		sps  []byte
+		want VideoInfo
+	}{
+		{
+			name: "HD progressive High",
+			sps:  h264TestSPS(100, 40, 120, 68, true, 4, 1, 50),
+			want: VideoInfo{Width: 1920, Height: 1080, FrameRate: 25, Profile: "High", Level: "4.0", Aspect: "16:9"},
+		},
+		{
+			name: "SD interlaced anamorphic",
+			sps:  h264TestSPS(77, 30, 45, 18, false, 0, 4, 50),
+			want: VideoInfo{Width: 720, Height: 576, FrameRate: 25, Interlaced: true, Profile: "Main", Level: "3.0", Aspect: "16:9"},
+		},
+		{
+			name: "SD progressive 4:3",
+			sps:  h264TestSPS(77, 30, 45, 36, true, 0, 2, 50),
+			want: VideoInfo{Width: 720, Height: 576, FrameRate: 25, Profile: "Main", Level: "3.0", Aspect: "4:3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sps, ok := parseH264SPS(unescapeRBSP(tt.sps))
+			if !ok {
+				t.Fatal("parseH264SPS failed")
+			}
+			if sps.info != tt.want {
+				t.Errorf("info = %+v, want %+v", sps.info, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHEVCSPS(t *testing.T) {
+	w := &bitWriter{}
+	w.u(4, 0) // sps_video_parameter_set_id
+	w.u(3, 0) // sps_max_sub_layers_minus1
+	w.u(1, 1) // sps_temporal_id_nesting_flag
+	w.u(3, 0) // general_profile_space, general_tier_flag
+	w.u(5, 2) // Main 10
+	w.u(32, 0x20000000)
+	w.u(1, 1) // general_progressive_source_flag
+	w.u(1, 0) // general_interlaced_source_flag
+	w.u(32, 0)
+	w.u(14, 0)
+	w.u(8, 153) // уровень 5.1
+	w.ue(0)     // sps_seq_parameter_set_id
+	w.ue(1)     // chroma_format_idc
+	w.ue(3840)
+	w.ue(2176)
+	w.u(1, 1) // conformance_window_flag
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.ue(8) // 16 строк снизу
+	w.ue(2) // bit_depth_luma_minus8
+	w.ue(2) // bit_depth_chroma_minus8
+	w.ue(4) // log2_max_pic_order_cnt_lsb_minus4
+	w.u(1, 1)
+	w.ue(4)
+	w.ue(2)
+	w.ue(0)
+	for _, v := range []uint32{0, 3, 0, 3, 2, 2} {
+		w.ue(v)
+	}
+	w.u(1, 0) // scaling_list_enabled_flag
+	w.u(2, 3) // amp, sao
+	w.u(1, 0) // pcm_enabled_flag
+	w.ue(2)   // num_short_term_ref_pic_sets
+	// Набор 0: две картинки назад, одна вперёд
+	w.ue(2)
+	w.ue(1)
+	w.ue(0)
+	w.u(1, 1)
+	w.ue(1)
+	w.u(1, 1)
+	w.ue(3)
+	w.u(1, 1)
+	// Набор 1 предсказан из набора 0 со сдвигом -1
+	w.u(1, 1) // inter_ref_pic_set_prediction_flag
+	w.u(1, 1) // delta_rps_sign
+	w.ue(0)   // abs_delta_rps_minus1
+	for j := 0; j < 4; j++ {
+		w.u(1, 1) // used_by_curr_pic_flag
+	}
+	w.u(1, 0) // long_term_ref_pics_present_flag
+	w.u(2, 3) // temporal mvp, strong intra smoothing
+	w.u(1, 1) // vui_parameters_present_flag
+	w.u(1, 0) // aspect_ratio_info_present_flag
+	w.u(1, 0) // overscan_info_present_flag
+	w.u(1, 0) // video_signal_type_present_flag
+	w.u(1, 0) // chroma_loc_info_present_flag
+	w.u(3, 0) // neutral_chroma, field_seq, frame_field_info
+	w.u(1, 0) // default_display_window_flag
+	w.u(1, 1) // vui_timing_info_present_flag
+	w.u(32, 1001)
+	w.u(32, 60000)
+
+	sps, ok := parseHEVCSPS(unescapeRBSP(w.rbsp()))
+	if !ok {
+		t.Fatal("parseHEVCSPS failed")
+	}
+	want := VideoInfo{Width: 3840, Height: 2160, FrameRate: 59.94, Profile: "Main 10", Level: "5.1", Aspect: "16:9"}
+	if sps.info != want {
+		t.Errorf("info = %+v, want %+v", sps.info, want)
+	}
+}
+
+func TestMPEG2SequenceHeader(t *testing.T) {
+	v := newVideoTracker(codecMPEG2)
+	v.write([]byte{
+		0x00, 0x00, 0x01, 0xB3, 0x2D, 0x02, 0x40, 0x33, // 720x576, 16:9, 25 fps
+		0xFF, 0xFF, 0xE0, 0x18,
+		0x00, 0x00, 0x01, 0xB5, 0x14, 0x82, 0x00, 0x01, 0x00, 0x00, // Main@Main, interlaced
+		0x00, 0x00, 0x01, 0xB8,
+	})
+
+	info := v.report()
+	if info == nil {
+		t.Fatal("sequence header not parsed")
+	}
+	want := VideoInfo{Width: 720, Height: 576, FrameRate: 25, Interlaced: true, Profile: "Main", Level: "Main", Aspect: "16:9"}
+	if *info != want {
+		t.Errorf("info = %+v, want %+v", *info, want)
+	}
+}
+
+// pesPackets раскладывает PES пакет видео с PTS по TS пакетам
+func pesPackets(pid uint16, pts uint64, es []byte, cc *uint8) [][]byte {
+	data := []byte{
+		0x00, 0x00, 0x01, 0xE0, 0x00, 0x00, 0x80, 0x80, 0x05,
+		0x21 | byte(pts>>29)&0x0E, byte(pts >> 22), byte(pts>>14) | 0x01, byte(pts >> 7), byte(pts<<1) | 0x01,
+	}
+	data = append(data, es...)
+
+	var packets [][]byte
+	for first := true; len(data) > 0; first = false {
+		pkt := payloadPacket(pid, *cc)
+		*cc++
+		if first {
+			pkt[1] |= 0x40
+		}
+		payload := pkt[4:]
+		if len(data) < len(payload) {
+			// Остаток выравнивается stuffing в adaptation field
+			stuffing := len(payload) - len(data)
+			pkt[3] |= 0x20
+			pkt[4] = byte(stuffing - 1)
+			if stuffing > 1 {
+				pkt[5] = 0x00
+				for i := 6; i < 4+stuffing; i++ {
+					pkt[i] = 0xFF
+				}
+			}
+			payload = pkt[4+stuffing:]
+		}
+		data = data[copy(payload, data):]
+		packets = append(packets, pkt)
+	}
+	return packets
+}
+
+func TestDemuxerVideoGOP(t *testing.T) {
+	d := NewDemuxer()
+	start := time.Unix(1700000000, 0)
+	for _, pkt := range testPSI() {
+		d.FeedPacket(pkt, start)
+	}
+
+	nal := func(header byte, rbsp []byte) []byte {
+		return append([]byte{0x00, 0x00, 0x00, 0x01, header}, rbsp...)
+	}
+	sps := nal(0x67, h264TestSPS(100, 40, 120, 34, false, 2, 1, 50))
+
+	// 1080i: кадры из двух полей, I-кадр каждые 12 кадров, IDR каждые 24;
+	// второе поле I-кадра тоже I, но GOP не начинает
+	var cc uint8
+	pts := uint64(90000)
+	for frame := 0; frame < 50; frame++ {
+		var es []byte
+		if frame%24 == 0 {
+			es = append(es, sps...)
+		}
+		first, second := uint32(0), uint32(0) // P
+		header := byte(0x41)
+		if frame%12 == 0 {
+			first, second = 2, 2 // I
+		}
+		if frame%24 == 0 {
+			header = 0x65 // IDR
+		}
+		es = append(es, nal(header, h264TestSlice(first, true, uint32(frame)))...)
+		es = append(es, nal(0x41, h264TestSlice(second, true, uint32(frame)))...)
+
+		for _, pkt := range pesPackets(0x0066, pts, es, &cc) {
+			d.FeedPacket(pkt, start)
+		}
+		pts += 3600
+	}
+	// Последний срез разбирается по следующему start code
+	for _, pkt := range pesPackets(0x0066, pts, nal(0x09, []byte{0xF0}), &cc) {
+		d.FeedPacket(pkt, start)
+	}
+
+	r := d.Flush(start.Add(time.Second))
+	info := r.Video[0x0066]
+	if info == nil {
+		t.Fatal("no video info for 0x0066")
+	}
+	want := VideoInfo{
+		Width: 1920, Height: 1080, FrameRate: 25, Interlaced: true, Profile: "High", Level: "4.0", Aspect: "16:9",
+		GOPLength: 12, IDRInterval: 960 * time.Millisecond,
+		Frozen: FrozenRepeatedSize, // срезы P-кадров тестового потока одинаковые
+	}
+	if *info != want {
+		t.Errorf("info = %+v, want %+v", *info, want)
+	}
+	if r.Video[0x00CA] != nil {
+		t.Errorf("audio PID has video info: %+v", r.Video[0x00CA])
+	}
+}
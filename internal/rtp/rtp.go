@@ -0,0 +1,186 @@
+package rtp
+
+import (
+	"errors"
+	"time"
+)
+
+// Константы RTP (RFC 3550) для MPEG-TS (RFC 2250, payload type 33)
+const (
+	Version    = 2
+	HeaderSize = 12
+	ClockRate  = 90000 // частота RTP timestamp для MP2T
+)
+
+// Параметры отслеживания номеров
+const (
+	reorderWindow = 32   // пакет, не пришедший за столько номеров, считается потерянным
+	maxDropout    = 3000 // больший скачок номера — перезапуск источника, а не потеря
+	historySize   = 4096 // история полученных номеров, больше maxDropout
+)
+
+// Ошибки разбора заголовка
+var (
+	ErrShortPacket = errors.New("packet is shorter than RTP header")
+	ErrVersion     = errors.New("not an RTP version 2 packet")
+)
+
+// Header поля заголовка RTP
+type Header struct {
+	PayloadType    uint8
+	Marker         bool
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+}
+
+// Parse разбирает заголовок RTP и возвращает полезную нагрузку без CSRC,
+// расширения заголовка и padding
+func Parse(data []byte) (Header, []byte, error) {
+	if len(data) < HeaderSize {
+		return Header{}, nil, ErrShortPacket
+	}
+	if data[0]>>6 != Version {
+		return Header{}, nil, ErrVersion
+	}
+
+	h := Header{
+		Marker:         data[1]&0x80 != 0,
+		PayloadType:    data[1] & 0x7F,
+		SequenceNumber: uint16(data[2])<<8 | uint16(data[3]),
+		Timestamp:      uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7]),
+		SSRC:           uint32(data[8])<<24 | uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11]),
+	}
+
+	offset := HeaderSize + 4*int(data[0]&0x0F) // CSRC
+	if data[0]&0x10 != 0 {                     // extension
+		if len(data) < offset+4 {
+			return Header{}, nil, ErrShortPacket
+		}
+		offset += 4 + 4*(int(data[offset+2])<<8|int(data[offset+3]))
+	}
+
+	end := len(data)
+	if data[0]&0x20 != 0 { // padding: последний байт — его длина
+		end -= int(data[end-1])
+	}
+	if offset > end {
+		return Header{}, nil, ErrShortPacket
+	}
+	return h, data[offset:end], nil
+}
+
+// Stats статистика RTP за окно
+type Stats struct {
+	Packets    int64
+	Lost       int64         // номера, не пришедшие за reorderWindow пакетов
+	Duplicates int64         // повторно полученные номера
+	Reordered  int64         // пакеты, пришедшие после пакета с большим номером
+	Jitter     time.Duration // interarrival jitter по RFC 3550 на конец окна
+}
+
+// Tracker отслеживает номера и timestamp RTP одного потока
+type Tracker struct {
+	started  bool
+	ssrc     uint32
+	maxSeq   int64 // наибольший расширенный номер
+	checked  int64 // номера меньше checked уже проверены на потерю
+	received [historySize]bool
+
+	hasTransit bool
+	transit    float64 // arrival - timestamp предыдущего пакета, такты 90 кГц
+	jitter     float64 // такты 90 кГц
+
+	window Stats
+}
+
+// Update учитывает пакет с заголовком h, пришедший в момент at,
+// и возвращает число номеров, признанных потерянными после этого пакета
+func (t *Tracker) Update(h Header, at time.Time) (lost int64) {
+	t.window.Packets++
+
+	if !t.started || h.SSRC != t.ssrc {
+		t.reset(h)
+		t.updateJitter(h, at)
+		return 0
+	}
+
+	// Расширенный номер относительно максимального с учётом переполнения 16 бит
+	delta := int64(int16(h.SequenceNumber - uint16(t.maxSeq)))
+	seq := t.maxSeq + delta
+
+	switch {
+	case delta > maxDropout || -delta > maxDropout:
+		t.reset(h) // источник перезапущен
+	case seq < t.checked:
+		t.window.Reordered++ // опоздал больше чем на окно, уже учтён как потерянный
+	case t.received[seq%historySize]:
+		t.window.Duplicates++
+		return 0
+	default:
+		t.received[seq%historySize] = true
+		if delta < 0 {
+			t.window.Reordered++
+		} else {
+			t.maxSeq = seq
+			lost = t.checkLoss()
+		}
+	}
+
+	t.updateJitter(h, at)
+	return lost
+}
+
+// reset начинает отслеживание с пакета h
+func (t *Tracker) reset(h Header) {
+	t.started = true
+	t.ssrc = h.SSRC
+	t.received = [historySize]bool{}
+	t.maxSeq = int64(h.SequenceNumber)
+	t.checked = t.maxSeq
+	t.received[t.maxSeq%historySize] = true
+	t.hasTransit = false
+}
+
+// checkLoss считает потерянными номера, вышедшие за окно переупорядочивания
+func (t *Tracker) checkLoss() (lost int64) {
+	for ; t.checked <= t.maxSeq-reorderWindow; t.checked++ {
+		index := t.checked % historySize
+		if !t.received[index] {
+			lost++
+		}
+		t.received[index] = false
+	}
+	t.window.Lost += lost
+	return lost
+}
+
+// updateJitter обновляет interarrival jitter (RFC 3550, A.8)
+func (t *Tracker) updateJitter(h Header, at time.Time) {
+	arrival := float64(at.UnixNano()) * ClockRate / float64(time.Second)
+	transit := arrival - float64(h.Timestamp)
+
+	if t.hasTransit {
+		d := transit - t.transit
+		// Переполнение 32-битного timestamp даёт скачок на 2^32 тактов
+		if d > 1<<31 {
+			d -= 1 << 32
+		} else if d < -(1 << 31) {
+			d += 1 << 32
+		}
+		if d < 0 {
+			d = -d
+		}
+		t.jitter += (d - t.jitter) / 16
+	}
+	t.transit = transit
+	t.hasTransit = true
+}
+
+// Flush возвращает статистику окна и начинает новое
+func (t *Tracker) Flush() Stats {
+	stats := t.window
+	stats.Jitter = time.Duration(t.jitter * float64(time.Second) / ClockRate)
+	t.window = Stats{}
+	return stats
+}
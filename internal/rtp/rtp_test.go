@@ -0,0 +1,154 @@
+package rtp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// packet собирает RTP пакет с payload type 33
+func packet(seq uint16, ts uint32, payload []byte) []byte {
+	data := []byte{
+		0x80, 33,
+		byte(seq >> 8), byte(seq),
+		byte(ts >> 24), byte(ts >> 16), byte(ts >> 8), byte(ts),
+		0x12, 0x34, 0x56, 0x78,
+	}
+	return append(data, payload...)
+}
+
+func TestParse(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x47}, 188)
+
+	h, got, err := Parse(packet(65535, 90000, payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.PayloadType != 33 || h.SequenceNumber != 65535 || h.Timestamp != 90000 || h.SSRC != 0x12345678 {
+		t.Errorf("header = %+v", h)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("payload length = %d, want %d", len(got), len(payload))
+	}
+
+	// CSRC, расширение заголовка и padding отрезаются
+	data := packet(1, 0, nil)
+	data[0] |= 0x10 | 0x20 | 0x01
+	data = append(data, 0, 0, 0, 1)       // CSRC
+	data = append(data, 0xBE, 0xDE, 0, 1) // extension header, 1 слово
+	data = append(data, 1, 2, 3, 4)       // extension
+	data = append(data, payload...)       // payload
+	data = append(data, 0, 0, 3)          // padding
+	if _, got, err := Parse(data); err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("Parse() with csrc/extension/padding: len = %d, err = %v", len(got), err)
+	}
+
+	// Сырой TS не принимается за RTP
+	if _, _, err := Parse(payload); err != ErrVersion {
+		t.Errorf("Parse(raw TS) error = %v, want ErrVersion", err)
+	}
+	if _, _, err := Parse([]byte{0x80, 33}); err != ErrShortPacket {
+		t.Errorf("Parse(short) error = %v, want ErrShortPacket", err)
+	}
+}
+
+func TestTrackerSequence(t *testing.T) {
+	tests := []struct {
+		name  string
+		seqs  []uint16
+		stats Stats
+	}{
+		{
+			name:  "in order across wrap",
+			seqs:  seqRange(65500, 100),
+			stats: Stats{Packets: 100},
+		},
+		{
+			name:  "loss",
+			seqs:  append(seqRange(0, 10), seqRange(15, 50)...),
+			stats: Stats{Packets: 60, Lost: 5},
+		},
+		{
+			name:  "reordered within window",
+			seqs:  append(append(seqRange(0, 10), 11, 10), seqRange(12, 50)...),
+			stats: Stats{Packets: 62, Reordered: 1},
+		},
+		{
+			name:  "duplicates",
+			seqs:  append(append(seqRange(0, 10), 9, 9), seqRange(10, 50)...),
+			stats: Stats{Packets: 62, Duplicates: 2},
+		},
+		{
+			name:  "late beyond window counts as lost",
+			seqs:  append(append(seqRange(0, 10), seqRange(11, 50)...), 10),
+			stats: Stats{Packets: 61, Lost: 1, Reordered: 1},
+		},
+		{
+			name:  "source restart is not loss",
+			seqs:  append(seqRange(0, 50), seqRange(30000, 50)...),
+			stats: Stats{Packets: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tracker Tracker
+			var lost int64
+			at := time.Now()
+			for i, seq := range tt.seqs {
+				lost += tracker.Update(Header{SequenceNumber: seq, Timestamp: uint32(i * 90), SSRC: 1}, at)
+				at = at.Add(time.Millisecond)
+			}
+
+			got := tracker.Flush()
+			if lost != got.Lost {
+				t.Errorf("Update() reported %d lost, stats %d", lost, got.Lost)
+			}
+			got.Jitter = 0
+			if got != tt.stats {
+				t.Errorf("stats = %+v, want %+v", got, tt.stats)
+			}
+			if next := tracker.Flush(); next.Packets != 0 || next.Lost != 0 {
+				t.Errorf("stats after flush = %+v", next)
+			}
+		})
+	}
+}
+
+func TestTrackerJitter(t *testing.T) {
+	var tracker Tracker
+	at := time.Now()
+
+	// Пакеты каждые 10 мс по timestamp, приход чередуется 5 и 15 мс: |D| = 5 мс
+	for i := 0; i < 500; i++ {
+		tracker.Update(Header{SequenceNumber: uint16(i), Timestamp: uint32(i * 900), SSRC: 1}, at)
+		if i%2 == 0 {
+			at = at.Add(5 * time.Millisecond)
+		} else {
+			at = at.Add(15 * time.Millisecond)
+		}
+	}
+
+	jitter := tracker.Flush().Jitter
+	if jitter < 4900*time.Microsecond || jitter > 5100*time.Microsecond {
+		t.Errorf("jitter = %v, want ~5ms", jitter)
+	}
+
+	// Равномерный приход: jitter затухает
+	for i := 500; i < 1000; i++ {
+		tracker.Update(Header{SequenceNumber: uint16(i), Timestamp: uint32(i * 900), SSRC: 1}, at)
+		at = at.Add(10 * time.Millisecond)
+	}
+	if jitter := tracker.Flush().Jitter; jitter > 100*time.Microsecond {
+		t.Errorf("jitter after steady arrival = %v, want ~0", jitter)
+	}
+}
+
+// seqRange возвращает n номеров начиная с first (с переполнением)
+func seqRange(first uint16, n int) []uint16 {
+	seqs := make([]uint16, n)
+	for i := range seqs {
+		seqs[i] = first + uint16(i)
+	}
+	return seqs
+}
@@ -0,0 +1,366 @@
+package sla
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/events"
+)
+
+// Stream поток, для которого считается доступность
+type Stream struct {
+	URL      string
+	Name     string
+	Provider string
+}
+
+// StreamsFromConfig возвращает потоки конфигурации с именем и провайдером
+func StreamsFromConfig(streams []config.Stream) []Stream {
+	result := make([]Stream, 0, len(streams))
+	for _, stream := range streams {
+		result = append(result, Stream{
+			URL:      stream.URL,
+			Name:     stream.Name,
+			Provider: stream.Provider,
+		})
+	}
+	return result
+}
+
+// Report доступность потока (или провайдера) за период.
+// Время, когда статус потока неизвестен (нет истории или мониторинг был остановлен),
+// в расчёт не входит
+type Report struct {
+	Stream   string    `json:"stream,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Provider string    `json:"provider"`
+	Streams  int       `json:"streams"` // число потоков (для провайдера)
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+
+	MonitoredSeconds float64  `json:"monitored_seconds"`    // время с известным статусом
+	UptimeSeconds    float64  `json:"uptime_seconds"`       // online
+	DowntimeSeconds  float64  `json:"downtime_seconds"`     // offline
+	Availability     *float64 `json:"availability_percent"` // null если статус неизвестен весь период
+	Outages          int      `json:"outages"`
+	MTTRSeconds      float64  `json:"mttr_seconds"`           // среднее время восстановления
+	LongestSeconds   float64  `json:"longest_outage_seconds"` // самый долгий простой
+
+	acc accumulator // исходные длительности для ByProvider
+}
+
+// accumulator суммирует интервалы одного потока или провайдера
+type accumulator struct {
+	uptime   time.Duration
+	downtime time.Duration
+	outages  int
+	longest  time.Duration
+}
+
+// addOutage учитывает простой длительностью d
+func (a *accumulator) addOutage(d time.Duration) {
+	a.outages++
+	if d > a.longest {
+		a.longest = d
+	}
+}
+
+// merge добавляет результаты другого потока
+func (a *accumulator) merge(other accumulator) {
+	a.uptime += other.uptime
+	a.downtime += other.downtime
+	a.outages += other.outages
+	if other.longest > a.longest {
+		a.longest = other.longest
+	}
+}
+
+// report заполняет числовые поля Report
+func (a accumulator) report(r Report) Report {
+	monitored := a.uptime + a.downtime
+	r.MonitoredSeconds = monitored.Seconds()
+	r.UptimeSeconds = a.uptime.Seconds()
+	r.DowntimeSeconds = a.downtime.Seconds()
+	r.Outages = a.outages
+	r.LongestSeconds = a.longest.Seconds()
+	r.acc = a
+
+	if monitored > 0 {
+		availability := float64(a.uptime) / float64(monitored) * 100
+		r.Availability = &availability
+	}
+	if a.outages > 0 {
+		r.MTTRSeconds = a.downtime.Seconds() / float64(a.outages)
+	}
+	return r
+}
+
+// Calculate считает доступность потоков за [from, to).
+// history — события online/offline/stopped по времени, включая события до from:
+// по ним определяется статус на начало периода
+func Calculate(streams []Stream, history []events.Event, from, to time.Time) []Report {
+	byStream := make(map[string][]events.Event)
+	for _, ev := range history {
+		if ev.Type == events.TypeOnline || ev.Type == events.TypeOffline || ev.Type == events.TypeStopped {
+			byStream[ev.Stream] = append(byStream[ev.Stream], ev)
+		}
+	}
+
+	reports := make([]Report, 0, len(streams))
+	for _, stream := range streams {
+		acc := calculate(byStream[stream.URL], from, to)
+		reports = append(reports, acc.report(Report{
+			Stream:   stream.URL,
+			Name:     stream.Name,
+			Provider: stream.Provider,
+			Streams:  1,
+			From:     from,
+			To:       to,
+		}))
+	}
+	return reports
+}
+
+// calculate проходит по событиям одного потока
+func calculate(history []events.Event, from, to time.Time) accumulator {
+	var acc accumulator
+	var known, online bool
+	var outageStart time.Time
+	cursor := from
+
+	// account добавляет интервал [cursor, until) к текущему статусу
+	account := func(until time.Time) {
+		if !known || !until.After(cursor) {
+			return
+		}
+		if online {
+			acc.uptime += until.Sub(cursor)
+		} else {
+			acc.downtime += until.Sub(cursor)
+		}
+	}
+
+	for _, ev := range history {
+		if !ev.Time.Before(to) {
+			break
+		}
+		isOnline := ev.Type == events.TypeOnline
+		stopped := ev.Type == events.TypeStopped
+
+		// Событие до начала периода задаёт только начальный статус
+		if ev.Time.Before(from) {
+			known, online = !stopped, isOnline
+			if known && !online {
+				outageStart = from
+			}
+			continue
+		}
+
+		account(ev.Time)
+		cursor = ev.Time
+
+		// Мониторинг остановлен: простой заканчивается здесь, дальше статус неизвестен
+		if stopped {
+			if known && !online {
+				acc.addOutage(ev.Time.Sub(outageStart))
+			}
+			known = false
+			continue
+		}
+
+		if known && online == isOnline {
+			continue // повтор того же статуса
+		}
+		if known && !online {
+			acc.addOutage(ev.Time.Sub(outageStart))
+		}
+		if !isOnline {
+			outageStart = ev.Time
+		}
+		known, online = true, isOnline
+	}
+
+	account(to)
+	if known && !online {
+		acc.addOutage(to.Sub(outageStart)) // простой продолжается в конце периода
+	}
+	return acc
+}
+
+// ByProvider суммирует отчёты потоков по провайдерам (в алфавитном порядке)
+func ByProvider(reports []Report) []Report {
+	totals := make(map[string]*accumulator)
+	counts := make(map[string]int)
+	var providers []string
+	var from, to time.Time
+
+	for _, r := range reports {
+		acc, ok := totals[r.Provider]
+		if !ok {
+			acc = &accumulator{}
+			totals[r.Provider] = acc
+			providers = append(providers, r.Provider)
+		}
+		acc.merge(r.acc)
+		counts[r.Provider]++
+		from, to = r.From, r.To
+	}
+
+	sort.Strings(providers)
+	result := make([]Report, 0, len(providers))
+	for _, provider := range providers {
+		result = append(result, totals[provider].report(Report{
+			Provider: provider,
+			Streams:  counts[provider],
+			From:     from,
+			To:       to,
+		}))
+	}
+	return result
+}
+
+// csvHeader колонки CSV отчёта
+var csvHeader = []string{
+	"stream", "name", "provider", "streams", "from", "to",
+	"monitored_seconds", "uptime_seconds", "downtime_seconds", "availability_percent",
+	"outages", "mttr_seconds", "longest_outage_seconds",
+}
+
+// WriteCSV пишет отчёты в CSV с заголовком
+func WriteCSV(w io.Writer, reports []Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, r := range reports {
+		availability := ""
+		if r.Availability != nil {
+			availability = strconv.FormatFloat(*r.Availability, 'f', 4, 64)
+		}
+		record := []string{
+			r.Stream,
+			r.Name,
+			r.Provider,
+			strconv.Itoa(r.Streams),
+			r.From.Format(time.RFC3339),
+			r.To.Format(time.RFC3339),
+			formatSeconds(r.MonitoredSeconds),
+			formatSeconds(r.UptimeSeconds),
+			formatSeconds(r.DowntimeSeconds),
+			availability,
+			strconv.Itoa(r.Outages),
+			formatSeconds(r.MTTRSeconds),
+			formatSeconds(r.LongestSeconds),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// formatSeconds секунды с точностью до миллисекунд
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
+
+// Summary отчёт за период по потокам и провайдерам
+type Summary struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Streams   []Report  `json:"streams,omitempty"`
+	Providers []Report  `json:"providers,omitempty"`
+}
+
+// Группировки отчёта
+const (
+	GroupAll      = ""         // потоки и провайдеры
+	GroupStream   = "stream"   // только потоки
+	GroupProvider = "provider" // только провайдеры
+)
+
+// Build считает отчёт за [from, to) с нужной группировкой
+func Build(streams []Stream, history []events.Event, from, to time.Time, group string) (Summary, error) {
+	if group != GroupAll && group != GroupStream && group != GroupProvider {
+		return Summary{}, fmt.Errorf("invalid group %q (must be %s or %s)", group, GroupStream, GroupProvider)
+	}
+
+	reports := Calculate(streams, history, from, to)
+	summary := Summary{From: from, To: to}
+	if group != GroupProvider {
+		summary.Streams = reports
+	}
+	if group != GroupStream {
+		summary.Providers = ByProvider(reports)
+	}
+	return summary, nil
+}
+
+// WriteCSV пишет строки потоков, затем провайдеров (у них пустая колонка stream)
+func (s Summary) WriteCSV(w io.Writer) error {
+	return WriteCSV(w, append(append([]Report{}, s.Streams...), s.Providers...))
+}
+
+// ParsePeriod определяет период отчёта: month (2025-01) или from/to
+// (RFC3339 или 2025-01-31). По умолчанию — с начала текущего месяца до now.
+// Конец периода не может быть позже now
+func ParsePeriod(from, to, month string, now time.Time) (time.Time, time.Time, error) {
+	var start, end time.Time
+
+	if month != "" {
+		if from != "" || to != "" {
+			return start, end, fmt.Errorf("month cannot be combined with from/to")
+		}
+		var err error
+		start, err = time.ParseInLocation("2006-01", month, now.Location())
+		if err != nil {
+			return start, end, fmt.Errorf("invalid month %q (YYYY-MM expected)", month)
+		}
+		end = start.AddDate(0, 1, 0)
+	} else {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		end = now
+
+		var err error
+		if from != "" {
+			if start, err = parseTime(from, now.Location()); err != nil {
+				return start, end, fmt.Errorf("invalid from: %w", err)
+			}
+		}
+		if to != "" {
+			if end, err = parseTime(to, now.Location()); err != nil {
+				return start, end, fmt.Errorf("invalid to: %w", err)
+			}
+		}
+	}
+
+	if end.After(now) {
+		end = now
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("empty period %s - %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+// parseTime разбирает RFC3339 или дату YYYY-MM-DD
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not RFC3339 or YYYY-MM-DD", value)
+}
@@ -0,0 +1,254 @@
+package sla
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/events"
+)
+
+var (
+	periodStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd   = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+)
+
+// at возвращает момент через h часов после начала периода
+func at(h float64) time.Time {
+	return periodStart.Add(time.Duration(h * float64(time.Hour)))
+}
+
+func status(stream string, t time.Time, online bool) events.Event {
+	ev := events.Event{Time: t, Stream: stream, Type: events.TypeOffline}
+	if online {
+		ev.Type = events.TypeOnline
+	}
+	return ev
+}
+
+func TestCalculate(t *testing.T) {
+	const url = "233.198.134.1:3333"
+
+	tests := []struct {
+		name         string
+		history      []events.Event
+		availability float64 // -1 = статус неизвестен
+		outages      int
+		mttr         time.Duration
+		longest      time.Duration
+	}{
+		{
+			name:         "no history",
+			availability: -1,
+		},
+		{
+			name:         "online before period",
+			history:      []events.Event{status(url, at(-48), true)},
+			availability: 100,
+		},
+		{
+			name: "single outage",
+			history: []events.Event{
+				status(url, at(-1), true),
+				status(url, at(6), false),
+				status(url, at(12), true),
+			},
+			availability: 75,
+			outages:      1,
+			mttr:         6 * time.Hour,
+			longest:      6 * time.Hour,
+		},
+		{
+			name: "offline at start and at end",
+			history: []events.Event{
+				status(url, at(-2), false),
+				status(url, at(2), true),
+				status(url, at(20), false),
+			},
+			availability: 75,
+			outages:      2,
+			mttr:         3 * time.Hour,
+			longest:      4 * time.Hour,
+		},
+		{
+			name: "status first known inside period",
+			history: []events.Event{
+				status(url, at(12), true),
+				status(url, at(18), false),
+				status(url, at(18.5), false), // повтор
+				status(url, at(21), true),
+				status(url, at(30), false), // после периода
+			},
+			availability: 75,
+			outages:      1,
+			mttr:         3 * time.Hour,
+			longest:      3 * time.Hour,
+		},
+		{
+			name: "monitoring gap",
+			history: []events.Event{
+				status(url, at(-1), true),
+				status(url, at(4), false),
+				events.Stopped(url, "shutdown", at(8)), // простой обрывается остановкой
+				status(url, at(16), true),              // 8 часов без мониторинга
+			},
+			availability: 75,
+			outages:      1,
+			mttr:         4 * time.Hour,
+			longest:      4 * time.Hour,
+		},
+		{
+			name: "stopped before period",
+			history: []events.Event{
+				status(url, at(-5), false),
+				events.Stopped(url, "shutdown", at(-4)),
+				status(url, at(12), true),
+			},
+			availability: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := Calculate([]Stream{{URL: url}}, tt.history, periodStart, periodEnd)
+			if len(reports) != 1 {
+				t.Fatalf("got %d reports, want 1", len(reports))
+			}
+			r := reports[0]
+
+			if tt.availability < 0 {
+				if r.Availability != nil || r.MonitoredSeconds != 0 {
+					t.Errorf("availability = %v, monitored = %v, want unknown", r.Availability, r.MonitoredSeconds)
+				}
+				return
+			}
+			if r.Availability == nil || *r.Availability != tt.availability {
+				t.Fatalf("availability = %v, want %v (report %+v)", r.Availability, tt.availability, r)
+			}
+			if r.Outages != tt.outages || r.MTTRSeconds != tt.mttr.Seconds() || r.LongestSeconds != tt.longest.Seconds() {
+				t.Errorf("outages = %d, mttr = %v, longest = %v, want %d, %v, %v",
+					r.Outages, r.MTTRSeconds, r.LongestSeconds, tt.outages, tt.mttr.Seconds(), tt.longest.Seconds())
+			}
+		})
+	}
+}
+
+func TestCalculateAfterRetention(t *testing.T) {
+	const url = "233.198.134.1:3333"
+	now := time.Now()
+
+	// Поток online дольше retention: его единственное событие старше 90 дней
+	store := events.NewStore(90 * 24 * time.Hour)
+	err := store.Append(
+		status(url, now.Add(-100*24*time.Hour), true),
+		events.Event{Time: now.Add(-95 * 24 * time.Hour), Stream: url, Type: events.TypePIDAdded},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(status("233.198.134.2:3333", now, true)); err != nil {
+		t.Fatal(err) // Append отбрасывает старые события
+	}
+
+	history := store.Query(events.Query{Streams: []string{url}})
+	if len(history) != 1 || history[0].Type != events.TypeOnline {
+		t.Fatalf("history after prune = %+v, want the online event only", history)
+	}
+
+	reports := Calculate([]Stream{{URL: url}}, history, now.Add(-24*time.Hour), now)
+	if r := reports[0]; r.Availability == nil || *r.Availability != 100 {
+		t.Errorf("availability = %v, want 100", r.Availability)
+	}
+}
+
+func TestByProvider(t *testing.T) {
+	streams := []Stream{
+		{URL: "233.198.134.1:3333", Name: "One", Provider: "B"},
+		{URL: "233.198.134.2:3333", Name: "Two", Provider: "A"},
+		{URL: "233.198.134.3:3333", Name: "Three", Provider: "A"},
+	}
+	history := []events.Event{
+		status("233.198.134.1:3333", at(-1), true),
+		status("233.198.134.2:3333", at(-1), true),
+		status("233.198.134.2:3333", at(12), false),
+		status("233.198.134.3:3333", at(-1), false),
+		status("233.198.134.3:3333", at(6), true),
+	}
+
+	summary, err := Build(streams, history, periodStart, periodEnd, GroupAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Streams) != 3 || len(summary.Providers) != 2 {
+		t.Fatalf("summary = %+v", summary)
+	}
+
+	a := summary.Providers[0]
+	if a.Provider != "A" || a.Streams != 2 || a.Outages != 2 {
+		t.Errorf("provider A = %+v", a)
+	}
+	// 18ч простоя из 48ч наблюдения
+	if a.Availability == nil || *a.Availability != 62.5 || a.LongestSeconds != (12*time.Hour).Seconds() {
+		t.Errorf("provider A availability = %v, longest = %v", a.Availability, a.LongestSeconds)
+	}
+	if b := summary.Providers[1]; b.Provider != "B" || b.Availability == nil || *b.Availability != 100 {
+		t.Errorf("provider B = %+v", b)
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 || records[0][0] != "stream" || records[4][0] != "" || records[4][2] != "A" {
+		t.Errorf("csv = %v", records)
+	}
+
+	if _, err := Build(streams, history, periodStart, periodEnd, "channel"); err == nil {
+		t.Error("Build() with invalid group: expected error")
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		from, to, month string
+		start, end      time.Time
+		wantErr         bool
+	}{
+		{name: "default is current month", start: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), end: now},
+		{name: "month", month: "2025-01", start: periodStart, end: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "current month clipped", month: "2025-03", start: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), end: now},
+		{name: "dates", from: "2025-01-01", to: "2025-01-02", start: periodStart, end: periodEnd},
+		{name: "rfc3339", from: "2025-01-01T00:00:00Z", to: "2025-01-02T00:00:00Z", start: periodStart, end: periodEnd},
+		{name: "month with from", month: "2025-01", from: "2025-01-01", wantErr: true},
+		{name: "bad month", month: "january", wantErr: true},
+		{name: "bad from", from: "yesterday", wantErr: true},
+		{name: "empty period", from: "2025-01-02", to: "2025-01-01", wantErr: true},
+		{name: "future", month: "2025-04", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParsePeriod(tt.from, tt.to, tt.month, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s - %s", start, end)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("period = %s - %s, want %s - %s", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package tsp
+
+import "time"
+
+// burstWindow окно, в котором считается размер всплеска датаграмм
+const burstWindow = time.Millisecond
+
+// arrivalTracker собирает интервалы между датаграммами и наибольший всплеск за окно
+type arrivalTracker struct {
+	last      time.Time
+	intervals []time.Duration
+	max       time.Duration
+	recent    []time.Time // приходы за последние burstWindow
+	burstMax  int64
+}
+
+// arrival учитывает датаграмму, пришедшую в момент at
+func (t *arrivalTracker) arrival(at time.Time) {
+	if !t.last.IsZero() {
+		// Метки ядра и время чтения могут чуть расходиться: отрицательный интервал — ноль
+		interval := max(at.Sub(t.last), 0)
+		t.intervals = append(t.intervals, interval)
+		t.max = max(t.max, interval)
+	}
+	t.last = at
+
+	drop := 0
+	for drop < len(t.recent) && at.Sub(t.recent[drop]) >= burstWindow {
+		drop++
+	}
+	t.recent = append(t.recent[drop:], at)
+	t.burstMax = max(t.burstMax, int64(len(t.recent)))
+}
+
+// flush возвращает статистику окна и начинает новое; nil, если датаграмм не было
+func (t *arrivalTracker) flush() *ArrivalInfo {
+	if len(t.intervals) == 0 && t.burstMax == 0 {
+		return nil
+	}
+	info := &ArrivalInfo{
+		Intervals:   t.intervals,
+		IntervalMax: t.max,
+		BurstMax:    t.burstMax,
+	}
+	t.intervals = nil
+	t.max = 0
+	t.burstMax = 0
+	return info
+}
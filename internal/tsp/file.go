@@ -0,0 +1,103 @@
+package tsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/config"
+	"github.com/otcnet/tsmonitor/internal/mpegts"
+)
+
+// FilePath возвращает путь к записи для url вида file:///path.ts
+func FilePath(streamURL string) (string, bool) {
+	return strings.CutPrefix(streamURL, config.FileScheme)
+}
+
+// replayChunk сколько читать из файла за раз
+const replayChunk = 64 * mpegts.PacketSize
+
+// FileReplay воспроизводит запись MPEG-TS через Demuxer. Время пакетов
+// восстанавливается по PCR, так что битрейт и анализ PCR считаются по времени
+// записи, а не по скорости чтения файла
+type FileReplay struct {
+	Path     string
+	Realtime bool          // выдавать пакеты в темпе записи, а не так быстро, как читается файл
+	Window   time.Duration // окно отчётов; 0 = 1s
+}
+
+// Run читает файл до конца (или отмены ctx) и вызывает report для каждого окна
+// времени записи и для неполного последнего окна. Время записи отсчитывается от start.
+// Запись без PCR не воспроизводится: её время восстановить не по чему
+func (f *FileReplay) Run(ctx context.Context, demux *mpegts.Demuxer, start time.Time, report func(*mpegts.Report)) error {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Path, err)
+	}
+	defer file.Close()
+
+	window := f.Window
+	if window <= 0 {
+		window = time.Second
+	}
+
+	var clock mpegts.PCRClock
+	reader := bufio.NewReaderSize(file, 16*replayChunk)
+	buf := make([]byte, replayChunk)
+	nextFlush := start.Add(window)
+	end := start // время последнего пакета
+	written := false
+
+	// Отмена контекста завершает чтение, прочитанное попадает в последний отчёт
+	for ctx.Err() == nil {
+		n, err := io.ReadFull(reader, buf)
+		for offset := 0; offset < n; offset += mpegts.PacketSize {
+			pkt := buf[offset:min(offset+mpegts.PacketSize, n)]
+			// Пакеты без sync byte на своём месте только двигают время: ресинхронизацию делает Demuxer;
+			// хвост файла короче пакета получает время предыдущего
+			if len(pkt) == mpegts.PacketSize {
+				end = start.Add(clock.Packet(pkt))
+			}
+
+			if f.Realtime {
+				if wait := time.Until(end); wait > time.Millisecond {
+					select {
+					case <-ctx.Done():
+					case <-time.After(wait):
+					}
+				}
+			}
+
+			// Окна без пакетов (пауза в записи) тоже закрываются
+			for !end.Before(nextFlush) {
+				report(demux.Flush(nextFlush))
+				nextFlush = nextFlush.Add(window)
+			}
+			demux.Write(pkt, end)
+			written = true
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+	}
+
+	if !written {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("%s is empty", f.Path)
+	}
+	if !clock.Started() && ctx.Err() == nil {
+		return fmt.Errorf("%s has no PCR: recording time cannot be restored", f.Path)
+	}
+	report(demux.Flush(end))
+	return nil
+}
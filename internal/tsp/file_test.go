@@ -0,0 +1,117 @@
+package tsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/mpegts"
+)
+
+// recordPacket создаёт пакет PID 0x0100 с CC и, если pcr >= 0, с PCR
+func recordPacket(cc uint8, pcr int64) []byte {
+	pkt := make([]byte, mpegts.PacketSize)
+	pkt[0] = mpegts.SyncByte
+	pkt[1] = 0x01
+	pkt[3] = 0x10 | cc&0x0F
+	if pcr >= 0 {
+		base, ext := uint64(pcr)/300, uint64(pcr)%300
+		pkt[3] |= 0x20
+		pkt[4] = 7
+		pkt[5] = 0x10
+		pkt[6] = byte(base >> 25)
+		pkt[7] = byte(base >> 17)
+		pkt[8] = byte(base >> 9)
+		pkt[9] = byte(base >> 1)
+		pkt[10] = byte(base<<7) | 0x7E | byte(ext>>8)
+		pkt[11] = byte(ext)
+	}
+	return pkt
+}
+
+func TestFileReplay(t *testing.T) {
+	if path, ok := FilePath("file:///var/tmp/capture.ts"); !ok || path != "/var/tmp/capture.ts" {
+		t.Errorf("FilePath() = %q, %v", path, ok)
+	}
+	if _, ok := FilePath("233.198.134.1:3333"); ok {
+		t.Error("FilePath() of multicast address = ok")
+	}
+
+	// 3.5 с записи: 250 пакетов/с, PCR каждые 10 пакетов (40 мс)
+	var data []byte
+	for i := 0; i < 875; i++ {
+		pcr := int64(-1)
+		if i%10 == 0 {
+			pcr = int64(i/10) * 27000000 / 25
+		}
+		data = append(data, recordPacket(uint8(i), pcr)...)
+	}
+	path := filepath.Join(t.TempDir(), "capture.ts")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []*mpegts.Report
+	start := time.Unix(1700000000, 0)
+	replay := &FileReplay{Path: path}
+	began := time.Now()
+	if err := replay.Run(context.Background(), mpegts.NewDemuxer(), start, func(r *mpegts.Report) {
+		reports = append(reports, r)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("replay without realtime took %v", elapsed)
+	}
+
+	// Три полных окна по времени записи и неполное последнее
+	if len(reports) != 4 {
+		t.Fatalf("reports = %d, want 4", len(reports))
+	}
+	for i, r := range reports[:3] {
+		if r.Start != start.Add(time.Duration(i)*time.Second) || r.Duration() != time.Second {
+			t.Errorf("window %d = %v..%v", i, r.Start, r.End)
+		}
+		if bitrate := r.Bitrate(); bitrate < 370000 || bitrate > 382000 {
+			t.Errorf("window %d bitrate = %d, want ~376000", i, bitrate)
+		}
+		if len(r.CCErrors) != 0 {
+			t.Errorf("window %d CC errors = %v", i, r.CCErrors)
+		}
+	}
+	if last := reports[3]; last.Packets == 0 || last.Duration() >= time.Second {
+		t.Errorf("last window = %d packets over %v", last.Packets, last.Duration())
+	}
+
+	missing := &FileReplay{Path: filepath.Join(t.TempDir(), "missing.ts")}
+	if err := missing.Run(context.Background(), mpegts.NewDemuxer(), start, func(*mpegts.Report) {}); err == nil {
+		t.Error("Run() of missing file succeeded")
+	}
+}
+
+func TestFileReplayNoPCR(t *testing.T) {
+	var data []byte
+	for i := 0; i < 500; i++ {
+		data = append(data, recordPacket(uint8(i), -1)...)
+	}
+	path := filepath.Join(t.TempDir(), "nopcr.ts")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без PCR все пакеты получили бы одно время: нулевая длительность и битрейт
+	var reports int
+	replay := &FileReplay{Path: path}
+	err := replay.Run(context.Background(), mpegts.NewDemuxer(), time.Unix(1700000000, 0), func(*mpegts.Report) {
+		reports++
+	})
+	if err == nil || !strings.Contains(err.Error(), "no PCR") {
+		t.Errorf("Run() error = %v, want no PCR", err)
+	}
+	if reports != 0 {
+		t.Errorf("reports = %d, want 0", reports)
+	}
+}
@@ -0,0 +1,73 @@
+package tsp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ResolveInterface находит сетевой интерфейс по IP адресу или имени (eth1)
+// и возвращает его вместе с IPv4 адресом, на котором принимается multicast
+func ResolveInterface(value string) (*net.Interface, net.IP, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		ifi, err := interfaceByAddress(ip)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ifi, ip, nil
+	}
+
+	ifi, err := net.InterfaceByName(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no interface %s: %w", value, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get addresses of %s: %w", value, err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ifi, ipNet.IP.To4(), nil
+		}
+	}
+	return nil, nil, fmt.Errorf("interface %s has no IPv4 address", value)
+}
+
+// interfaceByAddress ищет сетевой интерфейс с заданным IP адресом
+func interfaceByAddress(ip net.IP) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return &ifaces[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no interface with address %s", ip)
+}
+
+// CheckInterfaces проверяет, что все интерфейсы есть на хосте; выключенные
+// интерфейсы не считаются ошибкой, но о них выводится предупреждение
+func CheckInterfaces(values []string) error {
+	var errs []error
+	for _, value := range values {
+		ifi, ip, err := ResolveInterface(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ifi.Flags&net.FlagUp == 0 {
+			fmt.Printf("⚠️  Interface %s (%s) is down\n", ifi.Name, ip)
+		}
+	}
+	return errors.Join(errs...)
+}
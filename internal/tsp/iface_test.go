@@ -0,0 +1,48 @@
+package tsp
+
+import (
+	"net"
+	"testing"
+)
+
+// loopback возвращает имя loopback интерфейса хоста
+func loopback(t *testing.T) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback != 0 {
+			return ifi.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
+
+func TestResolveInterface(t *testing.T) {
+	lo := loopback(t)
+
+	for _, value := range []string{"127.0.0.1", lo} {
+		ifi, ip, err := ResolveInterface(value)
+		if err != nil {
+			t.Fatalf("ResolveInterface(%q) error = %v", value, err)
+		}
+		if ifi.Name != lo || !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Errorf("ResolveInterface(%q) = %s, %s", value, ifi.Name, ip)
+		}
+	}
+
+	for _, value := range []string{"192.0.2.254", "nosuchif0", ""} {
+		if _, _, err := ResolveInterface(value); err == nil {
+			t.Errorf("ResolveInterface(%q) expected error", value)
+		}
+	}
+
+	if err := CheckInterfaces([]string{lo, "nosuchif0", "192.0.2.254"}); err == nil {
+		t.Error("CheckInterfaces() expected error")
+	}
+	if err := CheckInterfaces([]string{lo, "127.0.0.1"}); err != nil {
+		t.Errorf("CheckInterfaces() error = %v", err)
+	}
+}
@@ -1,43 +1,168 @@
 package tsp
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // StreamMetrics содержит все метрики для одного потока
 type StreamMetrics struct {
-	StreamURL   string
-	Description string
-	Status      bool      // online/offline
-	LastSeen    time.Time
-	Bitrate     BitrateInfo
-	PIDs        []PIDInfo
-	ServiceInfo ServiceInfo
-	CCErrors    map[string]int64 // PID -> error count
-	TSID        string           // Transport Stream ID
+	StreamURL   string           `json:"stream_url"`
+	Description string           `json:"description"`
+	Status      bool             `json:"status"` // online/offline
+	LastSeen    time.Time        `json:"last_seen"`
+	Bitrate     BitrateInfo      `json:"bitrate"`
+	PIDs        []PIDInfo        `json:"pids"`
+	SystemPIDs  []PIDInfo        `json:"system_pids,omitempty"` // PSI/SI, null и прочие PID вне PMT (только с битрейтом)
+	ServiceInfo ServiceInfo      `json:"service_info"`
+	CCErrors    map[string]int64 `json:"cc_errors"`          // PID -> error count
+	TSID        string           `json:"tsid"`               // Transport Stream ID
+	Programs    []ProgramInfo    `json:"programs,omitempty"` // Программы из PAT/PMT (несколько в MPTS)
+
+	ETR290Errors  []ETR290Error `json:"etr290_errors,omitempty"` // Ошибки TR 101 290 с прошлого обновления
+	ETR290Checked bool          `json:"etr290_checked"`          // Индикаторы TR 101 290 проверяются (только нативный режим)
+	PCR           []PCRInfo     `json:"pcr,omitempty"`           // Анализ PCR за последнее окно (только нативный режим)
+	Senders       []SenderInfo  `json:"senders,omitempty"`       // Отправители датаграмм за окно (только нативный режим)
+	RTP           *RTPInfo      `json:"rtp,omitempty"`           // Статистика RTP за окно (input: rtp)
+	MDI           *MDIInfo      `json:"mdi,omitempty"`           // MDI за последний интервал (только нативный режим)
+	Arrival       *ArrivalInfo  `json:"arrival,omitempty"`       // Время прихода датаграмм за окно (только нативный режим)
+}
+
+// ProgramInfo программа транспортного потока: PMT, сервис из SDT и её элементарные потоки
+type ProgramInfo struct {
+	Number      int      `json:"number"` // program_number = service_id
+	PMTPID      string   `json:"pmt_pid"`
+	PCRPID      string   `json:"pcr_pid"` // пусто, пока не получена PMT
+	ServiceName string   `json:"service_name,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
+	ServiceType string   `json:"service_type,omitempty"` // HD или SD
+	PIDs        []string `json:"pids"`                   // элементарные потоки
+	BitrateBPS  int64    `json:"bitrate_bps"`            // PMT и элементарные потоки (если Bitrate.PerPID)
+}
+
+// sortPrograms сортирует программы по номеру
+func sortPrograms(programs []ProgramInfo) {
+	sort.Slice(programs, func(i, j int) bool { return programs[i].Number < programs[j].Number })
+}
+
+// ArrivalInfo интервалы между UDP датаграммами и всплески за окно
+type ArrivalInfo struct {
+	Intervals   []time.Duration `json:"-"`               // интервалы между датаграммами (для гистограммы)
+	IntervalMax time.Duration   `json:"interval_max_ns"` // наибольший интервал
+	BurstMax    int64           `json:"burst_max"`       // наибольшее число датаграмм за 1 мс
+}
+
+// MDIInfo Media Delivery Index (RFC 4445) за последний завершённый интервал
+type MDIInfo struct {
+	Interval time.Duration `json:"interval_ns"`
+	DF       time.Duration `json:"df_ns"` // delay factor: нужная глубина буфера приёмника
+	MLR      float64       `json:"mlr"`   // media loss rate: потерянных TS пакетов в секунду
+}
+
+// RTPInfo потери и порядок RTP пакетов за окно: сетевые потери в отличие от ошибок CC
+type RTPInfo struct {
+	Packets    int64         `json:"packets"`
+	Lost       int64         `json:"lost"`
+	Duplicates int64         `json:"duplicates"`
+	Reordered  int64         `json:"reordered"`
+	Jitter     time.Duration `json:"jitter_ns"` // interarrival jitter RFC 3550
+	NonRTP     int64         `json:"non_rtp"`   // датаграммы без заголовка RTP
+}
+
+// SenderInfo отправитель UDP датаграмм потока за окно
+type SenderInfo struct {
+	Address   string `json:"address"`
+	Datagrams int64  `json:"datagrams"`
+	Expected  bool   `json:"expected"` // совпадает с source потока (или source не задан)
+}
+
+// PCRInfo содержит анализ PCR одного PID за окно
+type PCRInfo struct {
+	PID         string          `json:"pid"`
+	Count       int             `json:"count"` // PCR за окно
+	Intervals   []time.Duration `json:"-"`     // интервалы между PCR (для гистограммы)
+	IntervalMax time.Duration   `json:"interval_max_ns"`
+	IntervalAvg time.Duration   `json:"interval_avg_ns"`
+	Jitter      []time.Duration `json:"-"`               // PCR overall jitter каждого PCR (для гистограммы)
+	JitterMax   time.Duration   `json:"jitter_max_ns"`   // размах jitter за окно
+	AccuracyMax time.Duration   `json:"accuracy_max_ns"` // максимальное |PCR_AC|
+	BitrateBPS  int64           `json:"bitrate_bps"`     // битрейт TS по PCR
+}
+
+// ETR290Error содержит количество ошибок одного индикатора ETSI TR 101 290
+type ETR290Error struct {
+	Priority  int    `json:"priority"`  // 1, 2 или 3
+	Indicator string `json:"indicator"` // TS_sync_loss, PAT_error, CC_error, ...
+	PID       string `json:"pid"`       // 0x0066 или "none" для ошибок всего потока
+	Count     int64  `json:"count"`
 }
 
 // BitrateInfo содержит информацию о битрейте
 type BitrateInfo struct {
-	TotalBPS int64 // Total TS bitrate (bits per second)
-	NetBPS   int64 // Net bitrate (payload only)
+	TotalBPS int64 `json:"total_bps"` // Total TS bitrate (bits per second)
+	NetBPS   int64 `json:"net_bps"`   // Net bitrate (payload only)
+
+	PerPID    bool    `json:"per_pid"`    // Битрейт по PID измерен (нативный режим или отчёт analyze у tsp)
+	NullRatio float64 `json:"null_ratio"` // Доля null пакетов (stuffing), 0..1
 }
 
 // PIDInfo содержит информацию о PID
 type PIDInfo struct {
-	PID          string
-	PIDDecimal   int    // PID в десятичном формате
-	Type         string // video, audio, data, other
-	Codec        string // h264, mpeg1audio, mpeg2audio, aac, etc
-	Language     string // rus, eng, kaz, kir, uzb, etc (optional)
-	IsSubtitle   bool   // true если это субтитры
-	SubtitleType string // DVB subtitles, teletext, etc
+	PID          string `json:"pid"`
+	PIDDecimal   int    `json:"pid_decimal"`             // PID в десятичном формате
+	Type         string `json:"type"`                    // video, audio, data, other
+	Codec        string `json:"codec"`                   // h264, mpeg1audio, mpeg2audio, aac, etc
+	Language     string `json:"language,omitempty"`      // rus, eng, kaz, kir, uzb, etc (optional)
+	IsSubtitle   bool   `json:"is_subtitle"`             // true если это субтитры
+	SubtitleType string `json:"subtitle_type,omitempty"` // DVB subtitles, teletext, etc
+	BitrateBPS   int64  `json:"bitrate_bps,omitempty"`   // Битрейт PID за последнее окно 1с (если Bitrate.PerPID)
+
+	Video *VideoInfo `json:"video,omitempty"` // Параметры видео из SPS/sequence header (только нативный режим)
+	Audio *AudioInfo `json:"audio,omitempty"` // Параметры аудио из заголовков кадров (только нативный режим)
+}
+
+// AudioInfo параметры аудио PID из заголовков кадров
+type AudioInfo struct {
+	Format     string `json:"format"`                // MPEG-1 Layer II, AAC LC, HE-AAC, AC-3, E-AC-3
+	SampleRate int    `json:"sample_rate"`           // Гц
+	Channels   int    `json:"channels"`              // включая LFE
+	Layout     string `json:"layout"`                // mono, stereo, joint stereo, dual mono, 5.1
+	BitrateBPS int64  `json:"bitrate_bps"`           // номинальный из заголовка или по размеру кадров
+	Dialnorm   int    `json:"dialnorm_db,omitempty"` // только AC-3 и E-AC-3
+
+	Level *AudioLevel `json:"level,omitempty"` // только MPEG audio Layer II
+}
+
+// AudioLevel уровень звука за окно по отсчётам подполос MP2 и состояние тишины
+type AudioLevel struct {
+	RMSDBFS  float64       `json:"rms_dbfs"`  // громкий канал; синусоида полной шкалы — -3 dBFS
+	PeakDBFS float64       `json:"peak_dbfs"` // наибольший отсчёт подполосы
+	Silent   time.Duration `json:"silent_ns"` // сколько звука подряд тише порога
+	Silence  bool          `json:"silence"`   // тишина дольше заданной длительности
+}
+
+// VideoInfo параметры видео PID из заголовков элементарного потока и измеренный GOP
+type VideoInfo struct {
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	FrameRate   float64       `json:"frame_rate"` // кадров в секунду
+	Interlaced  bool          `json:"interlaced"`
+	Profile     string        `json:"profile,omitempty"`
+	Level       string        `json:"level,omitempty"`
+	Aspect      string        `json:"aspect,omitempty"`          // отношение сторон изображения: 16:9, 4:3
+	GOPLength   int           `json:"gop_length,omitempty"`      // кадров между I-кадрами
+	IDRInterval time.Duration `json:"idr_interval_ns,omitempty"` // между IDR (IRAP в HEVC) по PTS
+
+	SuspectFrozen bool   `json:"suspect_frozen"`          // застывшее изображение по размерам кадров
+	FrozenReason  string `json:"frozen_reason,omitempty"` // no_key_frames, repeated_sizes, tiny_frames
 }
 
 // ServiceInfo содержит информацию о сервисе из SDT
 type ServiceInfo struct {
-	ServiceName string
-	Provider    string
-	TSID        string // Transport Stream ID
-	ServiceType string // HD/SD/etc
+	ServiceName string `json:"service_name"`
+	Provider    string `json:"provider"`
+	TSID        string `json:"tsid"`         // Transport Stream ID
+	ServiceType string `json:"service_type"` // HD/SD/etc
 }
 
 // StreamTypeMap маппинг stream_type на названия кодеков
@@ -0,0 +1,577 @@
+package tsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/mpegts"
+	"github.com/otcnet/tsmonitor/internal/rtp"
+)
+
+// NativeRunner принимает multicast поток сам и разбирает TS пакеты в Go, без tsp
+type NativeRunner struct {
+	LocalInterface string
+	StreamURL      string
+	Description    string
+	Source         string        // Адрес источника для SSM (S,G); пусто = любой источник
+	RTP            bool          // Датаграммы в RTP (SMPTE 2022-2): заголовок снимается, номера отслеживаются
+	MDIInterval    time.Duration // Интервал расчёта MDI (RFC 4445); 0 = 1s
+
+	SilenceLevel    float64       // Порог тишины MP2 аудио, dBFS; 0 = mpegts.DefaultSilenceLevel
+	SilenceDuration time.Duration // Сколько должна длиться тишина; 0 = mpegts.DefaultSilenceDuration
+
+	conn         *net.UDPConn
+	mu           sync.Mutex
+	running      bool
+	restarts     int
+	restartDelay time.Duration
+
+	MetricsChan chan *StreamMetrics
+}
+
+// NewNativeRunner создает новый NativeRunner
+func NewNativeRunner(localInterface, streamURL, description string) *NativeRunner {
+	return &NativeRunner{
+		LocalInterface: localInterface,
+		StreamURL:      streamURL,
+		Description:    description,
+		restartDelay:   5 * time.Second,
+		MetricsChan:    make(chan *StreamMetrics, 100),
+	}
+}
+
+// Start запускает приём потока
+func (r *NativeRunner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return fmt.Errorf("runner already running for %s", r.StreamURL)
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	go r.runLoop(ctx)
+	return nil
+}
+
+// runLoop основной цикл работы
+func (r *NativeRunner) runLoop(ctx context.Context) {
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+		close(r.MetricsChan)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			if err := r.receive(ctx); err != nil {
+				fmt.Printf("[%s] native input error: %v\n", r.StreamURL, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.restartDelay):
+			}
+
+			r.mu.Lock()
+			r.restarts++
+			r.mu.Unlock()
+		}
+	}
+}
+
+// receive подключается к multicast группе и разбирает пакеты
+func (r *NativeRunner) receive(ctx context.Context) error {
+	if path, ok := FilePath(r.StreamURL); ok {
+		return r.replay(ctx, path)
+	}
+
+	conn, err := listenMulticast(r.StreamURL, r.LocalInterface, r.Source)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r.mu.Lock()
+	r.conn = conn
+	r.mu.Unlock()
+
+	// Время прихода от ядра точнее для интервалов и всплесков; без него — время чтения
+	oob := make([]byte, oobSize)
+	if err := enableTimestamps(conn); err != nil {
+		oob = nil
+	}
+
+	demux := r.newDemuxer()
+	mdiInterval := r.MDIInterval
+	if mdiInterval <= 0 {
+		mdiInterval = time.Second
+	}
+	demux.SetMDIInterval(mdiInterval)
+	buf := make([]byte, 65536)
+	nextFlush := time.Now().Add(time.Second)
+	senders := make(map[string]int64) // датаграммы по адресу отправителя за окно
+	var arrivals arrivalTracker
+	var tracker rtp.Tracker
+	var nonRTP int64
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Дедлайн не даёт зависнуть на чтении, когда поток пропал
+		conn.SetReadDeadline(nextFlush)
+		n, oobn, _, from, err := conn.ReadMsgUDP(buf, oob)
+		now := time.Now()
+
+		if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read from %s: %w", r.StreamURL, err)
+		}
+		if n > 0 {
+			sender := from.IP.String()
+			senders[sender]++
+			// Датаграммы чужого источника учитываем, но не разбираем
+			if r.Source == "" || sender == r.Source {
+				at := now
+				if ts, ok := parseTimestamp(oob[:oobn]); ok {
+					at = ts
+				}
+				arrivals.arrival(at)
+
+				payload := buf[:n]
+				if r.RTP {
+					if h, data, err := rtp.Parse(payload); err == nil {
+						// Потерянная датаграмма уносит столько же TS пакетов, сколько пришло в этой
+						if lost := tracker.Update(h, at); lost > 0 {
+							demux.ReportLoss(lost * int64(len(data)/mpegts.PacketSize))
+						}
+						payload = data
+					} else {
+						nonRTP++ // без заголовка RTP разбираем как сырой TS
+					}
+				}
+				demux.Write(payload, at)
+			}
+		}
+
+		if !now.Before(nextFlush) {
+			report := demux.Flush(now)
+			metrics := MetricsFromReport(report, r.StreamURL, r.Description)
+			metrics.Senders = senderInfo(senders, r.Source)
+			clear(senders)
+			metrics.Arrival = arrivals.flush()
+			if r.RTP {
+				metrics.RTP = rtpInfo(tracker.Flush(), nonRTP)
+				nonRTP = 0
+			}
+			select {
+			case r.MetricsChan <- metrics:
+			default:
+			}
+			nextFlush = now.Add(time.Second)
+		}
+	}
+}
+
+// newDemuxer создаёт Demuxer с настройками детектора тишины потока
+func (r *NativeRunner) newDemuxer() *mpegts.Demuxer {
+	demux := mpegts.NewDemuxer()
+	level, duration := r.SilenceLevel, r.SilenceDuration
+	if level == 0 {
+		level = mpegts.DefaultSilenceLevel
+	}
+	if duration <= 0 {
+		duration = mpegts.DefaultSilenceDuration
+	}
+	demux.SetSilence(level, duration)
+	return demux
+}
+
+// replay воспроизводит запись в темпе PCR; по окончании файла runLoop начнёт его заново
+func (r *NativeRunner) replay(ctx context.Context, path string) error {
+	replay := &FileReplay{Path: path, Realtime: true}
+	err := replay.Run(ctx, r.newDemuxer(), time.Now(), func(report *mpegts.Report) {
+		select {
+		case r.MetricsChan <- MetricsFromReport(report, r.StreamURL, r.Description):
+		default:
+		}
+	})
+	if err == nil && ctx.Err() == nil {
+		fmt.Printf("[%s] end of file, replaying in %v\n", r.StreamURL, r.restartDelay)
+	}
+	return err
+}
+
+// Stop закрывает сокет; runLoop переподключится, если контекст не отменён
+func (r *NativeRunner) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		return r.conn.Close()
+	}
+	return nil
+}
+
+// IsRunning проверяет работает ли runner
+func (r *NativeRunner) IsRunning() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// Restarts возвращает число перезапусков приёма потока
+func (r *NativeRunner) Restarts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.restarts
+}
+
+// Metrics возвращает канал с метриками потока
+func (r *NativeRunner) Metrics() <-chan *StreamMetrics {
+	return r.MetricsChan
+}
+
+// ssmRange диапазон SSM (RFC 4607): к его группам можно подключиться только как (S,G)
+var ssmRange = &net.IPNet{IP: net.IPv4(232, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}
+
+// kernelSourceFilter сообщает, отбрасывает ли ядро датаграммы не от source.
+// Вне диапазона SSM подключаемся к группе от любого источника и проверяем отправителя
+// сами, чтобы чужой источник был виден как expected=false
+func kernelSourceFilter(group net.IP, source string) bool {
+	return source != "" && ssmRange.Contains(group)
+}
+
+// listenMulticast подключается к multicast группе на интерфейсе localInterface (IP или имя).
+// Группы из 232/8 с source подключаются как (S,G), остальные — от любого источника
+func listenMulticast(streamURL, localInterface, source string) (*net.UDPConn, error) {
+	group, err := net.ResolveUDPAddr("udp4", streamURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream address %s: %w", streamURL, err)
+	}
+
+	ifi, localAddress, err := ResolveInterface(localInterface)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn *net.UDPConn
+	if kernelSourceFilter(group.IP, source) {
+		conn, err = listenSourceMulticast(group, localAddress, net.ParseIP(source))
+	} else {
+		conn, err = net.ListenMulticastUDP("udp4", ifi, group)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to join %s: %w", streamURL, err)
+	}
+
+	// Запас буфера на всплески трафика
+	conn.SetReadBuffer(4 * 1024 * 1024)
+
+	return conn, nil
+}
+
+// hdServiceTypes типы сервисов SDT, которые считаются HD
+var hdServiceTypes = map[uint8]bool{
+	0x11: true, // MPEG-2 HD digital television service
+	0x19: true, // Advanced codec HD digital television service
+	0x1A: true, // Advanced codec HD NVOD time-shifted service
+	0x1B: true, // Advanced codec HD NVOD reference service
+	0x1C: true, // Advanced codec frame compatible plano-stereoscopic HD
+	0x1D: true, // Advanced codec frame compatible plano-stereoscopic HD NVOD
+}
+
+// senderInfo превращает счётчики датаграмм по отправителям в отсортированный список
+func senderInfo(senders map[string]int64, source string) []SenderInfo {
+	if len(senders) == 0 {
+		return nil
+	}
+
+	result := make([]SenderInfo, 0, len(senders))
+	for address, datagrams := range senders {
+		result = append(result, SenderInfo{
+			Address:   address,
+			Datagrams: datagrams,
+			Expected:  source == "" || address == source,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result
+}
+
+// rtpInfo переводит статистику RTP окна в RTPInfo
+func rtpInfo(stats rtp.Stats, nonRTP int64) *RTPInfo {
+	return &RTPInfo{
+		Packets:    stats.Packets,
+		Lost:       stats.Lost,
+		Duplicates: stats.Duplicates,
+		Reordered:  stats.Reordered,
+		Jitter:     stats.Jitter,
+		NonRTP:     nonRTP,
+	}
+}
+
+// MetricsFromReport преобразует отчёт нативного демультиплексора в StreamMetrics
+func MetricsFromReport(report *mpegts.Report, streamURL, description string) *StreamMetrics {
+	metrics := &StreamMetrics{
+		StreamURL:   streamURL,
+		Description: description,
+		LastSeen:    report.LastPacket,
+		PIDs:        []PIDInfo{},
+		CCErrors:    make(map[string]int64),
+	}
+
+	metrics.Bitrate.TotalBPS = report.Bitrate()
+	metrics.Bitrate.NetBPS = report.NetBitrate()
+	metrics.Bitrate.PerPID = true
+	metrics.Bitrate.NullRatio = report.NullRatio()
+	metrics.ETR290Checked = true
+
+	seen := make(map[uint16]bool)
+	for _, program := range report.Programs {
+		for _, es := range program.Streams {
+			if seen[es.PID] {
+				continue
+			}
+			seen[es.PID] = true
+			pid := pidInfoFromStream(es)
+			pid.BitrateBPS = report.PIDBitrate(es.PID)
+			if video := report.Video[es.PID]; video != nil {
+				pid.Video = &VideoInfo{
+					Width:       video.Width,
+					Height:      video.Height,
+					FrameRate:   video.FrameRate,
+					Interlaced:  video.Interlaced,
+					Profile:     video.Profile,
+					Level:       video.Level,
+					Aspect:      video.Aspect,
+					GOPLength:   video.GOPLength,
+					IDRInterval: video.IDRInterval,
+
+					SuspectFrozen: video.Frozen != "",
+					FrozenReason:  video.Frozen,
+				}
+			}
+			if audio := report.Audio[es.PID]; audio != nil {
+				pid.Audio = &AudioInfo{
+					Format:     audio.Format,
+					SampleRate: audio.SampleRate,
+					Channels:   audio.Channels,
+					Layout:     audio.Layout,
+					BitrateBPS: audio.Bitrate,
+					Dialnorm:   audio.Dialnorm,
+				}
+				if level := audio.Level; level != nil {
+					pid.Audio.Level = &AudioLevel{
+						RMSDBFS:  level.RMS,
+						PeakDBFS: level.Peak,
+						Silent:   level.Silent,
+						Silence:  level.Silence,
+					}
+				}
+			}
+			metrics.PIDs = append(metrics.PIDs, pid)
+		}
+	}
+
+	metrics.SystemPIDs = systemPIDs(report, seen)
+	metrics.Programs = programInfo(report)
+
+	if len(report.Services) > 0 {
+		svc := report.Services[0]
+		metrics.ServiceInfo.ServiceName = svc.Name
+		metrics.ServiceInfo.Provider = svc.Provider
+		metrics.ServiceInfo.ServiceType = "SD"
+		if hdServiceTypes[svc.Type] {
+			metrics.ServiceInfo.ServiceType = "HD"
+		}
+	}
+
+	if report.HasTSID {
+		metrics.TSID = fmt.Sprintf("0x%04X", report.TSID)
+		metrics.ServiceInfo.TSID = metrics.TSID
+	}
+
+	for pid, errors := range report.CCErrors {
+		if errors > 0 {
+			metrics.CCErrors[fmt.Sprintf("0x%04X", pid)] = errors
+		}
+	}
+
+	for key, count := range report.ETR290 {
+		pid := "none"
+		if key.PID != mpegts.NoPID {
+			pid = fmt.Sprintf("0x%04X", key.PID)
+		}
+		metrics.ETR290Errors = append(metrics.ETR290Errors, ETR290Error{
+			Priority:  key.Indicator.Priority,
+			Indicator: key.Indicator.Name,
+			PID:       pid,
+			Count:     count,
+		})
+	}
+
+	for pid, stats := range report.PCR {
+		metrics.PCR = append(metrics.PCR, PCRInfo{
+			PID:         fmt.Sprintf("0x%04X", pid),
+			Count:       stats.Count,
+			Intervals:   stats.Intervals,
+			IntervalMax: stats.IntervalMax,
+			IntervalAvg: stats.IntervalAvg,
+			Jitter:      stats.Jitter,
+			JitterMax:   stats.JitterMax,
+			AccuracyMax: stats.AccuracyMax,
+			BitrateBPS:  stats.Bitrate,
+		})
+	}
+
+	if report.MDI != nil {
+		metrics.MDI = &MDIInfo{
+			Interval: report.MDI.Interval,
+			DF:       report.MDI.DF,
+			MLR:      report.MDI.MLR,
+		}
+	}
+
+	metrics.UpdateStatus()
+
+	return metrics
+}
+
+// programInfo строит программы отчёта с сервисами SDT и битрейтом PMT и элементарных потоков
+func programInfo(report *mpegts.Report) []ProgramInfo {
+	services := make(map[uint16]mpegts.Service, len(report.Services))
+	for _, svc := range report.Services {
+		services[svc.ID] = svc
+	}
+
+	var programs []ProgramInfo
+	for _, program := range report.Programs {
+		info := ProgramInfo{
+			Number:     int(program.Number),
+			PMTPID:     fmt.Sprintf("0x%04X", program.PMTPID),
+			PIDs:       []string{},
+			BitrateBPS: report.PIDBitrate(program.PMTPID),
+		}
+		if program.PMT {
+			info.PCRPID = fmt.Sprintf("0x%04X", program.PCRPID)
+		}
+		if svc, ok := services[program.Number]; ok {
+			info.ServiceName = svc.Name
+			info.Provider = svc.Provider
+			info.ServiceType = "SD"
+			if hdServiceTypes[svc.Type] {
+				info.ServiceType = "HD"
+			}
+		}
+		for _, es := range program.Streams {
+			info.PIDs = append(info.PIDs, fmt.Sprintf("0x%04X", es.PID))
+			info.BitrateBPS += report.PIDBitrate(es.PID)
+		}
+		programs = append(programs, info)
+	}
+	return programs
+}
+
+// siPIDNames имена таблиц на зарезервированных PID
+var siPIDNames = map[uint16]string{
+	mpegts.PATPID: "pat",
+	mpegts.CATPID: "cat",
+	0x0002:        "tsdt",
+	mpegts.NITPID: "nit",
+	mpegts.SDTPID: "sdt",
+	mpegts.EITPID: "eit",
+	0x0013:        "rst",
+	mpegts.TDTPID: "tdt",
+}
+
+// systemPIDs возвращает PID вне PMT, по которым были пакеты в окне
+func systemPIDs(report *mpegts.Report, esPIDs map[uint16]bool) []PIDInfo {
+	pmtPIDs := make(map[uint16]bool)
+	pcrPIDs := make(map[uint16]bool)
+	for _, program := range report.Programs {
+		pmtPIDs[program.PMTPID] = true
+		if program.PMT {
+			pcrPIDs[program.PCRPID] = true
+		}
+	}
+
+	var pids []PIDInfo
+	for pid := range report.PIDPackets {
+		if esPIDs[pid] {
+			continue
+		}
+
+		pids = append(pids, systemPIDInfo(pid, report.PIDBitrate(pid), pmtPIDs, pcrPIDs))
+	}
+
+	sort.Slice(pids, func(i, j int) bool {
+		return pids[i].PIDDecimal < pids[j].PIDDecimal
+	})
+	return pids
+}
+
+// systemPIDInfo описывает PID вне PMT: null, таблицы PSI/SI, PMT или отдельный PID PCR
+func systemPIDInfo(pid uint16, bitrate int64, pmtPIDs, pcrPIDs map[uint16]bool) PIDInfo {
+	info := PIDInfo{
+		PID:        fmt.Sprintf("0x%04X", pid),
+		PIDDecimal: int(pid),
+		Type:       "other",
+		Codec:      "unknown",
+		BitrateBPS: bitrate,
+	}
+
+	switch {
+	case pid == mpegts.NullPID:
+		info.Type = "null"
+		info.Codec = "stuffing"
+	case siPIDNames[pid] != "":
+		info.Type = "psi"
+		info.Codec = siPIDNames[pid]
+	case pmtPIDs[pid]:
+		info.Type = "psi"
+		info.Codec = "pmt"
+	case pcrPIDs[pid]:
+		info.Codec = "pcr"
+	}
+	return info
+}
+
+// pidInfoFromStream строит PIDInfo из элементарного потока PMT
+func pidInfoFromStream(es mpegts.ElementaryStream) PIDInfo {
+	streamType := fmt.Sprintf("0x%02X", es.Type)
+
+	pid := PIDInfo{
+		PID:        fmt.Sprintf("0x%04X", es.PID),
+		PIDDecimal: int(es.PID),
+		Type:       GetPIDType(streamType),
+		Codec:      StreamTypeMap[streamType],
+		Language:   es.Language,
+	}
+
+	if pid.Codec == "" {
+		pid.Codec = "type_" + streamType
+	}
+
+	if es.Subtitling {
+		pid.IsSubtitle = true
+		pid.SubtitleType = "dvb_subtitle"
+	}
+
+	return pid
+}
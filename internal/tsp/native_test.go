@@ -0,0 +1,202 @@
+package tsp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/otcnet/tsmonitor/internal/mpegts"
+)
+
+func TestMetricsFromReport(t *testing.T) {
+	now := time.Now()
+	report := &mpegts.Report{
+		Start:       now.Add(-time.Second),
+		End:         now,
+		LastPacket:  now,
+		Packets:     3000,
+		NullPackets: 300,
+		PIDPackets: map[uint16]int64{
+			0x0000:         10,
+			0x012E:         10,
+			0x0066:         2000,
+			0x00CA:         680,
+			mpegts.NullPID: 300,
+		},
+		CCErrors: map[uint16]int64{0x00CA: 2},
+		ETR290: map[mpegts.ETR290Error]int64{
+			{Indicator: mpegts.CCError, PID: 0x00CA}:          2,
+			{Indicator: mpegts.TSSyncLoss, PID: mpegts.NoPID}: 1,
+		},
+		TSID:    12,
+		HasTSID: true,
+		Programs: []mpegts.Program{{
+			Number: 1000,
+			PMTPID: 0x012E,
+			PCRPID: 0x0066,
+			Streams: []mpegts.ElementaryStream{
+				{Type: 0x1B, PID: 0x0066},
+				{Type: 0x03, PID: 0x00CA, Language: "rus"},
+			},
+			PMT: true,
+		}, {
+			Number: 1001, // в PAT, PMT ещё не получена
+			PMTPID: 0x0130,
+		}},
+		Services: []mpegts.Service{{ID: 1000, Type: 0x19, Name: "Silk Way", Provider: "OTCNET"}},
+		Video: map[uint16]*mpegts.VideoInfo{
+			0x0066: {Width: 1920, Height: 1080, FrameRate: 25, Interlaced: true, Profile: "High", Level: "4.0", Aspect: "16:9", GOPLength: 12, Frozen: mpegts.FrozenTinyFrames},
+		},
+		Audio: map[uint16]*mpegts.AudioInfo{
+			0x00CA: {Format: "MPEG-1 Layer II", SampleRate: 48000, Channels: 2, Layout: "stereo", Bitrate: 192000,
+				Level: &mpegts.AudioLevel{RMS: -120, Peak: -120, Silent: 12 * time.Second, Silence: true}},
+		},
+	}
+
+	m := MetricsFromReport(report, "233.198.134.1:3333", "Test Stream")
+
+	if !m.Status {
+		t.Errorf("Status = false, want true")
+	}
+	if m.Bitrate.TotalBPS != 3000*188*8 {
+		t.Errorf("TotalBPS = %d, want %d", m.Bitrate.TotalBPS, 3000*188*8)
+	}
+	if m.Bitrate.NullRatio != 0.1 {
+		t.Errorf("NullRatio = %v, want 0.1", m.Bitrate.NullRatio)
+	}
+	if !m.ETR290Checked {
+		t.Errorf("ETR290Checked = false, want true")
+	}
+
+	if len(m.PIDs) != 2 {
+		t.Fatalf("PIDs count = %d, want 2", len(m.PIDs))
+	}
+	if m.PIDs[0].Codec != "h264" || m.PIDs[0].BitrateBPS != 2000*188*8 {
+		t.Errorf("PID[0] = %+v, want h264 at %d bps", m.PIDs[0], 2000*188*8)
+	}
+	if m.PIDs[1].Language != "rus" || m.PIDs[1].Type != "audio" {
+		t.Errorf("PID[1] = %+v, want rus audio", m.PIDs[1])
+	}
+	if v := m.PIDs[0].Video; v == nil || v.Width != 1920 || v.Height != 1080 || v.GOPLength != 12 ||
+		!v.SuspectFrozen || v.FrozenReason != "tiny_frames" {
+		t.Errorf("PID[0].Video = %+v, want 1920x1080 GOP 12 suspected frozen", v)
+	}
+	if m.PIDs[1].Video != nil {
+		t.Errorf("PID[1].Video = %+v, want nil for audio", m.PIDs[1].Video)
+	}
+	if a := m.PIDs[1].Audio; a == nil || a.SampleRate != 48000 || a.Channels != 2 || a.BitrateBPS != 192000 {
+		t.Errorf("PID[1].Audio = %+v, want 48 kHz stereo at 192000 bps", a)
+	} else if l := a.Level; l == nil || !l.Silence || l.Silent != 12*time.Second || l.RMSDBFS != -120 {
+		t.Errorf("PID[1].Audio.Level = %+v, want 12s of silence", l)
+	}
+
+	wantSystem := map[string]string{"0x0000": "pat", "0x012E": "pmt", "0x1FFF": "stuffing"}
+	if len(m.SystemPIDs) != len(wantSystem) {
+		t.Fatalf("SystemPIDs count = %d, want %d", len(m.SystemPIDs), len(wantSystem))
+	}
+	for _, pid := range m.SystemPIDs {
+		if wantSystem[pid.PID] != pid.Codec {
+			t.Errorf("SystemPID %s codec = %s, want %s", pid.PID, pid.Codec, wantSystem[pid.PID])
+		}
+	}
+
+	if m.ServiceInfo.ServiceName != "Silk Way" || m.ServiceInfo.ServiceType != "HD" {
+		t.Errorf("ServiceInfo = %+v, want Silk Way HD", m.ServiceInfo)
+	}
+	if m.TSID != "0x000C" {
+		t.Errorf("TSID = %s, want 0x000C", m.TSID)
+	}
+	wantProgram := ProgramInfo{
+		Number: 1000, PMTPID: "0x012E", PCRPID: "0x0066",
+		ServiceName: "Silk Way", Provider: "OTCNET", ServiceType: "HD",
+		PIDs: []string{"0x0066", "0x00CA"}, BitrateBPS: (10 + 2000 + 680) * 188 * 8,
+	}
+	if len(m.Programs) != 2 || !reflect.DeepEqual(m.Programs[0], wantProgram) {
+		t.Errorf("Programs = %+v, want %+v", m.Programs, wantProgram)
+	} else if m.Programs[1].PCRPID != "" {
+		t.Errorf("PCRPID before PMT = %q, want empty", m.Programs[1].PCRPID)
+	}
+	if m.CCErrors["0x00CA"] != 2 {
+		t.Errorf("CCErrors[0x00CA] = %d, want 2", m.CCErrors["0x00CA"])
+	}
+	if len(m.ETR290Errors) != 2 {
+		t.Errorf("ETR290Errors count = %d, want 2", len(m.ETR290Errors))
+	}
+}
+
+func TestSenderInfo(t *testing.T) {
+	senders := map[string]int64{"10.0.0.2": 5, "10.0.0.1": 900}
+
+	got := senderInfo(senders, "10.0.0.1")
+	want := []SenderInfo{
+		{Address: "10.0.0.1", Datagrams: 900, Expected: true},
+		{Address: "10.0.0.2", Datagrams: 5, Expected: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("senderInfo() = %+v, want %+v", got, want)
+	}
+
+	// Без source любой отправитель ожидаемый
+	for _, sender := range senderInfo(senders, "") {
+		if !sender.Expected {
+			t.Errorf("sender %s unexpected without source", sender.Address)
+		}
+	}
+	if got := senderInfo(map[string]int64{}, "10.0.0.1"); got != nil {
+		t.Errorf("senderInfo() of empty window = %+v, want nil", got)
+	}
+}
+
+func TestKernelSourceFilter(t *testing.T) {
+	tests := []struct {
+		group  string
+		source string
+		want   bool
+	}{
+		{"232.10.1.1", "10.20.0.5", true},     // SSM: (*,G) невозможно, фильтрует ядро
+		{"233.198.134.1", "10.20.0.5", false}, // отправитель проверяется при приёме
+		{"232.10.1.1", "", false},
+		{"239.1.1.1", "", false},
+	}
+	for _, tt := range tests {
+		if got := kernelSourceFilter(net.ParseIP(tt.group), tt.source); got != tt.want {
+			t.Errorf("kernelSourceFilter(%s, %q) = %v, want %v", tt.group, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestArrivalTracker(t *testing.T) {
+	var tracker arrivalTracker
+	start := time.Unix(1700000000, 0)
+
+	// Каждые 2 мс, затем всплеск из 5 датаграмм за 400 мкс
+	at := start
+	for i := 0; i < 10; i++ {
+		tracker.arrival(at)
+		at = at.Add(2 * time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		tracker.arrival(at)
+		at = at.Add(100 * time.Microsecond)
+	}
+
+	info := tracker.flush()
+	if info == nil {
+		t.Fatal("flush() = nil, want stats")
+	}
+	if len(info.Intervals) != 14 || info.IntervalMax != 2*time.Millisecond || info.BurstMax != 5 {
+		t.Errorf("arrival = %d intervals, max %v, burst %d; want 14, 2ms, 5",
+			len(info.Intervals), info.IntervalMax, info.BurstMax)
+	}
+
+	// Интервал до первой датаграммы нового окна считается от последней предыдущего
+	tracker.arrival(at.Add(10 * time.Millisecond))
+	info = tracker.flush()
+	if info == nil || len(info.Intervals) != 1 || info.BurstMax != 1 {
+		t.Errorf("next window = %+v, want 1 interval, burst 1", info)
+	}
+	if info := tracker.flush(); info != nil {
+		t.Errorf("empty window = %+v, want nil", info)
+	}
+}
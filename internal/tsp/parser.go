@@ -3,9 +3,12 @@ package tsp
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/otcnet/tsmonitor/internal/mpegts"
 )
 
 // Регулярные выражения для парсинга
@@ -17,6 +20,20 @@ var (
 	serviceRegex          = regexp.MustCompile(`Service: "([^"]+)", Provider: "([^"]*)"`)
 	tsidRegex             = regexp.MustCompile(`Transport Stream Id: (0x[0-9A-F]+) \((\d+)\)`)
 	serviceTypeRegex      = regexp.MustCompile(`Service type: (0x[0-9A-F]+) \(([^)]+)\)`)
+	continuityRegex       = regexp.MustCompile(`continuity:.*PID: (0x[0-9A-Fa-f]+)`)
+	pmtHeaderRegex        = regexp.MustCompile(`^\* PMT, .*PID (0x[0-9A-F]+)`)
+	programRegex          = regexp.MustCompile(`Program: (0x[0-9A-F]+) \((\d+)\), PCR PID: (0x[0-9A-F]+)`)
+	serviceIDRegex        = regexp.MustCompile(`Service Id: (0x[0-9A-F]+) \((\d+)\)`)
+)
+
+// bitrateMonitorMarker отмечает конец очередного блока вывода tsp
+const bitrateMonitorMarker = "bitrate_monitor:"
+
+// Строки отчёта плагина analyze --normalized: отчёт начинается строкой "ts:",
+// по строке "pid:" на каждый PID
+const (
+	analyzeReportMarker = "ts:"
+	analyzePIDMarker    = "pid:"
 )
 
 // ParseOutput парсит вывод tsp команды и возвращает StreamMetrics
@@ -42,6 +59,17 @@ func ParseOutput(output string, streamURL string, description string) (*StreamMe
 	// Парсим service info
 	parseServiceInfo(output, metrics)
 
+	// Группируем PID по программам (MPTS)
+	parsePrograms(output, metrics)
+
+	// Битрейт по PID из отчёта analyze
+	parsePIDBitrates(output, metrics)
+
+	// Парсим CC ошибки (только за последний блок)
+	parseCCErrors(output, metrics)
+
+	// TR 101 290 из вывода tsp не проверяется: разрывы CC уже учтены в CCErrors
+
 	// Обновляем статус
 	metrics.UpdateStatus()
 
@@ -78,8 +106,8 @@ func parseBitrate(output string, metrics *StreamMetrics) error {
 func parsePIDs(output string, metrics *StreamMetrics) error {
 	lines := strings.Split(output, "\n")
 	
-	// Используем map для дедупликации PIDs
-	pidMap := make(map[string]PIDInfo)
+	// Используем map для дедупликации PIDs, порядок сохраняем как в выводе
+	seen := make(map[string]bool)
 
 	for i := 0; i < len(lines); i++ {
 		line := lines[i]
@@ -96,7 +124,7 @@ func parsePIDs(output string, metrics *StreamMetrics) error {
 		pidDec := matches[4]
 
 		// Пропускаем если этот PID уже есть
-		if _, exists := pidMap[pidHex]; exists {
+		if seen[pidHex] {
 			continue
 		}
 
@@ -138,17 +166,151 @@ func parsePIDs(output string, metrics *StreamMetrics) error {
 			}
 		}
 
-		pidMap[pidHex] = pid
-	}
-
-	// Конвертируем map в slice
-	for _, pid := range pidMap {
+		seen[pidHex] = true
 		metrics.PIDs = append(metrics.PIDs, pid)
 	}
 
 	return nil
 }
 
+// parsePIDBitrates заполняет битрейт по PID из последнего отчёта analyze
+// (строки "pid:pid=102:...:bitrate=4500000:..."). PID вне PMT попадают в
+// SystemPIDs, как в нативном режиме; битрейт программ и доля null пакетов
+// считаются по тем же PID
+func parsePIDBitrates(output string, metrics *StreamMetrics) {
+	var report string
+	if idx := strings.LastIndex(output, "\n"+analyzeReportMarker); idx >= 0 {
+		report = output[idx+1:]
+	} else if strings.HasPrefix(output, analyzeReportMarker) {
+		report = output
+	} else {
+		return // отчётов analyze нет: плагин не добавлен или отчёт ещё не пришёл
+	}
+
+	bitrates := make(map[uint16]int64)
+	var total int64
+	for _, line := range strings.Split(report, "\n") {
+		if !strings.HasPrefix(line, analyzePIDMarker) {
+			continue
+		}
+
+		pid, bitrate := -1, int64(-1)
+		for _, field := range strings.Split(line, ":") {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "pid":
+				if v, err := strconv.ParseUint(value, 10, 13); err == nil {
+					pid = int(v)
+				}
+			case "bitrate":
+				if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+					bitrate = v
+				}
+			}
+		}
+		if pid < 0 || bitrate < 0 {
+			continue
+		}
+		bitrates[uint16(pid)] = bitrate
+		total += bitrate
+	}
+
+	if len(bitrates) == 0 {
+		return // analyze ещё не выдал отчёт
+	}
+	metrics.Bitrate.PerPID = true
+	if total > 0 {
+		metrics.Bitrate.NullRatio = float64(bitrates[mpegts.NullPID]) / float64(total)
+	}
+
+	esPIDs := make(map[uint16]bool, len(metrics.PIDs))
+	for i := range metrics.PIDs {
+		pid := uint16(metrics.PIDs[i].PIDDecimal)
+		esPIDs[pid] = true
+		metrics.PIDs[i].BitrateBPS = bitrates[pid]
+	}
+
+	pmtPIDs := make(map[uint16]bool)
+	pcrPIDs := make(map[uint16]bool)
+	for i := range metrics.Programs {
+		program := &metrics.Programs[i]
+		if pid, ok := parseHexPID(program.PMTPID); ok {
+			pmtPIDs[pid] = true
+			program.BitrateBPS = bitrates[pid]
+		}
+		if pid, ok := parseHexPID(program.PCRPID); ok {
+			pcrPIDs[pid] = true
+		}
+		for _, es := range program.PIDs {
+			if pid, ok := parseHexPID(es); ok {
+				program.BitrateBPS += bitrates[pid]
+			}
+		}
+	}
+
+	metrics.SystemPIDs = nil
+	for pid, bitrate := range bitrates {
+		if !esPIDs[pid] {
+			metrics.SystemPIDs = append(metrics.SystemPIDs, systemPIDInfo(pid, bitrate, pmtPIDs, pcrPIDs))
+		}
+	}
+	sort.Slice(metrics.SystemPIDs, func(i, j int) bool {
+		return metrics.SystemPIDs[i].PIDDecimal < metrics.SystemPIDs[j].PIDDecimal
+	})
+}
+
+// parseHexPID разбирает PID вида 0x0066
+func parseHexPID(value string) (uint16, bool) {
+	pid, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 13)
+	return uint16(pid), err == nil
+}
+
+// parseCCErrors считает ошибки continuity counter по PID.
+// Буфер runner'а перепарсивается целиком на каждом bitrate_monitor,
+// поэтому учитываем только строки после предыдущего блока bitrate_monitor,
+// иначе одни и те же ошибки попадут в счётчик несколько раз.
+func parseCCErrors(output string, metrics *StreamMetrics) {
+	for _, line := range strings.Split(lastBlock(output), "\n") {
+		matches := continuityRegex.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			continue
+		}
+
+		pidValue, err := strconv.ParseUint(matches[1][2:], 16, 16)
+		if err != nil {
+			continue
+		}
+
+		// Приводим к формату PIDInfo.PID (0x0066)
+		pid := fmt.Sprintf("0x%04X", pidValue)
+		metrics.CCErrors[pid]++
+	}
+}
+
+// lastBlock возвращает вывод между двумя последними строками bitrate_monitor.
+// Если предыдущая строка уже вытеснена из буфера, весь вывод до последней
+// строки считается новым.
+func lastBlock(output string) string {
+	end := strings.LastIndex(output, bitrateMonitorMarker)
+	if end < 0 {
+		return output
+	}
+
+	start := strings.LastIndex(output[:end], bitrateMonitorMarker)
+	if start < 0 {
+		return output[:end]
+	}
+
+	// Пропускаем остаток строки предыдущего bitrate_monitor
+	if nl := strings.IndexByte(output[start:end], '\n'); nl >= 0 {
+		return output[start+nl+1 : end]
+	}
+	return ""
+}
+
 // parseServiceInfo извлекает информацию о сервисе из SDT
 func parseServiceInfo(output string, metrics *StreamMetrics) {
 	serviceMatches := serviceRegex.FindStringSubmatch(output)
@@ -165,15 +327,77 @@ func parseServiceInfo(output string, metrics *StreamMetrics) {
 
 	typeMatches := serviceTypeRegex.FindStringSubmatch(output)
 	if len(typeMatches) > 0 {
-		serviceType := typeMatches[2]
-		if strings.Contains(strings.ToUpper(serviceType), "HD") {
-			metrics.ServiceInfo.ServiceType = "HD"
-		} else if strings.Contains(strings.ToUpper(serviceType), "SD") {
-			metrics.ServiceInfo.ServiceType = "SD"
-		} else {
-			metrics.ServiceInfo.ServiceType = "SD"
+		metrics.ServiceInfo.ServiceType = serviceTypeClass(typeMatches[2])
+	}
+}
+
+// serviceTypeClass сводит описание типа сервиса из SDT к HD или SD
+func serviceTypeClass(description string) string {
+	if strings.Contains(strings.ToUpper(description), "HD") {
+		return "HD"
+	}
+	return "SD"
+}
+
+// parsePrograms группирует элементарные потоки по программам из PMT и
+// сопоставляет программам сервисы SDT по service_id. Буфер содержит таблицы
+// несколько раз, действует последняя версия каждой программы
+func parsePrograms(output string, metrics *StreamMetrics) {
+	programs := make(map[int]*ProgramInfo)
+	services := make(map[int]ProgramInfo) // service_id -> имя, провайдер, тип; -1 без Service Id
+
+	var program *ProgramInfo
+	var pmtPID string
+	var section string // таблица текущего блока вывода
+	serviceID := -1
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "* ") {
+			section = line
+			program = nil
+			serviceID = -1
+			pmtPID = ""
+			if m := pmtHeaderRegex.FindStringSubmatch(line); m != nil {
+				pmtPID = m[1]
+			}
+			continue
+		}
+
+		switch {
+		case pmtPID != "":
+			if m := programRegex.FindStringSubmatch(line); m != nil {
+				number, _ := strconv.Atoi(m[2])
+				program = &ProgramInfo{Number: number, PMTPID: pmtPID, PCRPID: m[3], PIDs: []string{}}
+				programs[number] = program
+			} else if m := elementaryStreamRegex.FindStringSubmatch(line); m != nil && program != nil {
+				program.PIDs = append(program.PIDs, m[3])
+			}
+
+		case strings.HasPrefix(section, "* SDT Actual"):
+			if m := serviceIDRegex.FindStringSubmatch(line); m != nil {
+				serviceID, _ = strconv.Atoi(m[2])
+			} else if m := serviceTypeRegex.FindStringSubmatch(line); m != nil {
+				svc := services[serviceID]
+				svc.ServiceType = serviceTypeClass(m[2])
+				services[serviceID] = svc
+			} else if m := serviceRegex.FindStringSubmatch(line); m != nil {
+				svc := services[serviceID]
+				svc.ServiceName, svc.Provider = m[1], m[2]
+				services[serviceID] = svc
+			}
+		}
+	}
+
+	metrics.Programs = nil
+	for number, program := range programs {
+		svc, ok := services[number]
+		if !ok && len(programs) == 1 {
+			svc = services[-1] // SDT без Service Id: единственный сервис SPTS
 		}
+		program.ServiceName, program.Provider, program.ServiceType = svc.ServiceName, svc.Provider, svc.ServiceType
+		metrics.Programs = append(metrics.Programs, *program)
 	}
+	sortPrograms(metrics.Programs)
 }
 
 func min(a, b int) int {
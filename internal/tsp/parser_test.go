@@ -1,6 +1,7 @@
 package tsp
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -94,6 +95,58 @@ func TestParseServiceInfo(t *testing.T) {
 	}
 }
 
+func TestParsePrograms(t *testing.T) {
+	// SPTS без Service Id: единственный сервис относится к единственной программе
+	metrics := &StreamMetrics{}
+	parsePrograms(testOutput1, metrics)
+	want := []ProgramInfo{{
+		Number: 1000, PMTPID: "0x012E", PCRPID: "0x0066",
+		ServiceName: "Silk Way", Provider: "OTCNET", ServiceType: "HD",
+		PIDs: []string{"0x0066", "0x00CA", "0x012F"},
+	}}
+	if !reflect.DeepEqual(metrics.Programs, want) {
+		t.Errorf("SPTS programs = %+v, want %+v", metrics.Programs, want)
+	}
+
+	// MPTS: программы сопоставляются сервисам по service_id, PMT повторяется в буфере
+	output := `* SDT Actual, TID 0x42 (66), PID 0x0011 (17)
+  Transport Stream Id: 0x0010 (16)
+  Service Id: 0x0002 (2), EITs: no, EITp/f: no, CA mode: free
+  - Descriptor 0: Service (0x48, 72), 14 bytes
+    Service type: 0x01 (Digital television service)
+    Service: "Second", Provider: "B"
+  Service Id: 0x0001 (1), EITs: no, EITp/f: no, CA mode: free
+  - Descriptor 0: Service (0x48, 72), 13 bytes
+    Service type: 0x19 (Advanced codec HD digital television service)
+    Service: "First", Provider: "A"
+
+* PMT, TID 0x02 (2), PID 0x0100 (256)
+  Program: 0x0001 (1), PCR PID: 0x0101 (257)
+  Elementary stream: type 0x1B (AVC video), PID: 0x0101 (257)
+  Elementary stream: type 0x0F (AAC Audio), PID: 0x0102 (258)
+
+* PMT, TID 0x02 (2), PID 0x0200 (512)
+  Program: 0x0002 (2), PCR PID: 0x0201 (513)
+  Elementary stream: type 0x02 (MPEG-2 Video), PID: 0x0201 (513)
+
+* bitrate_monitor: 2026/01/26 22:38:39, TS bitrate: 8,000,000 bits/s, net bitrate: 7,500,000 bits/s
+
+* PMT, TID 0x02 (2), PID 0x0100 (256)
+  Program: 0x0001 (1), PCR PID: 0x0101 (257)
+  Elementary stream: type 0x1B (AVC video), PID: 0x0101 (257)
+  Elementary stream: type 0x0F (AAC Audio), PID: 0x0102 (258)`
+
+	metrics = &StreamMetrics{}
+	parsePrograms(output, metrics)
+	want = []ProgramInfo{
+		{Number: 1, PMTPID: "0x0100", PCRPID: "0x0101", ServiceName: "First", Provider: "A", ServiceType: "HD", PIDs: []string{"0x0101", "0x0102"}},
+		{Number: 2, PMTPID: "0x0200", PCRPID: "0x0201", ServiceName: "Second", Provider: "B", ServiceType: "SD", PIDs: []string{"0x0201"}},
+	}
+	if !reflect.DeepEqual(metrics.Programs, want) {
+		t.Errorf("MPTS programs = %+v, want %+v", metrics.Programs, want)
+	}
+}
+
 func TestParseOutput(t *testing.T) {
 	metrics, err := ParseOutput(testOutput1, "233.198.134.1:3333", "Test Stream")
 	if err != nil {
@@ -119,4 +172,99 @@ func TestParseOutput(t *testing.T) {
 	if !metrics.Status {
 		t.Errorf("Status = false, want true")
 	}
+
+	// TR 101 290 по выводу tsp не проверяется
+	if metrics.ETR290Checked || len(metrics.ETR290Errors) != 0 {
+		t.Errorf("ETR 290 from tsp output: checked = %v, errors = %v", metrics.ETR290Checked, metrics.ETR290Errors)
+	}
+}
+
+func TestParseCCErrors(t *testing.T) {
+	// Ошибки до предыдущего bitrate_monitor уже были учтены и не должны
+	// попасть в счётчик повторно
+	output := `* continuity: packet index: 1,024, PID: 0x0066 (102), missing 2 packets
+* bitrate_monitor: 2026/01/26 22:38:38, TS bitrate: 5,077,945 bits/s, net bitrate: 4,758,039 bits/s
+* continuity: packet index: 2,048, PID: 0x0066 (102), missing 1 packets
+* continuity: packet index: 2,100, PID: 0x00CA (202), missing 3 packets
+* continuity: TS: 2,300, PID: 0x0066, missing 1 packets
+* bitrate_monitor: 2026/01/26 22:38:39, TS bitrate: 5,077,945 bits/s, net bitrate: 4,758,039 bits/s`
+
+	metrics := &StreamMetrics{CCErrors: make(map[string]int64)}
+	parseCCErrors(output, metrics)
+
+	if metrics.CCErrors["0x0066"] != 2 {
+		t.Errorf("CCErrors[0x0066] = %d, want 2", metrics.CCErrors["0x0066"])
+	}
+	if metrics.CCErrors["0x00CA"] != 1 {
+		t.Errorf("CCErrors[0x00CA] = %d, want 1", metrics.CCErrors["0x00CA"])
+	}
+
+	// Первый блок без предыдущего bitrate_monitor учитывается целиком
+	first := `* continuity: packet index: 1,024, PID: 0x66 (102), missing 2 packets
+* bitrate_monitor: 2026/01/26 22:38:38, TS bitrate: 5,077,945 bits/s, net bitrate: 4,758,039 bits/s`
+
+	metrics = &StreamMetrics{CCErrors: make(map[string]int64)}
+	parseCCErrors(first, metrics)
+
+	if metrics.CCErrors["0x0066"] != 1 {
+		t.Errorf("first block CCErrors[0x0066] = %d, want 1", metrics.CCErrors["0x0066"])
+	}
+}
+
+func TestParsePIDBitrates(t *testing.T) {
+	// Два отчёта analyze --normalized: действует последний
+	output := testOutput1 + `
+ts:id=12:bytes=1000:pkt=100:bitrate=6000000:services=1:pids=6
+pid:pid=102:pes:video:bitrate=9999999:packets=1
+* bitrate_monitor: 2026/01/26 22:38:40, TS bitrate: 5,000,000 bits/s, net bitrate: 4,000,000 bits/s
+ts:id=12:bytes=1000:pkt=100:bitrate=5000000:services=1:pids=7
+global:bitrate=5000000:pids=7
+pid:pid=0:global:psi:bitrate=15000:description=PAT
+pid:pid=17:global:psi:bitrate=5000:description=SDT
+pid:pid=102:pes:video:pcr:bitrate=4000000:description=AVC video
+pid:pid=202:pes:audio:bitrate=192000:description=MPEG-1 Audio, rus
+pid:pid=302:pmt:psi:bitrate=15000:description=PMT
+pid:pid=303:pes:audio:bitrate=128000:description=MPEG-1 Audio, kaz
+pid:pid=8191:stuffing:bitrate=645000:description=Stuffing
+table:pid=0:tid=0:tables=10`
+
+	metrics, err := ParseOutput(output, "233.198.134.1:3333", "Silk Way")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !metrics.Bitrate.PerPID {
+		t.Fatal("PerPID = false, want true")
+	}
+	want := map[string]int64{"0x0066": 4000000, "0x00CA": 192000, "0x012F": 128000}
+	for _, pid := range metrics.PIDs {
+		if pid.BitrateBPS != want[pid.PID] {
+			t.Errorf("PID %s bitrate = %d, want %d", pid.PID, pid.BitrateBPS, want[pid.PID])
+		}
+	}
+
+	var system []string
+	for _, pid := range metrics.SystemPIDs {
+		system = append(system, pid.PID+" "+pid.Type+" "+pid.Codec)
+	}
+	wantSystem := []string{"0x0000 psi pat", "0x0011 psi sdt", "0x012E psi pmt", "0x1FFF null stuffing"}
+	if !reflect.DeepEqual(system, wantSystem) {
+		t.Errorf("SystemPIDs = %v, want %v", system, wantSystem)
+	}
+
+	if metrics.Bitrate.NullRatio != 0.129 {
+		t.Errorf("NullRatio = %v, want 0.129", metrics.Bitrate.NullRatio)
+	}
+	if len(metrics.Programs) != 1 || metrics.Programs[0].BitrateBPS != 4335000 {
+		t.Errorf("Programs = %+v, want bitrate 4335000", metrics.Programs)
+	}
+
+	// Без отчёта analyze битрейт по PID неизвестен
+	metrics, err = ParseOutput(testOutput1, "233.198.134.1:3333", "Silk Way")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.Bitrate.PerPID || metrics.SystemPIDs != nil {
+		t.Errorf("without analyze report: PerPID = %v, SystemPIDs = %v", metrics.Bitrate.PerPID, metrics.SystemPIDs)
+	}
 }
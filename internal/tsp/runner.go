@@ -11,15 +11,28 @@ import (
 	"time"
 )
 
+// Runner источник метрик одного потока (tsp или нативный разбор)
+type Runner interface {
+	Start(ctx context.Context) error
+	Stop() error
+	IsRunning() bool
+	Restarts() int
+	Metrics() <-chan *StreamMetrics
+}
+
 // StreamingRunner запускает долгоживущий процесс tsp
 type StreamingRunner struct {
 	LocalInterface string
 	StreamURL      string
 	Description    string
+	Source         string // Адрес источника для SSM (S,G); пусто = любой источник
+	PIDBitrate     bool   // Добавить плагин analyze: битрейт по PID и программам
 	
 	cmd           *exec.Cmd
 	mu            sync.Mutex
 	running       bool
+	stopped       bool // Stop вызван: завершение tsp не перезапускает его
+	restarts      int
 	restartDelay  time.Duration
 	
 	MetricsChan chan *StreamMetrics
@@ -68,28 +81,55 @@ func (r *StreamingRunner) runLoop(ctx context.Context) {
 				fmt.Printf("[%s] tsp error: %v\n", r.StreamURL, err)
 			}
 
+			r.mu.Lock()
+			stopped := r.stopped
+			r.mu.Unlock()
+			if stopped {
+				return
+			}
+
 			select {
 			case <-ctx.Done():
 				return
 			case <-time.After(r.restartDelay):
 			}
+
+			r.mu.Lock()
+			r.restarts++
+			r.mu.Unlock()
 		}
 	}
 }
 
 // runTSP запускает процесс tsp и читает его вывод
 func (r *StreamingRunner) runTSP(ctx context.Context) error {
+	// Интерфейс может быть задан именем, tsp нужен его адрес
+	_, localAddress, err := ResolveInterface(r.LocalInterface)
+	if err != nil {
+		return err
+	}
+
 	args := []string{
 		"-I", "ip",
-		"--local-address", r.LocalInterface,
+		"--local-address", localAddress.String(),
+	}
+	if r.Source != "" {
+		args = append(args, "--source", r.Source, "--ssm")
+	}
+	args = append(args,
 		r.StreamURL,
 		"-O", "drop",
 		"-P", "continuity",
 		"-P", "tables", "--all-sections",
+	)
+	if r.PIDBitrate {
+		args = append(args, "-P", "analyze", "--normalized", "--interval", "1")
+	}
+	args = append(args,
 		"-P", "bitrate_monitor",
 		"-p", "1",
 		"-t", "1",
-	}
+	)
 
 	cmd := exec.CommandContext(ctx, "tsp", args...)
 	
@@ -111,23 +151,37 @@ func (r *StreamingRunner) runTSP(ctx context.Context) error {
 	r.cmd = cmd
 	r.mu.Unlock()
 
+	// Вспомогательные горутины живут не дольше этого запуска tsp:
+	// после остановки runner'а канал метрик закрывается, писать в него нельзя
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
 	// Канал для объединения строк из stdout и stderr
 	linesChan := make(chan string, 100)
-	
-	// Читаем stdout в отдельной горутине
-	go func() {
-		scanner := bufio.NewScanner(stdout)
+
+	// pipesClosed закрывается, когда оба вывода дочитаны до EOF: процесс завершился
+	pipesClosed := make(chan struct{})
+	var readers sync.WaitGroup
+	readLines := func(scanner *bufio.Scanner) {
+		defer readers.Done()
 		for scanner.Scan() {
-			linesChan <- scanner.Text()
+			select {
+			case linesChan <- scanner.Text():
+			case <-done:
+				return
+			}
 		}
-	}()
-	
-	// Читаем stderr в отдельной горутине  
+	}
+	readers.Add(2)
+	go readLines(bufio.NewScanner(stdout))
+	go readLines(bufio.NewScanner(stderr))
 	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			linesChan <- scanner.Text()
-		}
+		readers.Wait()
+		close(pipesClosed)
 	}()
 
 	// Обрабатываем строки
@@ -136,13 +190,15 @@ func (r *StreamingRunner) runTSP(ctx context.Context) error {
 	const maxBufferSize = 500 * 1024
 
 	// Горутина для проверки таймаута
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		ticker := time.NewTicker(10 * time.Second)
 		defer ticker.Stop()
 		
 		for {
 			select {
-			case <-ctx.Done():
+			case <-done:
 				return
 			case <-ticker.C:
 				if time.Since(lastUpdate) > 10*time.Second {
@@ -165,59 +221,90 @@ func (r *StreamingRunner) runTSP(ctx context.Context) error {
 		}
 	}()
 
+	handleLine := func(line string) {
+		buffer.WriteString(line)
+		buffer.WriteString("\n")
+
+		// Парсим когда видим bitrate_monitor
+		if strings.Contains(line, "bitrate_monitor:") {
+			metrics, err := ParseOutput(buffer.String(), r.StreamURL, r.Description)
+			if err == nil {
+				select {
+				case r.MetricsChan <- metrics:
+					lastUpdate = time.Now()
+				default:
+				}
+			}
+
+			// Обрезаем буфер если слишком большой
+			if buffer.Len() > maxBufferSize {
+				content := trimOutput(buffer.String(), maxBufferSize/2)
+				buffer.Reset()
+				buffer.WriteString(content)
+			}
+		}
+
+		// Защита от переполнения
+		if buffer.Len() > maxBufferSize*2 {
+			content := trimOutput(buffer.String(), maxBufferSize)
+			buffer.Reset()
+			buffer.WriteString(content)
+		}
+	}
+
 	// Основной цикл чтения
 	for {
 		select {
 		case <-ctx.Done():
 			cmd.Process.Kill()
+			cmd.Wait()
 			return nil
-			
-		case line := <-linesChan:
-			buffer.WriteString(line)
-			buffer.WriteString("\n")
 
-			// Парсим когда видим bitrate_monitor
-			if strings.Contains(line, "bitrate_monitor:") {
-				metrics, err := ParseOutput(buffer.String(), r.StreamURL, r.Description)
-				if err == nil {
-					select {
-					case r.MetricsChan <- metrics:
-						lastUpdate = time.Now()
-					default:
-					}
-				}
+		case line := <-linesChan:
+			handleLine(line)
 
-				// Обрезаем буфер если слишком большой
-				if buffer.Len() > maxBufferSize {
-					content := buffer.String()
-					keepFrom := len(content) - maxBufferSize/2
-					if keepFrom < 0 {
-						keepFrom = 0
-					}
-					buffer.Reset()
-					buffer.WriteString(content[keepFrom:])
+		case <-pipesClosed:
+			// Дочитываем то, что читатели успели передать до EOF
+		drain:
+			for {
+				select {
+				case line := <-linesChan:
+					handleLine(line)
+				default:
+					break drain
 				}
 			}
-
-			// Защита от переполнения
-			if buffer.Len() > maxBufferSize*2 {
-				content := buffer.String()
-				keepFrom := len(content) - maxBufferSize
-				if keepFrom < 0 {
-					keepFrom = 0
-				}
-				buffer.Reset()
-				buffer.WriteString(content[keepFrom:])
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("tsp exited: %w", err)
 			}
+			return fmt.Errorf("tsp exited")
 		}
 	}
 }
 
+// trimOutput оставляет последние keep байт вывода, начиная с целой строки.
+// Отчёт analyze, начатый не дальше keep байт до обрезки, сохраняется целиком,
+// чтобы не разбирать его половину; строки pid: без заголовка парсер пропускает
+func trimOutput(output string, keep int) string {
+	start := len(output) - keep
+	if start <= 0 {
+		return output
+	}
+	if idx := strings.LastIndex(output[:start], "\n"+analyzeReportMarker); idx >= 0 && start-idx <= keep {
+		return output[idx+1:]
+	}
+	if idx := strings.IndexByte(output[start:], '\n'); idx >= 0 {
+		return output[start+idx+1:]
+	}
+	return ""
+}
+
 // Stop останавливает процесс tsp
 func (r *StreamingRunner) Stop() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	r.stopped = true
 	if r.cmd != nil && r.cmd.Process != nil {
 		if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
 			return r.cmd.Process.Kill()
@@ -233,3 +320,15 @@ func (r *StreamingRunner) IsRunning() bool {
 	defer r.mu.Unlock()
 	return r.running
 }
+
+// Restarts возвращает число перезапусков приёма потока
+func (r *StreamingRunner) Restarts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.restarts
+}
+
+// Metrics возвращает канал с метриками потока
+func (r *StreamingRunner) Metrics() <-chan *StreamMetrics {
+	return r.MetricsChan
+}
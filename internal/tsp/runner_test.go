@@ -0,0 +1,135 @@
+package tsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTSP подменяет tsp в PATH скриптом, который печатает одно обновление и завершается
+func fakeTSP(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script stands in for tsp")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"echo '* bitrate_monitor: 2026/01/26 22:38:39, TS bitrate: 8,000,000 bits/s, net bitrate: 7,500,000 bits/s'\n" +
+		"exit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "tsp"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestStreamingRunnerRestartsExitedProcess(t *testing.T) {
+	if _, _, err := ResolveInterface("127.0.0.1"); err != nil {
+		t.Skipf("no loopback address: %v", err)
+	}
+	fakeTSP(t)
+
+	r := NewStreamingRunner("127.0.0.1", "udp://233.198.134.1:3333", "Test")
+	r.restartDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Каждый запуск успевает отдать обновление до выхода процесса
+	updates := 0
+	deadline := time.After(5 * time.Second)
+	for r.Restarts() < 2 || updates < 2 {
+		select {
+		case m := <-r.Metrics():
+			if m.Bitrate.TotalBPS != 8000000 {
+				t.Errorf("Bitrate.TotalBPS = %d, want 8000000", m.Bitrate.TotalBPS)
+			}
+			updates++
+		case <-deadline:
+			t.Fatalf("Restarts() = %d, updates = %d: exited tsp not restarted", r.Restarts(), updates)
+		}
+	}
+
+	cancel()
+	for range r.Metrics() {
+	}
+	if r.IsRunning() {
+		t.Error("runner still running after cancel")
+	}
+}
+
+func TestStreamingRunnerStopDoesNotRestart(t *testing.T) {
+	if _, _, err := ResolveInterface("127.0.0.1"); err != nil {
+		t.Skipf("no loopback address: %v", err)
+	}
+	fakeTSP(t)
+
+	r := NewStreamingRunner("127.0.0.1", "udp://233.198.134.1:3333", "Test")
+	r.restartDelay = 10 * time.Millisecond
+	r.Stop()
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// После Stop процесс не перезапускается, и канал метрик закрывается
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-r.Metrics():
+			if !ok {
+				if r.Restarts() != 0 {
+					t.Errorf("Restarts() = %d after Stop, want 0", r.Restarts())
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("stopped runner kept restarting tsp")
+		}
+	}
+}
+
+func TestTrimOutput(t *testing.T) {
+	const report = "ts:id=12:bytes=1000:pkt=100:bitrate=5000000:services=1:pids=3\n" +
+		"pid:pid=0:global:psi:bitrate=15000:description=PAT\n" +
+		"pid:pid=102:pes:video:pcr:bitrate=4000000:description=AVC video\n" +
+		"pid:pid=8191:stuffing:bitrate=985000:description=Stuffing\n"
+	const monitor = "* bitrate_monitor: 2026/01/26 22:38:40, TS bitrate: 5,000,000 bits/s, net bitrate: 4,015,000 bits/s\n"
+	filler := strings.Repeat("* continuity: packet index: 1,024, PID: 0x66 (102), missing 2 packets\n", 20)
+
+	// Обрезка посреди отчёта analyze сохраняет его целиком
+	output := filler + report + monitor
+	trimmed := trimOutput(output, len(monitor)+100)
+	if trimmed != report+monitor {
+		t.Errorf("cut through report: got %q", trimmed)
+	}
+	metrics := &StreamMetrics{}
+	parsePIDBitrates(trimmed, metrics)
+	if !metrics.Bitrate.PerPID || metrics.Bitrate.NullRatio != 0.197 {
+		t.Errorf("trimmed report: PerPID = %v, NullRatio = %v", metrics.Bitrate.PerPID, metrics.Bitrate.NullRatio)
+	}
+
+	// Без отчёта вывод обрезается по границе строки
+	output = filler + monitor
+	trimmed = trimOutput(output, len(monitor)+10)
+	if trimmed != monitor {
+		t.Errorf("cut at line boundary: got %q", trimmed)
+	}
+
+	// Давний отчёт не удерживает буфер: остаются только pid: строки без заголовка, они не разбираются
+	output = report + filler + filler + monitor
+	trimmed = trimOutput(output, len(filler))
+	if len(trimmed) > len(filler) {
+		t.Errorf("old report kept: %d bytes, want at most %d", len(trimmed), len(filler))
+	}
+	output = "ts:id=12:bitrate=5000000\n" + filler + report[strings.Index(report, "pid:"):] + monitor
+	metrics = &StreamMetrics{}
+	parsePIDBitrates(trimOutput(output, len(report)+len(monitor)), metrics)
+	if metrics.Bitrate.PerPID {
+		t.Error("pid lines without report header parsed")
+	}
+}
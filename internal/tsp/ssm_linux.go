@@ -0,0 +1,55 @@
+//go:build linux
+
+package tsp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// listenSourceMulticast подключается к (S,G): сокет привязан к адресу группы,
+// ядро пропускает только датаграммы от source (IP_ADD_SOURCE_MEMBERSHIP)
+func listenSourceMulticast(group *net.UDPAddr, localAddress, source net.IP) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			return setsockopt(c, func(fd int) error {
+				return syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+			})
+		},
+	}
+
+	pc, err := lc.ListenPacket(context.Background(), "udp4", group.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", group, err)
+	}
+	conn := pc.(*net.UDPConn)
+
+	// struct ip_mreq_source: группа, адрес интерфейса, источник
+	mreq := make([]byte, 0, 12)
+	mreq = append(mreq, group.IP.To4()...)
+	mreq = append(mreq, localAddress.To4()...)
+	mreq = append(mreq, source.To4()...)
+
+	raw, err := conn.SyscallConn()
+	if err == nil {
+		err = setsockopt(raw, func(fd int) error {
+			return syscall.SetsockoptString(fd, syscall.IPPROTO_IP, syscall.IP_ADD_SOURCE_MEMBERSHIP, string(mreq))
+		})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to join (%s, %s): %w", source, group.IP, err)
+	}
+	return conn, nil
+}
+
+// setsockopt выполняет set на файловом дескрипторе сокета
+func setsockopt(c syscall.RawConn, set func(fd int) error) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) { sockErr = set(int(fd)) }); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tsp
+
+import (
+	"fmt"
+	"net"
+)
+
+// listenSourceMulticast SSM в нативном режиме реализован только для Linux
+func listenSourceMulticast(group *net.UDPAddr, localAddress, source net.IP) (*net.UDPConn, error) {
+	return nil, fmt.Errorf("source-specific multicast is not supported on this platform, use input: tsp")
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package tsp
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"time"
+)
+
+// oobSize место под управляющее сообщение с временем прихода
+const oobSize = 64
+
+// enableTimestamps включает метки времени прихода датаграмм от ядра (SO_TIMESTAMPNS):
+// они не зависят от задержек планировщика при чтении из сокета
+func enableTimestamps(conn *net.UDPConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return setsockopt(raw, func(fd int) error {
+		return syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TIMESTAMPNS, 1)
+	})
+}
+
+// parseTimestamp извлекает время прихода из управляющих сообщений датаграммы
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level != syscall.SOL_SOCKET || msg.Header.Type != syscall.SCM_TIMESTAMPNS {
+			continue
+		}
+		// struct timespec: два long, 64 или 32 бита в зависимости от платформы
+		switch len(msg.Data) {
+		case 16:
+			sec := int64(binary.NativeEndian.Uint64(msg.Data[0:8]))
+			nsec := int64(binary.NativeEndian.Uint64(msg.Data[8:16]))
+			return time.Unix(sec, nsec), true
+		case 8:
+			sec := int32(binary.NativeEndian.Uint32(msg.Data[0:4]))
+			nsec := int32(binary.NativeEndian.Uint32(msg.Data[4:8]))
+			return time.Unix(int64(sec), int64(nsec)), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package tsp
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// oobSize на других платформах управляющие сообщения не читаются
+const oobSize = 0
+
+// enableTimestamps метки времени ядра поддерживаются только на Linux
+func enableTimestamps(conn *net.UDPConn) error {
+	return fmt.Errorf("kernel receive timestamps are not supported on this platform")
+}
+
+// parseTimestamp без меток ядра используется время чтения из сокета
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	return time.Time{}, false
+}